@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"BinanceAutoBot2/internal/account"
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/events"
+	"BinanceAutoBot2/internal/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var accountLog = logging.For("account")
+
+// accountRedisKey 账户全量状态在 Redis 中的 Key，值为 account.Snapshot 的 JSON
+const accountRedisKey = "Account"
+
+// accountSync 负责把 account.Account 与交易所保持一致并发布到 Redis
+// WS 推送即时更新余额与仓位，随后触发一次去抖的 REST 刷新，补齐推送里没有的
+// 可用余额、维持保证金与强平价格
+type accountSync struct {
+	account   *account.Account
+	apiClient *binance.APIClient
+	rdb       *redis.Client
+	redisKey  string // 多账户模式下为 <account>:Account
+	streams   *events.Publisher
+	refreshCh chan struct{}
+}
+
+func newAccountSync(apiClient *binance.APIClient, rdb *redis.Client, prefix string, streams *events.Publisher) *accountSync {
+	return &accountSync{
+		account:   account.NewAccount(),
+		apiClient: apiClient,
+		rdb:       rdb,
+		redisKey:  prefix + accountRedisKey,
+		streams:   streams,
+		refreshCh: make(chan struct{}, 1),
+	}
+}
+
+// Refresh 通过 REST 全量拉取账户与持仓风险并发布
+func (s *accountSync) Refresh(ctx context.Context) error {
+	info, err := s.apiClient.GetAccountInfo()
+	if err != nil {
+		return err
+	}
+	risks, err := s.apiClient.GetPositions("")
+	if err != nil {
+		return err
+	}
+	s.account.LoadFromREST(info, risks)
+	s.Publish(ctx)
+	return nil
+}
+
+// ApplyEvent 应用 ACCOUNT_UPDATE / MARGIN_CALL 推送，发布后安排一次 REST 补全
+func (s *accountSync) ApplyEvent(ctx context.Context, event binance.UserDataEvent) {
+	if event.EventType != "ACCOUNT_UPDATE" && event.EventType != "MARGIN_CALL" {
+		return
+	}
+	s.account.ApplyEvent(event)
+	s.Publish(ctx)
+
+	if event.EventType == "MARGIN_CALL" {
+		snap := s.account.Snapshot()
+		accountLog.Warn("收到追加保证金通知", "margin_ratio", snap.MarginRatio)
+	}
+	s.RequestRefresh()
+}
+
+// RequestRefresh 非阻塞地请求一次 REST 刷新，已有待处理请求时合并
+func (s *accountSync) RequestRefresh() {
+	select {
+	case s.refreshCh <- struct{}{}:
+	default:
+	}
+}
+
+// Run 处理刷新请求，每次刷新前等待一小段时间，把成交瞬间连续推送的多条事件合并为一次 REST 调用
+func (s *accountSync) Run(ctx context.Context) {
+	const debounce = time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.refreshCh:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(debounce):
+		}
+
+		// 去抖期间到达的请求已被本次刷新覆盖
+		select {
+		case <-s.refreshCh:
+		default:
+		}
+
+		if err := s.Refresh(ctx); err != nil {
+			accountLog.Warn("账户状态 REST 补全失败", logging.Err(err))
+		}
+	}
+}
+
+// Publish 把账户快照整体写入 Redis，并推送到 Stream:Account
+func (s *accountSync) Publish(ctx context.Context) {
+	snap := s.account.Snapshot()
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	metrics.RedisWrite(accountRedisKey, s.rdb.Set(ctx, s.redisKey, data, 0).Err())
+	metrics.RedisWrite("stream", s.streams.PublishAccount(ctx, snap))
+}
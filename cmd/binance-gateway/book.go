@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/codec"
+	"BinanceAutoBot2/internal/events"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/orderbook"
+	"BinanceAutoBot2/internal/shmbook"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var bookLog = logging.For("book")
+
+// bookTopN 每次发布的盘口档数
+const bookTopN = 20
+
+// defaultStaleAfter 未配置 market_data.stale_after_ms 时的停滞判定时长
+const defaultStaleAfter = 5 * time.Second
+
+// bookPublisher 把本地盘口 TopN 快照依次写入共享内存、OrderBook:<SYM> Key 与事件流，
+// 并在超过 staleAfter 未收到任何深度推送时把盘口标记为 STALE (或删除 Key)
+type bookPublisher struct {
+	ob            *orderbook.LocalOrderBook
+	rdb           *redis.Client
+	streams       *events.Publisher
+	shm           *shmbook.Writer
+	enc           codec.BookEncoder
+	redisKey      string
+	keyTTL        time.Duration // >0 时 Key 带过期时间写入
+	staleAfter    time.Duration
+	deleteOnStale bool
+
+	mu        sync.Mutex
+	buf       []byte // 编码缓冲区，在每次发布间复用
+	lastEvent time.Time
+	stale     bool
+}
+
+func newBookPublisher(ob *orderbook.LocalOrderBook, rdb *redis.Client, streams *events.Publisher, shm *shmbook.Writer,
+	enc codec.BookEncoder, staleAfter, keyTTL time.Duration, deleteOnStale bool) *bookPublisher {
+	if staleAfter <= 0 {
+		staleAfter = defaultStaleAfter
+	}
+	return &bookPublisher{
+		ob:            ob,
+		rdb:           rdb,
+		streams:       streams,
+		shm:           shm,
+		enc:           enc,
+		redisKey:      "OrderBook:" + ob.Symbol,
+		keyTTL:        keyTTL,
+		staleAfter:    staleAfter,
+		deleteOnStale: deleteOnStale,
+		lastEvent:     time.Now(), // 启动后一直收不到推送同样视为停滞
+	}
+}
+
+// OnEvent 记录一次深度推送到达；即使事件因断层未被应用，也说明连接没有冻结
+func (b *bookPublisher) OnEvent() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastEvent = time.Now()
+	if b.stale {
+		b.stale = false
+		bookLog.Info("行情恢复推送", "symbol", b.ob.Symbol)
+	}
+}
+
+// Stale 是否处于行情停滞状态
+func (b *bookPublisher) Stale() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stale
+}
+
+// Publish 发布当前盘口，状态取自状态机 (SYNCED / RESYNCING)，停滞期间覆盖为 STALE
+func (b *bookPublisher) Publish(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snap := b.ob.GetTopN(bookTopN)
+	if b.stale {
+		snap.State = binance.BookStateStale
+	}
+	b.publishLocked(ctx, &snap, true)
+}
+
+func (b *bookPublisher) publishLocked(ctx context.Context, snap *binance.OrderBookSnapshot, writeKey bool) {
+	// 共享内存最先写，不经过编码与网络
+	_ = b.shm.Write(snap)
+
+	var err error
+	if b.buf, err = b.enc.AppendBook(b.buf[:0], snap); err != nil {
+		bookLog.Error("盘口编码失败", "symbol", b.ob.Symbol, logging.Err(err))
+		return
+	}
+	if writeKey {
+		// 使用一个极短的 context 防止 Redis 阻塞 WS 接收协程
+		rCtx, rCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		metrics.RedisWrite("OrderBook", b.rdb.Set(rCtx, b.redisKey, b.buf, b.keyTTL).Err())
+		rCancel()
+	}
+	metrics.RedisWrite("stream", b.streams.PublishBook(ctx, *snap, b.buf, b.enc.Format()))
+}
+
+// Run 停滞检测循环，直到 ctx 取消
+func (b *bookPublisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(max(b.staleAfter/4, 100*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.checkStale(ctx)
+		}
+	}
+}
+
+func (b *bookPublisher) checkStale(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	idle := time.Since(b.lastEvent)
+	if b.stale || idle < b.staleAfter {
+		return
+	}
+	b.stale = true
+	bookLog.Warn("未收到深度推送，盘口标记为 STALE", "symbol", b.ob.Symbol, "idle", idle.Truncate(time.Millisecond))
+
+	snap := b.ob.GetTopN(bookTopN)
+	snap.State = binance.BookStateStale
+	if b.deleteOnStale {
+		rCtx, rCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		metrics.RedisWrite("OrderBook", b.rdb.Del(rCtx, b.redisKey).Err())
+		rCancel()
+	}
+	// 删除模式下不再写 Key，共享内存与事件流仍收到 STALE 快照
+	b.publishLocked(ctx, &snap, !b.deleteOnStale)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/orderbook"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var healthLog = logging.For("health")
+
+// 组件状态
+const (
+	healthOK       = "ok"       // 正常
+	healthDegraded = "degraded" // 有异常但不影响交易 (如可选的 Redis 不在线、续期失败但 ListenKey 仍在有效期内)
+	healthDown     = "down"     // 不可交易，/readyz 返回 503
+)
+
+const (
+	// listenKeyValidity ListenKey 自最近一次创建 / 续期起的有效期
+	listenKeyValidity = 60 * time.Minute
+	// clockCheckInterval 时钟偏差的测量间隔
+	clockCheckInterval = time.Minute
+	// clockWarnOffset / clockMaxOffset 时钟偏差超过前者为 degraded，超过后者 (接近 recvWindow) 为 down
+	clockWarnOffset = time.Second
+	clockMaxOffset  = 4 * time.Second
+	// redisPingTimeout 每次健康检查 Ping Redis 的超时
+	redisPingTimeout = 100 * time.Millisecond
+)
+
+// componentHealth 单个组件的状态
+type componentHealth struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Since  int64  `json:"since,omitempty"` // 进入当前连接状态的时间 (毫秒)，仅连接类组件
+}
+
+// healthReport /healthz 与 /readyz 的响应
+type healthReport struct {
+	Status     string                     `json:"status"` // 各组件中最差的状态
+	Ready      bool                       `json:"ready"`  // 没有任何组件为 down
+	Time       int64                      `json:"time"`
+	Components map[string]componentHealth `json:"components"`
+}
+
+// connState WebSocket 连接状态
+type connState struct {
+	connected bool
+	since     time.Time
+	lastErr   error
+}
+
+// listenKeyState ListenKey 最近一次创建 / 续期成功的时间与最近一次结果
+type listenKeyState struct {
+	at  time.Time
+	err error
+}
+
+// healthMonitor 汇总行情、私有流、ListenKey、Redis 与时钟偏差的状态，供编排系统与策略端判断能否交易
+type healthMonitor struct {
+	api           *binance.APIClient
+	rdb           *redis.Client
+	redisOptional bool
+
+	mu         sync.Mutex
+	ob         *orderbook.LocalOrderBook
+	book       *bookPublisher
+	depth      connState
+	user       map[string]*connState      // 组件名 → 私有流连接状态，每个账户一条
+	listenKeys map[string]*listenKeyState // 组件名 → ListenKey 状态，每个账户一个
+	wsAPI      map[string]*connState      // 组件名 → 连接状态，未启用 WebSocket API 下单时为空
+	clock      time.Duration
+	clockAt    time.Time
+	clockErr   error
+}
+
+func newHealthMonitor(api *binance.APIClient, rdb *redis.Client, redisOptional bool) *healthMonitor {
+	return &healthMonitor{
+		api:           api,
+		rdb:           rdb,
+		redisOptional: redisOptional,
+		depth:         connState{since: time.Now()},
+		user:          make(map[string]*connState),
+		listenKeys:    make(map[string]*listenKeyState),
+		wsAPI:         make(map[string]*connState),
+	}
+}
+
+// WatchBook 关联本地盘口与发布器 (行情模块在私有流之后才初始化)
+func (h *healthMonitor) WatchBook(ob *orderbook.LocalOrderBook, book *bookPublisher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ob, h.book = ob, book
+}
+
+// DepthConnected / DepthDisconnected 作为深度 WSClient 的连接回调
+func (h *healthMonitor) DepthConnected() { h.setConn(&h.depth, true, nil) }
+
+func (h *healthMonitor) DepthDisconnected(err error) { h.setConn(&h.depth, false, err) }
+
+// UserStreamEnabled 登记一个账户的私有流与 ListenKey (单账户时组件名为 user_stream / listen_key，
+// 多账户时为 user_stream:<account> / listen_key:<account>)，返回私有流连接 / 断线回调与 ListenKey 创建 / 续期结果回调
+func (h *healthMonitor) UserStreamEnabled(userComponent, listenKeyComponent string) (onConnect func(), onDisconnect func(error), onListenKey func(error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := &connState{since: time.Now()}
+	lk := &listenKeyState{}
+	h.user[userComponent] = c
+	h.listenKeys[listenKeyComponent] = lk
+	onListenKey = func(err error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		lk.err = err
+		if err == nil {
+			lk.at = time.Now()
+		}
+	}
+	return func() { h.setConn(c, true, nil) }, func(err error) { h.setConn(c, false, err) }, onListenKey
+}
+
+// WSAPIEnabled 登记一条 WebSocket API 下单连接 (单个下单 Key 时组件名为 ws_api，多个时为 ws_api/<key>，
+// 多账户时再带 :<account>)，返回作为该 WSAPIClient 连接 / 断线回调的函数
+func (h *healthMonitor) WSAPIEnabled(component string) (onConnect func(), onDisconnect func(error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := &connState{since: time.Now()}
+	h.wsAPI[component] = c
+	return func() { h.setConn(c, true, nil) }, func(err error) { h.setConn(c, false, err) }
+}
+
+func (h *healthMonitor) setConn(c *connState, connected bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c.connected != connected {
+		c.since = time.Now()
+	}
+	c.connected = connected
+	c.lastErr = err
+}
+
+// Run 周期性测量与交易所的时钟偏差，直到 ctx 取消
+func (h *healthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(clockCheckInterval)
+	defer ticker.Stop()
+	for {
+		h.checkClock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthMonitor) checkClock() {
+	offset, err := h.api.ClockOffset()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clockErr = err
+	if err != nil {
+		healthLog.Warn("时钟偏差测量失败", logging.Err(err))
+		return
+	}
+	h.clock, h.clockAt = offset, time.Now()
+	if offset.Abs() >= clockWarnOffset {
+		healthLog.Warn("本机与交易所时钟偏差过大，请检查 NTP", "offset", offset.Round(time.Millisecond))
+	}
+}
+
+// Report 生成当前状态；Redis 在此时 Ping，其余组件读取回调记录的状态
+func (h *healthMonitor) Report(ctx context.Context) healthReport {
+	components := make(map[string]componentHealth, 6)
+	components["redis"] = h.redisHealth(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	components["orderbook"] = h.bookHealth()
+	components["depth_ws"] = connHealth(h.depth)
+	for name, conn := range h.user {
+		components[name] = connHealth(*conn)
+	}
+	for name, lk := range h.listenKeys {
+		components[name] = listenKeyHealth(*lk)
+	}
+	for name, conn := range h.wsAPI {
+		// 断线期间下单自动回退 REST，只是延迟变高，不影响交易
+		c := connHealth(*conn)
+		if c.Status == healthDown {
+			c.Status, c.Detail = healthDegraded, "下单已回退 REST: "+c.Detail
+		}
+		components[name] = c
+	}
+	components["clock"] = h.clockHealth()
+
+	report := healthReport{Status: healthOK, Ready: true, Time: time.Now().UnixMilli(), Components: components}
+	for _, c := range components {
+		switch c.Status {
+		case healthDown:
+			report.Status, report.Ready = healthDown, false
+		case healthDegraded:
+			if report.Status == healthOK {
+				report.Status = healthDegraded
+			}
+		}
+	}
+	return report
+}
+
+func (h *healthMonitor) bookHealth() componentHealth {
+	if h.ob == nil {
+		return componentHealth{Status: healthDown, Detail: "行情模块未启动"}
+	}
+	ready, synced := h.ob.Status()
+	switch {
+	case h.book.Stale():
+		return componentHealth{Status: healthDown, Detail: "STALE: 深度推送停滞"}
+	case !ready:
+		return componentHealth{Status: healthDown, Detail: "等待快照"}
+	case !synced:
+		return componentHealth{Status: healthDown, Detail: "等待增量缝合"}
+	}
+	return componentHealth{Status: healthOK}
+}
+
+func connHealth(c connState) componentHealth {
+	if c.connected {
+		return componentHealth{Status: healthOK, Since: c.since.UnixMilli()}
+	}
+	detail := "未连接"
+	if c.lastErr != nil {
+		detail = c.lastErr.Error()
+	}
+	return componentHealth{Status: healthDown, Detail: detail, Since: c.since.UnixMilli()}
+}
+
+func listenKeyHealth(lk listenKeyState) componentHealth {
+	if lk.at.IsZero() {
+		detail := "尚未获取"
+		if lk.err != nil {
+			detail = lk.err.Error()
+		}
+		return componentHealth{Status: healthDown, Detail: detail}
+	}
+	age := time.Since(lk.at)
+	if age >= listenKeyValidity {
+		return componentHealth{Status: healthDown, Detail: "已超过 60 分钟未成功续期", Since: lk.at.UnixMilli()}
+	}
+	if lk.err != nil {
+		return componentHealth{Status: healthDegraded, Detail: "最近一次续期失败: " + lk.err.Error(), Since: lk.at.UnixMilli()}
+	}
+	return componentHealth{Status: healthOK, Since: lk.at.UnixMilli()}
+}
+
+func (h *healthMonitor) clockHealth() componentHealth {
+	if h.clockAt.IsZero() {
+		detail := "尚未测量"
+		if h.clockErr != nil {
+			detail = h.clockErr.Error()
+		}
+		return componentHealth{Status: healthDegraded, Detail: detail}
+	}
+	detail := "offset " + h.clock.Round(time.Millisecond).String()
+	switch abs := h.clock.Abs(); {
+	case abs >= clockMaxOffset:
+		return componentHealth{Status: healthDown, Detail: detail}
+	case abs >= clockWarnOffset || h.clockErr != nil:
+		return componentHealth{Status: healthDegraded, Detail: detail}
+	}
+	return componentHealth{Status: healthOK, Detail: detail}
+}
+
+func (h *healthMonitor) redisHealth(ctx context.Context) componentHealth {
+	pCtx, cancel := context.WithTimeout(ctx, redisPingTimeout)
+	defer cancel()
+	if err := h.rdb.Ping(pCtx).Err(); err != nil {
+		// redis.optional 时策略可以只走 UDS，Redis 不在线不影响交易
+		status := healthDown
+		if h.redisOptional {
+			status = healthDegraded
+		}
+		return componentHealth{Status: status, Detail: err.Error()}
+	}
+	return componentHealth{Status: healthOK}
+}
+
+// healthHandler /healthz 存活检查：进程在服务即返回 200，响应体给出各组件状态
+// readyHandler /readyz 就绪检查：任一组件为 down 时返回 503，策略端与编排系统据此停止下单
+func (h *healthMonitor) healthHandler(w http.ResponseWriter, r *http.Request) { h.serve(w, r, false) }
+
+func (h *healthMonitor) readyHandler(w http.ResponseWriter, r *http.Request) { h.serve(w, r, true) }
+
+func (h *healthMonitor) serve(w http.ResponseWriter, r *http.Request, readiness bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := h.Report(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if readiness && !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Register 把 /healthz 与 /readyz 注册到 mux
+func (h *healthMonitor) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", h.healthHandler)
+	mux.HandleFunc("/readyz", h.readyHandler)
+}
+
+// serveHealth 在 TCP (或另一个 Unix Socket) 上单独暴露 /healthz 与 /readyz，供不便访问 UDS 的编排系统探测
+func serveHealth(addr string, h *healthMonitor) {
+	listener, network, err := listenAddr(addr)
+	if err != nil {
+		fatal(healthLog, "健康检查监听失败", "addr", addr, logging.Err(err))
+	}
+	mux := http.NewServeMux()
+	h.Register(mux)
+	healthLog.Info("健康检查已启动", "network", network, "addr", addr)
+	if err := http.Serve(listener, mux); err != nil {
+		fatal(healthLog, "健康检查服务退出", logging.Err(err))
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/logging"
+)
+
+var keysLog = logging.For("keys")
+
+// wsAPIConn 某个下单 Key 的 WebSocket API 常驻连接
+type wsAPIConn struct {
+	key    string
+	client *binance.WSAPIClient
+}
+
+// apiKeys 按角色拆分后的 API Key：只读请求 (账户 / 持仓查询、ListenKey、对账、时钟) 固定走 read，
+// 下单类请求经 orders 在各下单 Key 之间分散，共用账户的一分钟下单额度
+type apiKeys struct {
+	read   *binance.APIClient
+	orders *binance.KeyPool
+	wsAPIs []wsAPIConn
+	rest   map[string]*binance.APIClient // Key 名 → REST 客户端，用于校验各 Key 属于同一账户
+}
+
+// newAPIKeys 为账户环境中的每个 Key 创建 APIClient (配置了 ws_api_url 时下单 Key 另建 WebSocket API 连接)；
+// onRoundTrip 挂在所有客户端上用于延迟与指标统计
+func newAPIKeys(account string, env config.EnvConfig, onRoundTrip func(rt binance.RoundTrip)) (*apiKeys, error) {
+	keys := env.APIKeys()
+	if len(keys) == 0 {
+		// 未配置任何 Key 时保持原行为：空 Key 的单一客户端，只能访问公共接口
+		keys = []config.APIKeyConfig{{Name: config.PrimaryKeyName, Role: config.KeyRoleAll}}
+	}
+
+	k := &apiKeys{orders: binance.NewKeyPool(env.OrderLimit1m), rest: make(map[string]*binance.APIClient, len(keys))}
+	for _, kc := range keys {
+		name := kc.Name
+		client := binance.NewAPIClient(kc.APIKey, kc.APISecret)
+		client.BaseURL = env.RestBaseURL
+		client.OnRoundTrip = func(rt binance.RoundTrip) {
+			onRoundTrip(rt)
+			k.orders.ObserveRoundTrip(rt)
+			metrics.KeyRoundTrip(account, name, rt)
+		}
+		k.rest[name] = client
+		if kc.CanRead() && k.read == nil {
+			k.read = client
+		}
+		if !kc.CanOrder() {
+			continue
+		}
+
+		var orders binance.OrderClient = client
+		if env.WSAPIURL != "" {
+			ws := binance.NewWSAPIClient(env.WSAPIURL, client)
+			if kc.WSAPIPrivateKeyPath != "" {
+				pemBytes, err := os.ReadFile(kc.WSAPIPrivateKeyPath)
+				if err != nil {
+					return nil, fmt.Errorf("读取 Key %s 的 WebSocket API 私钥失败: %w", name, err)
+				}
+				logonKey, err := binance.LoadEd25519Key(pemBytes)
+				if err != nil {
+					return nil, fmt.Errorf("Key %s 的 WebSocket API 私钥格式错误: %w", name, err)
+				}
+				ws.LogonAPIKey, ws.LogonKey = kc.WSAPILogonKey, logonKey
+			}
+			ws.OnRoundTrip = client.OnRoundTrip
+			orders = ws
+			k.wsAPIs = append(k.wsAPIs, wsAPIConn{key: name, client: ws})
+		}
+		k.orders.AddKey(name, orders)
+		keysLog.Info("下单 Key 已加载", "account", account, "key", name, "role", kc.Role, "ws_api", env.WSAPIURL != "")
+	}
+	return k, nil
+}
+
+// verifySingleAccount 确认所有 Key 属于同一账户：同一账户有效期内申请 ListenKey 返回同一个 key，
+// 不同账户 (子账户) 则不同。子账户混用时私有流与对账只覆盖只读 Key 所在账户，撤单 / 改单也可能发往无权操作的 Key，
+// 因此拒绝启动，子账户应配置为 accounts 中的独立账户
+func (k *apiKeys) verifySingleAccount() error {
+	if len(k.rest) < 2 {
+		return nil
+	}
+	want, err := k.read.GetListenKey()
+	if err != nil {
+		return fmt.Errorf("无法确认只读 Key 所属账户: %w", err)
+	}
+	for name, client := range k.rest {
+		if client == k.read {
+			continue
+		}
+		got, err := client.GetListenKey()
+		if err != nil {
+			return fmt.Errorf("无法确认 Key %s 所属账户: %w", name, err)
+		}
+		if got != want {
+			return fmt.Errorf("Key %s 与只读 Key 不属于同一账户，子账户请配置为 accounts 中的独立账户", name)
+		}
+	}
+	return nil
+}
+
+// Run 启动各下单 Key 的 WebSocket API 连接与 REST 连接保温，连接状态以 base 为组件名报告给 health
+// (多个下单 Key 时为 base/<key>)
+func (k *apiKeys) Run(ctx context.Context, health *healthMonitor, base string) {
+	for _, c := range k.wsAPIs {
+		component := base
+		if len(k.wsAPIs) > 1 {
+			component = base + "/" + c.key
+		}
+		onConnect, onDisconnect := health.WSAPIEnabled(component)
+		c.client.OnConnect = metrics.ConnectHook(component, onConnect)
+		c.client.OnDisconnect = onDisconnect
+		go c.client.Run(ctx)
+	}
+}
+
+// ServeHTTP 实现 UDS 路由 /api/keys：返回账户本分钟的下单额度使用情况与各下单 Key 分担的请求数
+func (k *apiKeys) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(k.orders.Stats())
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/latency"
+	"BinanceAutoBot2/internal/logging"
+)
+
+var latencyLog = logging.For("latency")
+
+// 延迟统计项
+const (
+	latExchangeToReceive = "exchange_to_receive" // 深度事件交易所事件时间 E → 网关收到 (含时钟偏差)
+	latReceiveToPublish  = "receive_to_publish"  // 网关收到深度事件 → 共享内存 / Redis / 事件流写完
+	latUDSToSend         = "uds_to_rest_send"    // UDS 收到下单请求 → 调用 REST 发单
+	latRESTRoundTrip     = "rest_round_trip"     // 单次 REST 请求往返 (含重试的每一次)
+	latWSAPIRoundTrip    = "ws_api_round_trip"   // 单次 WebSocket API 请求往返
+	latOrderToAck        = "order_to_ack"        // REST 发单 → 私有流收到该订单的第一条推送
+)
+
+// latencyNames 固定的输出顺序
+var latencyNames = []string{latExchangeToReceive, latReceiveToPublish, latUDSToSend, latRESTRoundTrip, latWSAPIRoundTrip, latOrderToAck}
+
+// latencyLogInterval 周期性汇总日志的间隔
+const latencyLogInterval = time.Minute
+
+// pendingAckTTL 发单后超过该时长仍未收到推送的订单不再等待 (如被拒单)
+const pendingAckTTL = time.Minute
+
+// latencyTracker 网关内各段延迟的直方图，GET /api/latency 输出累计分布，每分钟打印一次本周期汇总
+type latencyTracker struct {
+	hists map[string]*latency.Histogram
+
+	mu      sync.Mutex
+	pending map[string]time.Time // clientOrderId → REST 发单时间，等待第一条私有流推送
+	last    map[string]latency.Snapshot
+}
+
+func newLatencyTracker() *latencyTracker {
+	l := &latencyTracker{
+		hists:   make(map[string]*latency.Histogram, len(latencyNames)),
+		pending: make(map[string]time.Time),
+		last:    make(map[string]latency.Snapshot),
+	}
+	for _, name := range latencyNames {
+		l.hists[name] = latency.NewHistogram()
+	}
+	return l
+}
+
+// Observe 记录一次耗时
+func (l *latencyTracker) Observe(name string, d time.Duration) {
+	l.hists[name].Observe(d)
+}
+
+// OnRoundTrip 作为 APIClient / WSAPIClient 的 OnRoundTrip 回调；网络错误 (无响应) 不计入往返延迟
+func (l *latencyTracker) OnRoundTrip(rt binance.RoundTrip) {
+	switch {
+	case rt.Status == 0:
+	case rt.Method == "WS":
+		l.Observe(latWSAPIRoundTrip, rt.Duration)
+	default:
+		l.Observe(latRESTRoundTrip, rt.Duration)
+	}
+}
+
+// OrderSent 记录订单的 REST 发单时间，等待私有流确认
+func (l *latencyTracker) OrderSent(clientOrderID string, at time.Time) {
+	if clientOrderID == "" {
+		return
+	}
+	l.mu.Lock()
+	l.pending[clientOrderID] = at
+	l.mu.Unlock()
+}
+
+// OnOrderUpdate 私有流 ORDER_TRADE_UPDATE 到达时调用，每笔订单只统计第一条推送
+func (l *latencyTracker) OnOrderUpdate(o *binance.OrderTradeUpdate) {
+	if o == nil {
+		return
+	}
+	l.mu.Lock()
+	sent, ok := l.pending[o.ClientOrderID]
+	if ok {
+		delete(l.pending, o.ClientOrderID)
+	}
+	l.mu.Unlock()
+	if ok {
+		l.Observe(latOrderToAck, time.Since(sent))
+	}
+}
+
+// Run 周期性打印各段延迟的本周期汇总，并清理等待过久的订单
+func (l *latencyTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(latencyLogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.logSummary()
+			l.expirePending()
+		}
+	}
+}
+
+func (l *latencyTracker) logSummary() {
+	for _, name := range latencyNames {
+		cur := l.hists[name].Snapshot()
+		delta := cur.Sub(l.last[name])
+		l.last[name] = cur
+		if delta.Count == 0 {
+			continue
+		}
+		latencyLog.Info("延迟汇总", "stage", name, "n", delta.Count, "avg", fmtDuration(delta.Mean()),
+			"p50", fmtDuration(delta.Quantile(0.5)), "p90", fmtDuration(delta.Quantile(0.9)), "p99", fmtDuration(delta.Quantile(0.99)))
+	}
+}
+
+func (l *latencyTracker) expirePending() {
+	cutoff := time.Now().Add(-pendingAckTTL)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, sent := range l.pending {
+		if sent.Before(cutoff) {
+			delete(l.pending, id)
+		}
+	}
+}
+
+func fmtDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+// latencyReport /api/latency 的单项输出，时间单位为毫秒
+type latencyReport struct {
+	Count   uint64          `json:"count"`
+	MeanMs  float64         `json:"mean_ms"`
+	P50Ms   float64         `json:"p50_ms"`
+	P90Ms   float64         `json:"p90_ms"`
+	P99Ms   float64         `json:"p99_ms"`
+	MaxMs   float64         `json:"max_ms"`
+	Buckets []latencyBucket `json:"buckets"`
+}
+
+// latencyBucket 累计桶：耗时 <= LeMs 的次数，最后一个桶 LeMs 为 -1 表示 +Inf
+type latencyBucket struct {
+	LeMs  float64 `json:"le_ms"`
+	Count uint64  `json:"count"`
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// ServeHTTP 处理 GET /api/latency
+func (l *latencyTracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	out := make(map[string]latencyReport, len(latencyNames))
+	for _, name := range latencyNames {
+		s := l.hists[name].Snapshot()
+		rep := latencyReport{
+			Count:  s.Count,
+			MeanMs: ms(s.Mean()),
+			P50Ms:  ms(s.Quantile(0.5)),
+			P90Ms:  ms(s.Quantile(0.9)),
+			P99Ms:  ms(s.Quantile(0.99)),
+			MaxMs:  ms(s.Max),
+		}
+		var cum uint64
+		for i, c := range s.Counts {
+			cum += c
+			le := -1.0
+			if i < len(s.Bounds) {
+				le = ms(s.Bounds[i])
+			}
+			rep.Buckets = append(rep.Buckets, latencyBucket{LeMs: le, Count: cum})
+		}
+		out[name] = rep
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/ledger"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/pnl"
+)
+
+var ledgerLog = logging.For("ledger")
+
+// ledgerSync 把下单指令、交易所回执、成交与撤单写入持久化账本
+// store 为 nil 时 (未配置 ledger.path) 所有记录操作为空操作
+type ledgerSync struct {
+	store   *ledger.Store
+	writer  *ledger.Writer // 异步批量写入，下单链路上不等待 fsync
+	account string         // 多账户模式下写入每条记录的 account 字段
+}
+
+// openLedgerSync 打开账本并启动异步写协程
+func openLedgerSync(path string) (*ledgerSync, error) {
+	store, err := ledger.Open(path, false)
+	if err != nil {
+		return nil, err
+	}
+	writer := ledger.NewWriter(store, func(err error, entries []ledger.Entry) {
+		ledgerLog.Error("账本批量写入失败", "entries", len(entries), "first_kind", entries[0].Kind,
+			"first_client_order_id", entries[0].ClientOrderID, logging.Err(err))
+	})
+	return &ledgerSync{store: store, writer: writer}, nil
+}
+
+// Close 提交缓冲中的记录后关闭账本文件
+func (l *ledgerSync) Close() {
+	if l.store == nil {
+		return
+	}
+	l.writer.Close()
+	l.store.Close()
+}
+
+// WithAccount 返回记录到指定账户的 ledgerSync，共用同一个账本文件与写协程
+func (l *ledgerSync) WithAccount(account string) *ledgerSync {
+	return &ledgerSync{store: l.store, writer: l.writer, account: account}
+}
+
+// Record 补齐策略标识后放入写入队列；提交在写协程中完成，写入失败只记日志，不影响交易链路
+func (l *ledgerSync) Record(e ledger.Entry) {
+	if l.store == nil {
+		return
+	}
+	if e.Account == "" {
+		e.Account = l.account
+	}
+	if e.Strategy == "" && e.ClientOrderID != "" {
+		e.Strategy = pnl.StrategyFromClientOrderID(e.ClientOrderID)
+	}
+	if !l.writer.Append(e) {
+		ledgerLog.Warn("账本已关闭，记录被丢弃", "kind", e.Kind, "client_order_id", e.ClientOrderID)
+	}
+}
+
+// RecordError 记录下单 / 撤单失败
+func (l *ledgerSync) RecordError(e ledger.Entry, err error) {
+	e.Error = err.Error()
+	l.Record(e)
+}
+
+// ApplyEvent 记录 ORDER_TRADE_UPDATE 中的成交与撤销
+func (l *ledgerSync) ApplyEvent(event binance.UserDataEvent) {
+	if event.EventType != "ORDER_TRADE_UPDATE" {
+		return
+	}
+	if e, ok := ledger.FromOrderUpdate(event.Order); ok {
+		l.Record(e)
+	}
+}
+
+// ServeHTTP 实现 UDS 查询路由 /api/ledger
+// 参数：account、strategy、symbol、kind、from / to (毫秒时间戳或 RFC3339)、limit
+func (l *ledgerSync) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if l.store == nil {
+		http.Error(w, "ledger disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := ledger.Filter{
+		Account:  q.Get("account"),
+		Strategy: q.Get("strategy"),
+		Symbol:   q.Get("symbol"),
+		Kind:     q.Get("kind"),
+	}
+	var err error
+	if filter.From, err = ledger.ParseTime(q.Get("from")); err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = ledger.ParseTime(q.Get("to")); err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := l.store.Query(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []ledger.Entry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/strategy"
+)
+
+// enforceSymbolSettings 启动时校验交易所的杠杆与保证金模式是否与 config.json 一致
+// 默认只校验：任何一个交易对不一致都返回错误，由 main 直接退出，绝不带着错误的杠杆开始交易；
+// apply 为 true (binance.apply_symbol_settings) 时才把不一致的设置改成配置值
+func enforceSymbolSettings(apiClient *binance.APIClient, symbols map[string]config.SymbolConfig, apply bool) error {
+	for symbol, want := range symbols {
+		positions, err := apiClient.GetPositions(symbol)
+		if err != nil {
+			return fmt.Errorf("%s 查询当前杠杆失败: %w", symbol, err)
+		}
+		if len(positions) == 0 {
+			return fmt.Errorf("%s 未返回任何持仓配置，请确认交易对名称", symbol)
+		}
+		current := positions[0]
+
+		// positionRisk 返回小写的 cross / isolated，与下单参数的 CROSSED / ISOLATED 不同
+		currentMarginType := binance.MarginTypeCrossed
+		if strings.EqualFold(current.MarginType, "isolated") {
+			currentMarginType = binance.MarginTypeIsolated
+		}
+		if want.MarginType != "" && want.MarginType != currentMarginType {
+			if !apply {
+				return fmt.Errorf("%s 保证金模式与配置不一致 (当前 %s, 期望 %s)，请手动调整，或设置 binance.apply_symbol_settings=true 由网关切换",
+					symbol, currentMarginType, want.MarginType)
+			}
+			mainLog.Warn("按配置切换保证金模式", "symbol", symbol, "from", currentMarginType, "to", want.MarginType)
+			if err := apiClient.ChangeMarginType(symbol, want.MarginType); err != nil {
+				return fmt.Errorf("%s 保证金模式与配置不一致 (当前 %s, 期望 %s)，且切换失败 (有持仓或挂单时无法切换): %w",
+					symbol, currentMarginType, want.MarginType, err)
+			}
+		}
+
+		if want.Leverage > 0 && want.Leverage != current.Leverage {
+			if !apply {
+				return fmt.Errorf("%s 杠杆与配置不一致 (当前 %dx, 期望 %dx)，请手动调整，或设置 binance.apply_symbol_settings=true 由网关调整",
+					symbol, current.Leverage, want.Leverage)
+			}
+			mainLog.Warn("按配置调整杠杆", "symbol", symbol, "from", current.Leverage, "to", want.Leverage)
+			resp, err := apiClient.ChangeLeverage(symbol, want.Leverage)
+			if err != nil {
+				return fmt.Errorf("%s 杠杆与配置不一致 (当前 %dx, 期望 %dx)，且调整失败: %w",
+					symbol, current.Leverage, want.Leverage, err)
+			}
+			if resp.Leverage != want.Leverage {
+				return fmt.Errorf("%s 杠杆调整后仍不一致: 交易所返回 %dx, 期望 %dx", symbol, resp.Leverage, want.Leverage)
+			}
+		}
+
+		mainLog.Info("账户设置与配置一致", "symbol", symbol, "leverage", want.Leverage, "margin_type", want.MarginType)
+	}
+	return nil
+}
+
+// registerMarginRoutes 注册杠杆、保证金模式与逐仓保证金调整的 UDS 路由，请求体中的 account 选择操作的账户；
+// 策略注册表启用时请求必须声明已注册的 strategy，账户与交易对受该策略的白名单约束
+func registerMarginRoutes(set *accountSet, strategies *strategy.Registry) {
+	http.HandleFunc("/api/leverage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Account  string `json:"account"`
+			Strategy string `json:"strategy"`
+			Symbol   string `json:"symbol"`
+			Leverage int    `json:"leverage"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Symbol == "" || req.Leverage <= 0 {
+			http.Error(w, "symbol and leverage are required", http.StatusBadRequest)
+			return
+		}
+		a, ok := set.resolveSymbol(w, req.Account, req.Symbol)
+		if !ok {
+			return
+		}
+		if err := strategies.AuthorizeSettings(req.Strategy, a.name, req.Symbol); err != nil {
+			writeStrategyError(w, "leverage", err)
+			return
+		}
+
+		resp, err := a.api.ChangeLeverage(req.Symbol, req.Leverage)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			udsLog.Error("杠杆调整失败", "account", a.name, "symbol", req.Symbol, logging.Err(err))
+			writeOrderError(w, err)
+			return
+		}
+		udsLog.Info("杠杆已调整", "account", a.name, "symbol", resp.Symbol, "leverage", resp.Leverage)
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	http.HandleFunc("/api/margin-type", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Account    string `json:"account"`
+			Strategy   string `json:"strategy"`
+			Symbol     string `json:"symbol"`
+			MarginType string `json:"margin_type"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Symbol == "" {
+			http.Error(w, "symbol is required", http.StatusBadRequest)
+			return
+		}
+		if req.MarginType != binance.MarginTypeIsolated && req.MarginType != binance.MarginTypeCrossed {
+			http.Error(w, "margin_type must be ISOLATED or CROSSED", http.StatusBadRequest)
+			return
+		}
+		a, ok := set.resolveSymbol(w, req.Account, req.Symbol)
+		if !ok {
+			return
+		}
+		if err := strategies.AuthorizeSettings(req.Strategy, a.name, req.Symbol); err != nil {
+			writeStrategyError(w, "margin_type", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := a.api.ChangeMarginType(req.Symbol, req.MarginType); err != nil {
+			udsLog.Error("保证金模式切换失败", "account", a.name, "symbol", req.Symbol, logging.Err(err))
+			writeOrderError(w, err)
+			return
+		}
+		udsLog.Info("保证金模式已切换", "account", a.name, "symbol", req.Symbol, "margin_type", req.MarginType)
+		json.NewEncoder(w).Encode(map[string]string{"symbol": req.Symbol, "margin_type": req.MarginType})
+	})
+
+	http.HandleFunc("/api/position-margin", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Account      string  `json:"account"`
+			Strategy     string  `json:"strategy"`
+			Symbol       string  `json:"symbol"`
+			PositionSide string  `json:"position_side"`
+			Amount       float64 `json:"amount"`
+			Action       string  `json:"action"` // "ADD" 或 "REDUCE"
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Symbol == "" || req.Amount <= 0 {
+			http.Error(w, "symbol and positive amount are required", http.StatusBadRequest)
+			return
+		}
+		if req.Action != "ADD" && req.Action != "REDUCE" {
+			http.Error(w, "action must be ADD or REDUCE", http.StatusBadRequest)
+			return
+		}
+		a, ok := set.resolveSymbol(w, req.Account, req.Symbol)
+		if !ok {
+			return
+		}
+		if err := strategies.AuthorizeSettings(req.Strategy, a.name, req.Symbol); err != nil {
+			writeStrategyError(w, "position_margin", err)
+			return
+		}
+
+		resp, err := a.api.ModifyPositionMargin(binance.PositionMarginRequest{
+			Symbol:       req.Symbol,
+			PositionSide: req.PositionSide,
+			Amount:       req.Amount,
+			Add:          req.Action == "ADD",
+		})
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			udsLog.Error("逐仓保证金调整失败", "account", a.name, "symbol", req.Symbol, logging.Err(err))
+			writeOrderError(w, err)
+			return
+		}
+		udsLog.Info("逐仓保证金已调整", "account", a.name, "symbol", req.Symbol, "action", req.Action, "amount", req.Amount)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -3,21 +3,36 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"log"
-	"net"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/codec"
 	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/events"
+	"BinanceAutoBot2/internal/ledger"
+	"BinanceAutoBot2/internal/logging"
 	"BinanceAutoBot2/internal/orderbook"
+	"BinanceAutoBot2/internal/shmbook"
+	"BinanceAutoBot2/internal/strategy"
+	"BinanceAutoBot2/internal/udsauth"
 
 	"github.com/redis/go-redis/v9"
 )
 
+var (
+	mainLog = logging.For("main")
+	udsLog  = logging.For("uds")
+	// depthLog 逐条深度推送的高频路径，按消息采样
+	depthLog = logging.Sampled("depth")
+)
+
 // LocalCommandReq 接收来自 Python 大脑的极简指令
 type LocalCommandReq struct {
 	Side     string  `json:"side"`     // "BUY" 或 "SELL"
@@ -29,162 +44,163 @@ func main() {
 	// 1. 加载配置
 	cfg, err := config.LoadConfig("config.json")
 	if err != nil {
-		log.Fatalf("[Main] 读取配置失败: %v", err)
+		fatal(mainLog, "读取配置失败", logging.Err(err))
+	}
+	if err := logging.Setup(logging.Options{
+		Level:          cfg.Log.Level,
+		Format:         cfg.Log.Format,
+		Components:     cfg.Log.Components,
+		SampleInterval: time.Duration(cfg.Log.SampleIntervalMs) * time.Millisecond,
+	}); err != nil {
+		fatal(mainLog, "日志配置错误", logging.Err(err))
 	}
 	activeEnv := cfg.Binance.GetActiveEnv()
 	symbol := cfg.Binance.Symbol
 
-	log.Printf("🚀 Starting Binance Gateway [%s] for %s...", cfg.Binance.ActiveEnv, symbol)
+	mainLog.Info("网关启动", "env", cfg.Binance.ActiveEnv, "symbol", symbol)
 
-	// ==========================================
-	// 🚨 修复点：在这里初始化 apiClient！
-	// ==========================================
-	apiClient := binance.NewAPIClient(activeEnv.APIKey, activeEnv.APISecret)
-	apiClient.BaseURL = activeEnv.RestBaseURL
-	// ==========================================
+	// ⏱️ 新增：端到端延迟直方图 (行情接收 / 发布、UDS → REST、REST 往返、发单 → 私有流确认)
+	lat := newLatencyTracker()
 
 	// 2. 初始化 Redis
-	rdb := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, DB: cfg.Redis.DB})
+	redisOpts := &redis.Options{Addr: cfg.Redis.Addr, DB: cfg.Redis.DB}
+	if cfg.Redis.Optional {
+		// Redis 可选时缩短拨号超时并关闭重试，Redis 不在线也不会拖慢 WS 回调
+		redisOpts.DialTimeout = 50 * time.Millisecond
+		redisOpts.MaxRetries = -1
+	}
+	rdb := redis.NewClient(redisOpts)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		log.Fatalf("[Main] Redis 连接失败: %v", err)
+		if !cfg.Redis.Optional {
+			fatal(mainLog, "Redis 连接失败", "addr", cfg.Redis.Addr, logging.Err(err))
+		}
+		mainLog.Warn("Redis 不可用 (redis.optional=true，继续启动，状态仅通过 UDS 提供)", "addr", cfg.Redis.Addr, logging.Err(err))
+	} else {
+		mainLog.Info("Redis 已连接", "addr", cfg.Redis.Addr)
 	}
-	log.Println("[Main] ✅ Redis connected.")
 
 	// ==========================================
-	// 🌟 新增优化：系统启动时，主动拉取一次真实余额进行“兜底初始化”
-	// 彻底解决系统刚启动时 Redis 里没有资金数据的真空期问题
+	// 📒 新增：持久化交易账本 (下单指令 / 回执 / 成交 / 撤单)，重启后仍可审计与复盘
 	// ==========================================
-	if initialBalance, err := apiClient.GetUSDTBalance(); err == nil {
-		// 直接将查询到的初始余额刷入 Redis
-		_ = rdb.Set(ctx, "Wallet:USDT", initialBalance, 0).Err()
-		log.Printf("[Main] 💰 初始资金盘点完成: 当前可用 USDT = %s", initialBalance)
+	journal := &ledgerSync{}
+	if cfg.Ledger.Path != "" {
+		journal, err = openLedgerSync(cfg.Ledger.Path)
+		if err != nil {
+			fatal(mainLog, "账本打开失败", "path", cfg.Ledger.Path, logging.Err(err))
+		}
+		defer journal.Close()
+		mainLog.Info("交易账本已打开", "path", cfg.Ledger.Path)
 	} else {
-		log.Printf("[Main] ⚠️ 初始资金盘点失败: %v", err)
+		mainLog.Warn("未配置 ledger.path，交易记录不会持久化")
 	}
-	// ==========================================
 
 	// ==========================================
-	// 🌟 新增：初始仓位兜底盘点
+	// 📡 新增：事件推送到 Redis Streams，策略端可 XREAD BLOCK 阻塞等待，不再轮询 Key；
+	// 同时投递给进程内 Hub，同机策略可直接通过 UDS /api/stream (SSE) 订阅
 	// ==========================================
-	// 🌟 修改處 1：初始倉位兜底盤點 (約 80 行附近)
-	if initialPos, initialEp, err := apiClient.GetPosition(symbol); err == nil {
-		_ = rdb.Set(ctx, "Position:"+symbol, initialPos, 0).Err()
-		_ = rdb.Set(ctx, "EntryPrice:"+symbol, initialEp, 0).Err() // 寫入均價
-		log.Printf("[Main] 📦 初始倉位盤點: %s 持倉 = %s | 均價 = %s", symbol, initialPos, initialEp)
-	} else {
-		log.Printf("[Main] ⚠️ 初始仓位盘点失败: %v", err)
+	hub := events.NewHub()
+	var streamsRdb *redis.Client
+	if cfg.Redis.Streams.Enabled {
+		streamsRdb = rdb
+		mainLog.Info("Redis Streams 推送已开启", "max_len", cfg.Redis.Streams.MaxLen)
 	}
-	// ==========================================
+	streams := events.NewPublisher(streamsRdb, cfg.Redis.Streams.MaxLen, hub)
 
 	// ==========================================
-	// 🌟 新增：启动私有资产监听通道，并同步至 Redis
+	// 👥 新增：按账户隔离的交易状态 (API Key、持仓、账户模型、盈亏、对账、私有流)；
+	// 未配置 accounts 时只有一个 default 账户，Redis Key 与之前完全一致
 	// ==========================================
-	listenKey, err := apiClient.GetListenKey()
-	if err != nil {
-		log.Printf("[Main] ⚠️ 获取 ListenKey 失败 (可能 API Key 权限不足): %v", err)
-	} else {
-		// 动态判断当前环境的 WebSocket 域名
-		wsBase := "wss://stream.binancefuture.com/ws/" // 默认测试网
-		if cfg.Binance.ActiveEnv == "mainnet" {
-			wsBase = "wss://fstream.binance.com/ws/" // 主网
-		}
-		userDataWSURL := wsBase + listenKey
-
-		// 🌟 修复点：将 event.Event 改为 event.EventType
-		go binance.StartUserDataStream(ctx, userDataWSURL, func(event binance.UserDataEvent) {
-			// 🌟 把 event.Event 改成 event.EventType
-			if event.EventType == "ACCOUNT_UPDATE" {
-				// 1. 同步最新钱包余额
-				for _, bal := range event.Account.Balances {
-					if bal.Asset == "USDT" {
-						_ = rdb.Set(ctx, "Wallet:USDT", bal.Balance, 0).Err()
-						log.Printf("💰 [Redis同步] 余额覆写 -> USDT: %s", bal.Balance)
-					}
-				}
+	// 🧾 新增：策略注册表，UDS 请求声明 strategy 后按各策略的交易对、名义价值、持仓与频率限额拦截
+	strategies := newStrategyRegistry(cfg.Strategies)
 
-				// 2. 同步最新仓位与均价
-				for _, pos := range event.Account.Positions {
-					if pos.Symbol == symbol {
-						_ = rdb.Set(ctx, "Position:"+symbol, pos.Amount, 0).Err()
-						_ = rdb.Set(ctx, "EntryPrice:"+symbol, pos.EntryPrice, 0).Err()
-						log.Printf("💾 [Redis同步] 仓位覆写 -> %s: 数量 %s (均价: %s)", symbol, pos.Amount, pos.EntryPrice)
-					}
-				}
-			}
-		})
+	var list []*tradingAccount
+	for _, ta := range cfg.TradingAccounts() {
+		a, err := newTradingAccount(ctx, ta, cfg.MultiAccount(), symbol, rdb, streams, journal, strategies, lat)
+		if err != nil {
+			fatal(mainLog, "账户初始化失败，拒绝启动", "account", ta.Name, logging.Err(err))
+		}
+		list = append(list, a)
+	}
+	set := newAccountSet(cfg.MultiAccount(), list)
 
-		// 每 30 分钟续期 ListenKey，防止 60 分钟后私有流断开
-		go func() {
-			ticker := time.NewTicker(30 * time.Minute)
-			defer ticker.Stop()
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case <-ticker.C:
-					if err := apiClient.RenewListenKey(listenKey); err != nil {
-						log.Printf("[Main] ⚠️ ListenKey 续期失败: %v", err)
-					} else {
-						log.Printf("[Main] ✅ ListenKey 续期成功")
-					}
-				}
-			}
-		}()
+	// 🩺 新增：组件健康状态 (行情连接、盘口同步、私有流、ListenKey、Redis、时钟偏差)，/healthz 与 /readyz 输出
+	health := newHealthMonitor(list[0].api, rdb, cfg.Redis.Optional)
+	go health.Run(ctx)
+	for _, a := range list {
+		a.Run(ctx, health)
+	}
 
-		// ==========================================
-		// 🛡️ 新增：企業級狀態對帳協程 (State Reconciliation)
-		// 目的：每 5 分鐘強制拉取一次 REST API 真實狀態，防止 WS 漏接導致的「幽靈倉位」
-		// ==========================================
-		go func() {
-			// 設定每 5 分鐘對帳一次 (頻率不要太高，以免消耗 API 權重)
-			ticker := time.NewTicker(5 * time.Minute)
-			defer ticker.Stop()
-
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case <-ticker.C:
-					log.Println("⏱️ [定時對帳] 啟動 REST API 狀態兜底同步...")
-
-					// 1. 強制核對並覆寫錢包餘額
-					if bal, err := apiClient.GetUSDTBalance(); err == nil {
-						_ = rdb.Set(ctx, "Wallet:USDT", bal, 0).Err()
-					} else {
-						log.Printf("⚠️ [定時對帳] 餘額同步失敗: %v", err)
-					}
-
-					// 2. 強制核對並覆寫真實倉位與均價
-					if posAmount, posEp, err := apiClient.GetPosition(symbol); err == nil {
-						_ = rdb.Set(ctx, "Position:"+symbol, posAmount, 0).Err()
-						_ = rdb.Set(ctx, "EntryPrice:"+symbol, posEp, 0).Err()
-						// log.Printf("⏱️ [定時對帳] 倉位核對完成 -> %s: 數量 %s", symbol, posAmount) // 怕日誌太吵可以註解掉這行
-					} else {
-						log.Printf("⚠️ [定時對帳] 倉位同步失敗: %v", err)
-					}
-				}
+	// ==========================================
+	// 📈 新增：标记价格驱动各账户的未实现盈亏与策略市价单的名义价值估算；同一环境的账户共用一条标记价格推送
+	// ==========================================
+	markPriceURLs := make(map[string][]*pnlSync)
+	for _, a := range list {
+		if a.env.WSMarkPriceURL == "" {
+			a.log.Warn("未配置 ws_mark_price_url，未实现盈亏将按开仓均价计算 (恒为 0)")
+			continue
+		}
+		markPriceURLs[a.env.WSMarkPriceURL] = append(markPriceURLs[a.env.WSMarkPriceURL], a.pnl)
+	}
+	for url, trackers := range markPriceURLs {
+		go binance.StartMarkPriceStream(ctx, url, func(event binance.MarkPriceEvent) {
+			if price, err := strconv.ParseFloat(event.MarkPrice, 64); err == nil {
+				strategies.OnMarkPrice(event.Symbol, price)
 			}
-		}()
-		// ==========================================
+			for _, t := range trackers {
+				t.OnMarkPrice(ctx, event)
+			}
+		}, metrics.ConnectHook("mark_price", nil))
 	}
-	// ==========================================
 
 	// 3. 启动行情状态机
 	// 3. 启动行情状态机 (🌟 升级为完全事件驱动的零延迟架构)
 	ob := orderbook.NewLocalOrderBook(symbol)
-	redisKey := "OrderBook:" + symbol
+	bookEnc, err := codec.New(cfg.Redis.BookFormat)
+	if err != nil {
+		fatal(mainLog, "盘口编码配置错误", logging.Err(err))
+	}
+	mainLog.Info("盘口编码格式", "format", bookEnc.Format(), "version", codec.BookVersion)
+
+	// 🧠 新增：同机低延迟通道，TopN 盘口写入 /dev/shm，消费端 mmap 直接读内存
+	var shmWriter *shmbook.Writer
+	if cfg.SharedMemory.Enabled {
+		shmPath := shmbook.Path(cfg.SharedMemory.Dir, symbol)
+		if shmWriter, err = shmbook.Create(shmPath, cfg.SharedMemory.Depth); err != nil {
+			fatal(mainLog, "共享内存盘口创建失败", "path", shmPath, logging.Err(err))
+		}
+		defer shmWriter.Close()
+		mainLog.Info("共享内存盘口已开启", "path", shmPath, "depth", shmWriter.Depth())
+	}
+
+	// ⏱️ 新增：快照携带交易所 / 接收 / 发布时间与 SYNCED / RESYNCING / STALE 状态，停滞时标记 STALE
+	book := newBookPublisher(ob, rdb, streams, shmWriter, bookEnc,
+		time.Duration(cfg.MarketData.StaleAfterMs)*time.Millisecond,
+		time.Duration(cfg.MarketData.KeyTTLMs)*time.Millisecond,
+		cfg.MarketData.DeleteOnStale)
+	go book.Run(ctx)
+	go lat.Run(ctx)
+	health.WatchBook(ob, book)
 
 	wsClient := &binance.WSClient{
-		URL: activeEnv.WSDepthURL,
+		URL:          activeEnv.WSDepthURL,
+		OnConnect:    metrics.ConnectHook("depth", health.DepthConnected),
+		OnDisconnect: health.DepthDisconnected,
 		OnDepthFunc: func(event binance.WSDepthEvent) {
+			book.OnEvent()
+			metrics.depthEvents.WithLabelValues(symbol).Inc()
+			lat.Observe(latExchangeToReceive, event.ReceivedAt.Sub(time.UnixMilli(event.EventTime)))
+
 			// 1. 毫秒级处理增量事件
 			_ = ob.ProcessDepthEvent(event)
 
 			// 2. 线程安全地检测序列号断层，重新拉取快照
 			if ob.CheckAndClearResync() {
-				log.Printf("[Main] 🔄 OrderBook 断层，重新拉取快照...")
+				depthLog.Warn("盘口序列号断层，重新拉取快照", "symbol", symbol, "update_id", event.FinalUpdateID)
+				metrics.resyncs.WithLabelValues(symbol).Inc()
+				// 先广播 RESYNCING，策略端立即停止使用旧盘口
+				book.Publish(ctx)
 				if snap, err := binance.GetDepthSnapshot(activeEnv.RestBaseURL, symbol, 1000); err == nil {
 					ob.InitWithSnapshot(snap)
 				}
@@ -193,11 +209,10 @@ func main() {
 
 			// 3. 🌟 绝对的零延迟：只要状态机 Ready，立马刷入 Redis！不等任何 Ticker！
 			if ob.IsReady && ob.Synced {
-				data, _ := json.Marshal(ob.GetTopN(20))
-				// 使用一个极短的 context 防止 Redis 阻塞 WS 接收协程
-				rCtx, rCancel := context.WithTimeout(ctx, 50*time.Millisecond)
-				_ = rdb.Set(rCtx, redisKey, data, 0).Err()
-				rCancel()
+				book.Publish(ctx)
+				elapsed := time.Since(event.ReceivedAt)
+				lat.Observe(latReceiveToPublish, elapsed)
+				depthLog.Debug("盘口已发布", "symbol", symbol, "update_id", event.FinalUpdateID, "latency", elapsed)
 			}
 		},
 	}
@@ -209,37 +224,104 @@ func main() {
 	if err == nil {
 		ob.InitWithSnapshot(snapshot)
 	} else {
-		log.Printf("[Main] ⚠️ 快照拉取失败 (测试网拥堵): %v", err)
+		mainLog.Warn("盘口快照拉取失败", "symbol", symbol, logging.Err(err))
 	}
 
 	// 5. 【核心】启动 UDS (Unix Domain Socket) HTTP 指令接收器
 	// 🌟 增强版：UDS HTTP 服务的处理逻辑 (带极详尽的日志打印)
 	http.HandleFunc("/api/order", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		udsStart := time.Now()
 		var req struct {
-			Symbol   string  `json:"symbol"`
-			Side     string  `json:"side"`
-			Quantity float64 `json:"quantity"`
-			Price    float64 `json:"price"`
+			Account       string  `json:"account"`  // 多账户模式下必填
+			Strategy      string  `json:"strategy"` // 配置了策略注册表时必填，同时作为 clientOrderId 前缀
+			Symbol        string  `json:"symbol"`
+			Side          string  `json:"side"`
+			Type          string  `json:"type"`
+			Quantity      float64 `json:"quantity"`
+			Price         float64 `json:"price"`
+			PositionSide  string  `json:"position_side"`   // 双向持仓模式下必填：LONG 或 SHORT
+			ClientOrderID string  `json:"client_order_id"` // 可选：由 Python 指定，便于超时后按 ID 追踪
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			log.Printf("❌ [UDS 接收] 解析 Python 指令失败: %v", err)
+			udsLog.Warn("下单请求解析失败", logging.Err(err))
 			http.Error(w, "解析请求失败", http.StatusBadRequest)
 			return
 		}
 		// 🚨 1. 新增：嚴格校驗與防呆攔截
 		if req.Symbol == "" {
-			log.Printf("❌ [嚴重錯誤] 拒絕發單！Python 傳來的 UDS 指令中 'symbol' 是空的！")
+			udsLog.Warn("拒绝下单: symbol 为空")
 			http.Error(w, "symbol cannot be empty", http.StatusBadRequest)
 			return
 		}
+		a, ok := set.resolveSymbol(w, req.Account, req.Symbol)
+		if !ok {
+			return
+		}
+		positions := a.positions
 
-		// 🚨 2. 修改：把 Python 傳來的真實 Symbol 也打印出來確認！
-		log.Printf("🤖 [UDS 接收] 收到 Python 引擎指令: [%s] %s %.4f @ %.2f", req.Symbol, req.Side, req.Quantity, req.Price)
+		// 🚨 双向持仓模式下，币安要求每笔订单声明 positionSide，否则会以 -4061 拒单
+		if positions.DualSide() && req.PositionSide != "LONG" && req.PositionSide != "SHORT" {
+			udsLog.Warn("拒绝下单: 双向持仓模式下 position_side 必须为 LONG 或 SHORT", "symbol", req.Symbol, "position_side", req.PositionSide)
+			http.Error(w, "position_side must be LONG or SHORT in hedge mode", http.StatusBadRequest)
+			return
+		}
+		if !positions.DualSide() && req.PositionSide != "" && req.PositionSide != "BOTH" {
+			http.Error(w, "position_side is only allowed in hedge mode", http.StatusBadRequest)
+			return
+		}
 
-		startTime := time.Now()
+		// 先分配 clientOrderId，保证账本中的指令、回执与后续成交能关联到同一笔订单
+		if req.ClientOrderID == "" {
+			req.ClientOrderID = binance.NewClientOrderID(clientOrderIDPrefix(req.Strategy))
+		}
+		orderLog := udsLog.With("account", a.name, "strategy", req.Strategy, "symbol", req.Symbol, "client_order_id", req.ClientOrderID)
+		orderLog.Info("收到下单请求", "side", req.Side, "type", req.Type, "quantity", req.Quantity, "price", req.Price)
+		requestEntry := ledger.Entry{
+			Kind:          ledger.KindOrderRequest,
+			Symbol:        req.Symbol,
+			Side:          req.Side,
+			PositionSide:  req.PositionSide,
+			OrderType:     req.Type,
+			ClientOrderID: req.ClientOrderID,
+			Quantity:      req.Quantity,
+			Price:         req.Price,
+		}
+		a.journal.Record(requestEntry)
+
+		// 🧾 策略身份与限额校验，通过后计入该策略的下单频率与挂单敞口
+		if err := strategies.AuthorizePlace(req.Strategy, strategy.Order{
+			Account:       a.name,
+			Symbol:        req.Symbol,
+			Side:          req.Side,
+			ClientOrderID: req.ClientOrderID,
+			Quantity:      req.Quantity,
+			Price:         req.Price,
+		}); err != nil {
+			requestEntry.Kind = ledger.KindOrderReject
+			a.journal.RecordError(requestEntry, err)
+			writeStrategyError(w, "place", err)
+			return
+		}
 
-		// 调用 API 客户端发起真实的交易请求
-		respData, err := apiClient.PlaceOrder(req.Symbol, req.Side, "LIMIT", req.Quantity, req.Price)
+		startTime := time.Now()
+		lat.Observe(latUDSToSend, startTime.Sub(udsStart))
+		lat.OrderSent(req.ClientOrderID, startTime)
+
+		// 调用下单通道发起真实的交易请求 (内部自带重试与超时查单)
+		order, err := a.orders.PlaceOrder(binance.OrderRequest{
+			Symbol:           req.Symbol,
+			Side:             req.Side,
+			Type:             req.Type,
+			Quantity:         req.Quantity,
+			Price:            req.Price,
+			PositionSide:     req.PositionSide,
+			NewClientOrderID: req.ClientOrderID,
+		})
+		metrics.OrderResult("place", err)
 
 		w.Header().Set("Content-Type", "application/json")
 
@@ -247,49 +329,241 @@ func main() {
 		// 🚨 核心修改：极详尽的失败与成功日志打印
 		// ==========================================
 		if err != nil {
-			// 如果发单失败，极其醒目地打印币安返回的真实报错（例如 Insufficient Margin）
-			log.Printf("❌ [执行失败] 极速发单被币安拒绝！耗时: %v", time.Since(startTime))
-			log.Printf("⚠️ [错误详情]: %v", err)
-
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": err.Error(),
-			})
+			// 发单失败时记录币安返回的真实报错（例如 Insufficient Margin）
+			orderLog.Error("下单失败", "latency", time.Since(startTime), logging.Err(err))
+
+			requestEntry.Kind = ledger.KindOrderReject
+			a.journal.RecordError(requestEntry, err)
+			if binance.ClassifyError(err) != binance.CategoryUnknownOutcome {
+				// 确定未下单才释放策略挂单敞口；结果未知的订单等推送或对账确认
+				strategies.Release(req.ClientOrderID)
+			}
+			writeOrderError(w, err)
+			return
+		}
+		a.journal.Record(ledger.FromOrderResponse(ledger.KindOrderAck, order))
+		a.reconciler.TrackOrder(order)
+
+		orderLog.Info("下单成功", "order_id", order.OrderID, "status", order.Status, "avg_price", order.AvgPrice,
+			"latency", time.Since(startTime))
+
+		// 将完整的成功回执返回给 Python 引擎
+		json.NewEncoder(w).Encode(order)
+	})
+
+	// 撤单：{"account": "alpha", "strategy": "grid", "symbol": "BTCUSDT", "client_order_id": "grid_..."}
+	// (account 仅多账户模式必填，strategy 仅配置了策略注册表时必填，且只能撤本策略的订单)
+	http.HandleFunc("/api/cancel", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Account       string `json:"account"`
+			Strategy      string `json:"strategy"`
+			Symbol        string `json:"symbol"`
+			ClientOrderID string `json:"client_order_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "解析请求失败", http.StatusBadRequest)
+			return
+		}
+		if req.Symbol == "" || req.ClientOrderID == "" {
+			http.Error(w, "symbol and client_order_id are required", http.StatusBadRequest)
+			return
+		}
+		a, ok := set.resolveSymbol(w, req.Account, req.Symbol)
+		if !ok {
+			return
+		}
+		if err := strategies.AuthorizeCancel(req.Strategy, a.name, req.ClientOrderID); err != nil {
+			writeStrategyError(w, "cancel", err)
+			return
+		}
+
+		requestEntry := ledger.Entry{Kind: ledger.KindCancelRequest, Symbol: req.Symbol, ClientOrderID: req.ClientOrderID}
+		a.journal.Record(requestEntry)
+
+		order, err := a.orders.CancelOrder(binance.CancelOrderRequest{Symbol: req.Symbol, OrigClientOrderID: req.ClientOrderID})
+		metrics.OrderResult("cancel", err)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			udsLog.Error("撤单失败", "account", a.name, "symbol", req.Symbol, "client_order_id", req.ClientOrderID, logging.Err(err))
+			requestEntry.Kind = ledger.KindCancelReject
+			a.journal.RecordError(requestEntry, err)
+			writeOrderError(w, err)
 			return
 		}
+		a.journal.Record(ledger.FromOrderResponse(ledger.KindCancelAck, order))
+		a.reconciler.TrackOrder(order)
+		udsLog.Info("撤单成功", "account", a.name, "symbol", req.Symbol, "client_order_id", order.ClientOrderID, "order_id", order.OrderID, "status", order.Status)
+		json.NewEncoder(w).Encode(order)
+	})
 
-		// 如果发单成功，提取关键字段打印战报
-		log.Printf("✅ [执行成功] 订单已发送至币安！耗时: %v", time.Since(startTime))
+	// 改单 (仅限价单)：{"strategy": "grid", "symbol": "BTCUSDT", "client_order_id": "grid_...", "side": "BUY", "quantity": 0.01, "price": 60000}
+	http.HandleFunc("/api/modify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Account       string  `json:"account"`
+			Strategy      string  `json:"strategy"`
+			Symbol        string  `json:"symbol"`
+			ClientOrderID string  `json:"client_order_id"`
+			Side          string  `json:"side"`
+			Quantity      float64 `json:"quantity"`
+			Price         float64 `json:"price"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "解析请求失败", http.StatusBadRequest)
+			return
+		}
+		if req.Symbol == "" || req.ClientOrderID == "" || req.Side == "" || req.Quantity <= 0 || req.Price <= 0 {
+			http.Error(w, "symbol, client_order_id, side, quantity and price are required", http.StatusBadRequest)
+			return
+		}
+		a, ok := set.resolveSymbol(w, req.Account, req.Symbol)
+		if !ok {
+			return
+		}
 
-		// 容错提取返回值（防止某些字段为空导致 panic）
-		orderId := respData["orderId"]
-		status := respData["status"]
-		avgPrice := respData["avgPrice"]
+		requestEntry := ledger.Entry{Kind: ledger.KindModifyRequest, Symbol: req.Symbol, Side: req.Side,
+			ClientOrderID: req.ClientOrderID, Quantity: req.Quantity, Price: req.Price}
+		a.journal.Record(requestEntry)
+
+		if err := strategies.AuthorizeModify(req.Strategy, strategy.Order{
+			Account:       a.name,
+			Symbol:        req.Symbol,
+			Side:          req.Side,
+			ClientOrderID: req.ClientOrderID,
+			Quantity:      req.Quantity,
+			Price:         req.Price,
+		}); err != nil {
+			requestEntry.Kind = ledger.KindModifyReject
+			a.journal.RecordError(requestEntry, err)
+			writeStrategyError(w, "modify", err)
+			return
+		}
 
-		log.Printf("📊 [订单回执] OrderID: %v | 状态: %v | 均价: %v", orderId, status, avgPrice)
+		order, err := a.orders.ModifyOrder(binance.ModifyOrderRequest{
+			Symbol:            req.Symbol,
+			OrigClientOrderID: req.ClientOrderID,
+			Side:              req.Side,
+			Quantity:          req.Quantity,
+			Price:             req.Price,
+		})
+		metrics.OrderResult("modify", err)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			udsLog.Error("改单失败", "account", a.name, "symbol", req.Symbol, "client_order_id", req.ClientOrderID, logging.Err(err))
+			requestEntry.Kind = ledger.KindModifyReject
+			a.journal.RecordError(requestEntry, err)
+			writeOrderError(w, err)
+			return
+		}
+		a.journal.Record(ledger.FromOrderResponse(ledger.KindModifyAck, order))
+		a.reconciler.TrackOrder(order)
+		udsLog.Info("改单成功", "account", a.name, "symbol", req.Symbol, "client_order_id", order.ClientOrderID, "order_id", order.OrderID,
+			"price", order.Price, "quantity", order.OrigQty)
+		json.NewEncoder(w).Encode(order)
+	})
 
-		// 将完整的成功回执返回给 Python 引擎
-		json.NewEncoder(w).Encode(respData)
+	// 查询 / 切换持仓模式：GET ?account= 返回当前模式，POST {"account": "alpha", "dual_side": true} 切换
+	http.HandleFunc("/api/position-mode", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			a, ok := set.resolve(w, r.URL.Query().Get("account"))
+			if !ok {
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]bool{"dual_side": a.positions.DualSide()})
+		case http.MethodPost:
+			var req struct {
+				Account  string `json:"account"`
+				Strategy string `json:"strategy"`
+				DualSide bool   `json:"dual_side"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "解析请求失败", http.StatusBadRequest)
+				return
+			}
+			a, ok := set.resolve(w, req.Account)
+			if !ok {
+				return
+			}
+			if err := strategies.AuthorizeSettings(req.Strategy, a.name, ""); err != nil {
+				writeStrategyError(w, "position_mode", err)
+				return
+			}
+			if err := a.api.SetPositionMode(req.DualSide); err != nil {
+				udsLog.Error("持仓模式切换失败 (需先平仓并撤销全部挂单)", "account", a.name, logging.Err(err))
+				writeOrderError(w, err)
+				return
+			}
+			a.positions.SetDualSide(req.DualSide)
+			a.RefreshPositions(ctx)
+			udsLog.Info("持仓模式已切换", "account", a.name, "dual_side", req.DualSide)
+			json.NewEncoder(w).Encode(map[string]bool{"dual_side": req.DualSide})
+		default:
+			http.Error(w, "Only GET/POST allowed", http.StatusMethodNotAllowed)
+		}
 	})
 
+	registerMarginRoutes(set, strategies)
+	http.Handle("/api/pnl", set.Route(func(a *tradingAccount) http.Handler { return a.pnl }))
+	http.Handle("/api/ledger", journal)
+	http.Handle("/api/reconcile", set.Route(func(a *tradingAccount) http.Handler { return a.recon }))
+	http.Handle("/api/stream", hub)
+	http.Handle("/api/latency", lat)
+	http.Handle("/api/keys", set.Route(func(a *tradingAccount) http.Handler { return a.keys }))
+	http.Handle("/api/strategies", strategiesHandler(strategies))
+
+	// ==========================================
+	// 📊 新增：Prometheus 指标，单独监听 (TCP 或 UDS)，抓取流量不与交易通道共用
+	// ==========================================
+	health.Register(http.DefaultServeMux)
+	if cfg.Health.Addr != "" {
+		go serveHealth(cfg.Health.Addr, health)
+	}
+
+	if cfg.Metrics.Addr != "" {
+		metrics.registry.MustRegister(newStateCollector(list, lat))
+		go serveMetrics(cfg.Metrics.Addr, metrics.registry)
+	} else {
+		mainLog.Info("未配置 metrics.addr，Prometheus 指标不对外暴露")
+	}
+
+	// 🔐 新增：UDS 访问控制，下单类请求按策略校验 Token / HMAC 并写入审计日志
+	guard, err := newUDSGuard(cfg.UDS, cfg.Strategies, http.DefaultServeMux)
+	if err != nil {
+		fatal(udsLog, "审计日志打开失败", "path", cfg.UDS.AuditLogPath, logging.Err(err))
+	}
+	defer guard.Close()
+
 	go func() {
 		sockFile := "/tmp/quant_engine.sock"
 		_ = os.Remove(sockFile) // 启动前清理历史遗留的 sock 文件
 
-		// 监听本地 Unix Socket，彻底绕过 TCP 端口
-		listener, err := net.Listen("unix", sockFile)
+		// 默认限制 socket 文件权限为仅当前用户可读写，防止其他用户注入恶意订单；
+		// 放行其他 UID 时按 uds.group 授权组读写，并由 SO_PEERCRED 在连接建立时校验对端身份
+		uids := udsAllowedUIDs(cfg.UDS)
+		mode, gid, err := udsSocketPerm(cfg.UDS, uids)
 		if err != nil {
-			log.Fatalf("Socket listen error: %v", err)
+			fatal(udsLog, "UDS 权限配置无效", logging.Err(err))
 		}
 
-		// 限制 socket 文件权限为仅当前用户可读写，防止其他用户注入恶意订单
-		if err := os.Chmod(sockFile, 0600); err != nil {
-			log.Fatalf("Socket chmod error: %v", err)
+		// 监听本地 Unix Socket，彻底绕过 TCP 端口；socket 文件创建时即为目标权限，不经过宽松权限的窗口
+		listener, err := udsauth.Listen(sockFile, mode, gid)
+		if err != nil {
+			fatal(udsLog, "UDS 监听失败", "path", sockFile, logging.Err(err))
 		}
 
-		log.Printf("[Main] 🎛️ 本地 UDS 极速通道已启动，监听文件: %s", sockFile)
-		if err := http.Serve(listener, nil); err != nil {
-			log.Fatalf("HTTP Serve error: %v", err)
+		udsLog.Info("UDS 通道已启动", "path", sockFile, "allowed_uids", uids, "mode", mode, "group", cfg.UDS.Group)
+		server := &http.Server{Handler: guard, ConnContext: udsauth.ConnContext}
+		if err := server.Serve(newUDSListener(listener, uids)); err != nil {
+			fatal(udsLog, "UDS 服务退出", logging.Err(err))
 		}
 	}()
 
@@ -297,7 +571,41 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
-	log.Println("\n[Main] 🛑 Shutdown signal received...")
+	mainLog.Info("收到退出信号，正在关闭")
 	cancel()
 	time.Sleep(1 * time.Second)
 }
+
+// writeOrderError 把下单错误按币安错误码和重试分类返回给 Python 引擎
+// category 为 unknown_outcome 时，策略端应先按 client_order_id 查询再决定是否重发
+func writeOrderError(w http.ResponseWriter, err error) {
+	resp := map[string]interface{}{
+		"error":    err.Error(),
+		"category": binance.ClassifyError(err).String(),
+	}
+	status := http.StatusInternalServerError
+
+	var apiErr *binance.APIError
+	if errors.As(err, &apiErr) {
+		resp["code"] = apiErr.Code
+		resp["msg"] = apiErr.Msg
+		if apiErr.Category() == binance.CategoryNonRetryable && apiErr.HTTPStatus < 500 {
+			// 交易所明确拒绝 (保证金不足、参数错误)，属于请求本身的问题
+			status = http.StatusBadRequest
+		}
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// fatal 记录错误后退出 (slog 没有 Fatal 级别)
+func fatal(l *slog.Logger, msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+// formatFloat 将数值写成 Redis 中的纯文本，保持与币安原始字符串相同的可读格式
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+var metricsLog = logging.For("metrics")
+
+// metricsNamespace 所有指标的前缀
+const metricsNamespace = "binance_gateway"
+
+// gatewayMetrics 网关的 Prometheus 指标，注册在独立的 Registry 上，只通过 metrics.addr 单独监听的 /metrics 暴露
+type gatewayMetrics struct {
+	registry *prometheus.Registry
+
+	wsConnects         *prometheus.CounterVec   // stream
+	wsReconnects       *prometheus.CounterVec   // stream
+	resyncs            *prometheus.CounterVec   // symbol
+	depthEvents        *prometheus.CounterVec   // symbol
+	orderRequests      *prometheus.CounterVec   // action, outcome, code
+	strategyRejections *prometheus.CounterVec   // strategy, reason
+	udsAuthRejections  *prometheus.CounterVec   // reason
+	restDuration       *prometheus.HistogramVec // method, endpoint
+	apiUsedWeight      prometheus.Gauge
+	apiOrderCount      prometheus.Gauge
+	keyOrderCount      *prometheus.GaugeVec   // key
+	listenKeyRenewals  *prometheus.CounterVec // result
+	redisWriteFailures *prometheus.CounterVec // target
+}
+
+// metrics 进程内唯一的指标集合；Redis 写入分散在各个同步器中，统一经此计数
+var metrics = newGatewayMetrics()
+
+func newGatewayMetrics() *gatewayMetrics {
+	m := &gatewayMetrics{
+		registry: prometheus.NewRegistry(),
+		wsConnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "ws_connects_total",
+			Help: "WebSocket 连接成功次数 (含首次连接)",
+		}, []string{"stream"}),
+		wsReconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "ws_reconnects_total",
+			Help: "WebSocket 断线后重连成功次数",
+		}, []string{"stream"}),
+		resyncs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "orderbook_resyncs_total",
+			Help: "本地盘口序列号断层后重新拉取快照的次数",
+		}, []string{"symbol"}),
+		depthEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "depth_events_total",
+			Help: "处理的深度增量推送条数",
+		}, []string{"symbol"}),
+		orderRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "order_requests_total",
+			Help: "UDS 下单 / 撤单请求结果；outcome 为 ok 或错误分类，code 为币安错误码",
+		}, []string{"action", "outcome", "code"}),
+		strategyRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "strategy_rejections_total",
+			Help: "网关按策略注册表拦截的下单 / 改单 / 撤单请求；reason 为具体限额",
+		}, []string{"strategy", "reason"}),
+		udsAuthRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "uds_auth_rejections_total",
+			Help: "UDS 通道拒绝的连接 (对端 UID 不允许) 与请求 (策略鉴权失败)；reason 为拒绝原因",
+		}, []string{"reason"}),
+		restDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace, Name: "rest_request_duration_seconds",
+			Help:    "REST 与 WebSocket API 请求往返耗时 (重试的每一次分别计入，网络错误不计入)；WebSocket API 的 method 为 WS，endpoint 为 API 方法名",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"method", "endpoint"}),
+		apiUsedWeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Name: "api_used_weight_1m",
+			Help: "最近一次 REST 响应头 X-MBX-USED-WEIGHT-1M (IP 一分钟内已用权重)",
+		}),
+		apiOrderCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Name: "api_order_count_1m",
+			Help: "最近一次下单响应头 X-MBX-ORDER-COUNT-1M (账户一分钟内下单数)",
+		}),
+		keyOrderCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace, Name: "api_key_order_count_1m",
+			Help: "按 API Key 统计的最近一次下单计数 (X-MBX-ORDER-COUNT-1M 或 WebSocket API rateLimits)",
+		}, []string{"account", "key"}),
+		listenKeyRenewals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "listen_key_renewals_total",
+			Help: "ListenKey 续期次数，result 为 ok 或 error",
+		}, []string{"result"}),
+		redisWriteFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace, Name: "redis_write_failures_total",
+			Help: "Redis 写入失败次数 (写入失败不会阻塞主流程，只在此计数)",
+		}, []string{"target"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.wsConnects, m.wsReconnects, m.resyncs, m.depthEvents, m.orderRequests, m.strategyRejections, m.udsAuthRejections, m.restDuration,
+		m.apiUsedWeight, m.apiOrderCount, m.keyOrderCount, m.listenKeyRenewals, m.redisWriteFailures,
+	)
+	return m
+}
+
+// ConnectHook 返回 WebSocket 连接成功的回调：每次都计入连接数，首次之后的连接计为重连，随后调用 next (可为 nil)
+func (m *gatewayMetrics) ConnectHook(stream string, next func()) func() {
+	var connected atomic.Bool
+	return func() {
+		m.wsConnects.WithLabelValues(stream).Inc()
+		if connected.Swap(true) {
+			m.wsReconnects.WithLabelValues(stream).Inc()
+		}
+		if next != nil {
+			next()
+		}
+	}
+}
+
+// OnRoundTrip 作为 APIClient / WSAPIClient 的 OnRoundTrip 回调，记录请求耗时与权重用量
+func (m *gatewayMetrics) OnRoundTrip(rt binance.RoundTrip) {
+	if rt.Status == 0 {
+		return
+	}
+	m.restDuration.WithLabelValues(rt.Method, rt.Endpoint).Observe(rt.Duration.Seconds())
+	if rt.UsedWeight1m >= 0 {
+		m.apiUsedWeight.Set(float64(rt.UsedWeight1m))
+	}
+	if rt.OrderCount1m >= 0 {
+		m.apiOrderCount.Set(float64(rt.OrderCount1m))
+	}
+}
+
+// KeyRoundTrip 按账户与 Key 记录交易所返回的下单计数
+func (m *gatewayMetrics) KeyRoundTrip(account, key string, rt binance.RoundTrip) {
+	if rt.OrderCount1m >= 0 {
+		m.keyOrderCount.WithLabelValues(account, key).Set(float64(rt.OrderCount1m))
+	}
+}
+
+// OrderResult 记录一次下单 / 撤单请求的结果
+func (m *gatewayMetrics) OrderResult(action string, err error) {
+	if err == nil {
+		m.orderRequests.WithLabelValues(action, "ok", "").Inc()
+		return
+	}
+	code := ""
+	var apiErr *binance.APIError
+	if errors.As(err, &apiErr) && apiErr.Code != 0 {
+		code = strconv.Itoa(apiErr.Code)
+	}
+	m.orderRequests.WithLabelValues(action, binance.ClassifyError(err).String(), code).Inc()
+}
+
+// StrategyRejected 记录一次被策略限额拦截的请求
+func (m *gatewayMetrics) StrategyRejected(strategy, reason string) {
+	m.strategyRejections.WithLabelValues(strategy, reason).Inc()
+}
+
+// UDSAuthRejected 记录一次被 UDS 访问控制拒绝的连接或请求
+func (m *gatewayMetrics) UDSAuthRejected(reason string) {
+	m.udsAuthRejections.WithLabelValues(reason).Inc()
+}
+
+// ListenKeyRenewal 记录一次 ListenKey 续期结果
+func (m *gatewayMetrics) ListenKeyRenewal(err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.listenKeyRenewals.WithLabelValues(result).Inc()
+}
+
+// RedisWrite 统计 Redis 写入结果，target 为 Key 类别 (如 Position、OrderBook) 或 stream
+func (m *gatewayMetrics) RedisWrite(target string, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		m.redisWriteFailures.WithLabelValues(target).Inc()
+	}
+}
+
+// stateCollector 在抓取时读取各账户的持仓、盈亏与延迟直方图，不在写入路径上额外维护一份 Gauge
+type stateCollector struct {
+	accounts []*tradingAccount
+	lat      *latencyTracker
+
+	positionDesc   *prometheus.Desc
+	entryPriceDesc *prometheus.Desc
+	realizedDesc   *prometheus.Desc
+	unrealizedDesc *prometheus.Desc
+	netDesc        *prometheus.Desc
+	strategyDesc   *prometheus.Desc
+	latencyDesc    *prometheus.Desc
+}
+
+func newStateCollector(accounts []*tradingAccount, lat *latencyTracker) *stateCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil)
+	}
+	return &stateCollector{
+		accounts:       accounts,
+		lat:            lat,
+		positionDesc:   desc("position_amount", "当前持仓数量 (空仓为负)", "account", "symbol", "position_side"),
+		entryPriceDesc: desc("position_entry_price", "当前持仓开仓均价", "account", "symbol", "position_side"),
+		realizedDesc:   desc("pnl_realized_usdt", "已实现盈亏", "account", "symbol"),
+		unrealizedDesc: desc("pnl_unrealized_usdt", "按标记价格计算的未实现盈亏", "account", "symbol"),
+		netDesc:        desc("pnl_net_usdt", "净盈亏 (已实现 + 未实现 - 手续费 + 资金费)", "account", "symbol"),
+		strategyDesc:   desc("strategy_pnl_net_usdt", "按策略汇总的净盈亏", "account", "strategy"),
+		latencyDesc:    desc("latency_seconds", "网关内各段延迟，与 /api/latency 同源", "stage"),
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.positionDesc, c.entryPriceDesc, c.realizedDesc, c.unrealizedDesc,
+		c.netDesc, c.strategyDesc, c.latencyDesc} {
+		ch <- d
+	}
+}
+
+// Collect 实现 prometheus.Collector
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, a := range c.accounts {
+		for symbol, legs := range a.positions.Legs() {
+			for side, leg := range legs {
+				ch <- prometheus.MustNewConstMetric(c.positionDesc, prometheus.GaugeValue, leg.Amount, a.name, symbol, side)
+				ch <- prometheus.MustNewConstMetric(c.entryPriceDesc, prometheus.GaugeValue, leg.EntryPrice, a.name, symbol, side)
+			}
+		}
+
+		report := a.pnl.engine.Report()
+		for symbol, s := range report.Symbols {
+			ch <- prometheus.MustNewConstMetric(c.realizedDesc, prometheus.GaugeValue, s.RealizedPnL, a.name, symbol)
+			ch <- prometheus.MustNewConstMetric(c.unrealizedDesc, prometheus.GaugeValue, s.UnrealizedPnL, a.name, symbol)
+			ch <- prometheus.MustNewConstMetric(c.netDesc, prometheus.GaugeValue, s.NetPnL, a.name, symbol)
+		}
+		for strategy, s := range report.Strategies {
+			ch <- prometheus.MustNewConstMetric(c.strategyDesc, prometheus.GaugeValue, s.NetPnL, a.name, strategy)
+		}
+	}
+
+	for _, name := range latencyNames {
+		s := c.lat.hists[name].Snapshot()
+		buckets := make(map[float64]uint64, len(s.Bounds))
+		var cum uint64
+		for i, bound := range s.Bounds {
+			cum += s.Counts[i]
+			buckets[bound.Seconds()] = cum
+		}
+		ch <- prometheus.MustNewConstHistogram(c.latencyDesc, s.Count, s.Sum.Seconds(), buckets, name)
+	}
+}
+
+// listenAddr 监听独立的辅助端口：addr 以 "/" 开头时视为 Unix Socket 路径，否则为 TCP 地址 (如 127.0.0.1:9100)
+func listenAddr(addr string) (net.Listener, string, error) {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") {
+		network = "unix"
+		_ = os.Remove(addr) // 清理历史遗留的 sock 文件
+	}
+	listener, err := net.Listen(network, addr)
+	return listener, network, err
+}
+
+// serveMetrics 在独立的监听地址上暴露 /metrics，与 UDS 交易通道互不影响
+func serveMetrics(addr string, reg *prometheus.Registry) {
+	listener, network, err := listenAddr(addr)
+	if err != nil {
+		fatal(metricsLog, "指标监听失败", "addr", addr, logging.Err(err))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	metricsLog.Info("Prometheus 指标已启动", "network", network, "addr", addr)
+	if err := http.Serve(listener, mux); err != nil {
+		fatal(metricsLog, "指标服务退出", logging.Err(err))
+	}
+}
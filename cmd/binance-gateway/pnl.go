@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/pnl"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var pnlLog = logging.For("pnl")
+
+// pnlRedisKey 盈亏报告在 Redis 中的 Key，值为 pnl.Report 的 JSON
+const pnlRedisKey = "PnL"
+
+const (
+	// pnlMarkPublishInterval 标记价格驱动的报告发布间隔：每个交易对每秒都有推送，逐条发布会反复序列化整份报告
+	pnlMarkPublishInterval = time.Second
+	// pnlWriteTimeout 盈亏报告写入 Redis 的超时，Redis 卡顿时不阻塞行情与私有流回调
+	pnlWriteTimeout = 100 * time.Millisecond
+)
+
+// pnlSync 把用户数据流与标记价格喂给盈亏引擎，并发布到 Redis
+type pnlSync struct {
+	engine   *pnl.Engine
+	rdb      *redis.Client
+	redisKey string // 多账户模式下为 <account>:PnL
+
+	lastMarkPublish atomic.Int64 // 上一次由标记价格触发发布的时间 (UnixNano)
+}
+
+func newPnLSync(rdb *redis.Client, prefix string) *pnlSync {
+	return &pnlSync{engine: pnl.NewEngine("USDT"), rdb: rdb, redisKey: prefix + pnlRedisKey}
+}
+
+// Seed 把 positionRisk 中的非零持仓载入盈亏引擎 (记在 pnl.InheritedStrategy 名下)
+func (s *pnlSync) Seed(risks []binance.PositionRisk) {
+	for _, r := range risks {
+		if r.PositionAmt != 0 {
+			s.engine.Seed(r.Symbol, r.PositionSide, r.PositionAmt, r.EntryPrice)
+			pnlLog.Info("载入启动前持仓", "symbol", r.Symbol, "position_side", r.PositionSide,
+				"amount", r.PositionAmt, "entry_price", r.EntryPrice)
+		}
+	}
+}
+
+// ApplyEvent 消费 ORDER_TRADE_UPDATE 成交与 ACCOUNT_UPDATE 资金费
+func (s *pnlSync) ApplyEvent(ctx context.Context, event binance.UserDataEvent) {
+	switch event.EventType {
+	case "ORDER_TRADE_UPDATE":
+		fill, ok := pnl.FillFromOrderUpdate(event.Order)
+		if !ok {
+			return
+		}
+		s.engine.OnFill(fill)
+	case "ACCOUNT_UPDATE":
+		if event.Account.Reason != "FUNDING_FEE" {
+			return
+		}
+		// 逐仓资金费会同时推送对应仓位；全仓只推送余额变化，交易对留空由引擎按持仓分摊
+		symbol := ""
+		if len(event.Account.Positions) > 0 {
+			symbol = event.Account.Positions[0].Symbol
+		}
+		for _, b := range event.Account.Balances {
+			if b.Asset != "USDT" {
+				continue
+			}
+			amount, _ := strconv.ParseFloat(b.BalanceChange, 64)
+			s.engine.OnFunding(symbol, amount)
+		}
+	default:
+		return
+	}
+	s.Publish(ctx)
+}
+
+// OnMarkPrice 标记价格更新后重新计算未实现盈亏；报告最多每 pnlMarkPublishInterval 发布一次，成交仍即时发布
+func (s *pnlSync) OnMarkPrice(ctx context.Context, event binance.MarkPriceEvent) {
+	price, err := strconv.ParseFloat(event.MarkPrice, 64)
+	if err != nil || price <= 0 {
+		return
+	}
+	s.engine.OnMarkPrice(event.Symbol, price)
+
+	now := time.Now().UnixNano()
+	last := s.lastMarkPublish.Load()
+	if now-last < int64(pnlMarkPublishInterval) || !s.lastMarkPublish.CompareAndSwap(last, now) {
+		return
+	}
+	s.Publish(ctx)
+}
+
+// Publish 把盈亏报告整体写入 Redis
+func (s *pnlSync) Publish(ctx context.Context) {
+	data, err := json.Marshal(s.engine.Report())
+	if err != nil {
+		return
+	}
+	wCtx, cancel := context.WithTimeout(ctx, pnlWriteTimeout)
+	defer cancel()
+	metrics.RedisWrite(pnlRedisKey, s.rdb.Set(wCtx, s.redisKey, data, 0).Err())
+}
+
+// ServeHTTP 实现 UDS 查询路由 /api/pnl，可选 ?strategy= 或 ?symbol= 过滤单个维度
+func (s *pnlSync) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report := s.engine.Report()
+	w.Header().Set("Content-Type", "application/json")
+
+	if strategy := r.URL.Query().Get("strategy"); strategy != "" {
+		stats, ok := report.Strategies[strategy]
+		if !ok {
+			http.Error(w, "unknown strategy", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+	if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+		stats, ok := report.Symbols[symbol]
+		if !ok {
+			http.Error(w, "unknown symbol", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
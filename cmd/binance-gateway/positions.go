@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/logging"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var positionLog = logging.For("position")
+
+// positionLeg 单条持仓腿 (单向持仓为 BOTH，双向持仓为 LONG / SHORT)
+type positionLeg struct {
+	Amount     float64
+	EntryPrice float64
+}
+
+// positionBook 按持仓模式维护各交易对的持仓腿，并负责写入 Redis
+//
+// Redis Key 约定 (多账户模式下均带 "<account>:" 前缀)：
+//   - 单向持仓：Position:<SYM> / EntryPrice:<SYM>
+//   - 双向持仓：Position:<SYM>:LONG / EntryPrice:<SYM>:LONG (SHORT 同理)，
+//     另外 Position:<SYM> 写入 LONG+SHORT 的净持仓，兼容只关心净头寸的策略
+type positionBook struct {
+	mu       sync.Mutex
+	dualSide bool
+	legs     map[string]map[string]positionLeg // symbol -> positionSide -> leg
+	rdb      *redis.Client
+	prefix   string // Redis Key 前缀，单账户模式为空
+}
+
+func newPositionBook(rdb *redis.Client, prefix string, dualSide bool) *positionBook {
+	return &positionBook{
+		dualSide: dualSide,
+		legs:     make(map[string]map[string]positionLeg),
+		rdb:      rdb,
+		prefix:   prefix,
+	}
+}
+
+// DualSide 返回当前是否为双向持仓模式
+func (b *positionBook) DualSide() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dualSide
+}
+
+// Legs 返回全部持仓腿的副本 (symbol -> positionSide -> leg)
+func (b *positionBook) Legs() map[string]map[string]positionLeg {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[string]map[string]positionLeg, len(b.legs))
+	for symbol, legs := range b.legs {
+		out[symbol] = make(map[string]positionLeg, len(legs))
+		for side, leg := range legs {
+			out[symbol][side] = leg
+		}
+	}
+	return out
+}
+
+// SetDualSide 切换持仓模式后清空旧的持仓腿，等待调用方重新盘点
+func (b *positionBook) SetDualSide(dualSide bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dualSide = dualSide
+	b.legs = make(map[string]map[string]positionLeg)
+}
+
+// Update 覆写一条持仓腿并同步到 Redis
+func (b *positionBook) Update(ctx context.Context, symbol, positionSide string, amount, entryPrice float64) {
+	if positionSide == "" {
+		positionSide = "BOTH"
+	}
+
+	b.mu.Lock()
+	if b.legs[symbol] == nil {
+		b.legs[symbol] = make(map[string]positionLeg)
+	}
+	b.legs[symbol][positionSide] = positionLeg{Amount: amount, EntryPrice: entryPrice}
+	dualSide := b.dualSide
+	var net float64
+	for _, leg := range b.legs[symbol] {
+		net += leg.Amount
+	}
+	b.mu.Unlock()
+
+	position, entry := b.prefix+"Position:"+symbol, b.prefix+"EntryPrice:"+symbol
+	if !dualSide || positionSide == "BOTH" {
+		metrics.RedisWrite("Position", b.rdb.Set(ctx, position, formatFloat(amount), 0).Err())
+		metrics.RedisWrite("EntryPrice", b.rdb.Set(ctx, entry, formatFloat(entryPrice), 0).Err())
+		return
+	}
+
+	// 双向持仓：SHORT 腿的 positionAmt 本身为负数，直接相加即为净持仓
+	metrics.RedisWrite("Position", b.rdb.Set(ctx, position+":"+positionSide, formatFloat(amount), 0).Err())
+	metrics.RedisWrite("EntryPrice", b.rdb.Set(ctx, entry+":"+positionSide, formatFloat(entryPrice), 0).Err())
+	metrics.RedisWrite("Position", b.rdb.Set(ctx, position, formatFloat(net), 0).Err())
+}
+
+// Sync 用 positionRisk 的全量结果覆写该交易对的所有持仓腿
+func (b *positionBook) Sync(ctx context.Context, symbol string, positions []binance.PositionRisk) {
+	if len(positions) == 0 {
+		// 无任何持仓记录时按空仓处理
+		sides := []string{"BOTH"}
+		if b.DualSide() {
+			sides = []string{"LONG", "SHORT"}
+		}
+		for _, side := range sides {
+			b.Update(ctx, symbol, side, 0, 0)
+		}
+		return
+	}
+	for _, p := range positions {
+		if p.Symbol != symbol {
+			continue
+		}
+		b.Update(ctx, symbol, p.PositionSide, p.PositionAmt, p.EntryPrice)
+	}
+}
+
+// Refresh 主动调用 REST 拉取持仓并覆写本地状态
+func (b *positionBook) Refresh(ctx context.Context, apiClient *binance.APIClient, symbol string) error {
+	positions, err := apiClient.GetPositions(symbol)
+	if err != nil {
+		return err
+	}
+	b.Sync(ctx, symbol, positions)
+	for _, p := range positions {
+		if p.Symbol == symbol {
+			positionLog.Info("仓位盘点", "symbol", symbol, "position_side", p.PositionSide, "amount", p.PositionAmt, "entry_price", p.EntryPrice)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/reconcile"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var reconcileLog = logging.For("reconcile")
+
+// reconcileChannel 对账差异事件的 Redis Pub/Sub 频道，消息为 reconcile.Discrepancy 的 JSON
+const reconcileChannel = "Events:Reconcile"
+
+// maxRecentDiscrepancies /api/reconcile 保留的最近差异条数
+const maxRecentDiscrepancies = 100
+
+// reconcileSync 驱动挂单与成交对账：定时器与私有流重连都通过 Trigger 请求一轮对账，
+// 发现差异后广播事件并刷新仓位与账户
+type reconcileSync struct {
+	reconciler *reconcile.Reconciler
+	orders     *binance.KeyPool // 对账发现的挂单登记到下单 Key 池，重启后仍可撤单 / 改单
+	rdb        *redis.Client
+	channel    string // 多账户模式下为 <account>:Events:Reconcile
+	symbols    []string
+	onRepair   func(ctx context.Context) // 有差异时调用，刷新依赖推送维护的本地状态
+	triggerCh  chan struct{}
+
+	mu     sync.Mutex
+	recent []reconcile.Discrepancy
+}
+
+func newReconcileSync(reconciler *reconcile.Reconciler, orders *binance.KeyPool, rdb *redis.Client, prefix string, symbols []string, onRepair func(ctx context.Context)) *reconcileSync {
+	return &reconcileSync{
+		reconciler: reconciler,
+		orders:     orders,
+		rdb:        rdb,
+		channel:    prefix + reconcileChannel,
+		symbols:    symbols,
+		onRepair:   onRepair,
+		triggerCh:  make(chan struct{}, 1),
+	}
+}
+
+// Trigger 非阻塞地请求一轮对账，已有待处理请求时合并
+func (s *reconcileSync) Trigger() {
+	select {
+	case s.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// Run 串行处理对账请求
+func (s *reconcileSync) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.triggerCh:
+		}
+		s.RunOnce(ctx)
+	}
+}
+
+// RunOnce 执行一轮对账并处理结果
+func (s *reconcileSync) RunOnce(ctx context.Context) {
+	start := time.Now()
+	found, err := s.reconciler.Reconcile(s.symbols)
+	if err != nil {
+		reconcileLog.Warn("对账查询失败", logging.Err(err))
+	}
+	for _, o := range s.reconciler.WorkingOrders() {
+		s.orders.Adopt(o.ClientOrderID)
+	}
+	if len(found) == 0 {
+		reconcileLog.Info("挂单与成交对账一致", "working_orders", len(s.reconciler.WorkingOrders()), "latency", time.Since(start))
+		return
+	}
+
+	for _, d := range found {
+		reconcileLog.Warn("发现对账差异", "type", d.Type, "symbol", d.Symbol, "client_order_id", d.ClientOrderID,
+			"order_id", d.OrderID, "trade_id", d.TradeID, "local_status", d.LocalStatus, "local_filled", d.LocalFilled,
+			"remote_status", d.RemoteStatus, "remote_filled", d.RemoteFilled)
+		if data, err := json.Marshal(d); err == nil {
+			metrics.RedisWrite(reconcileChannel, s.rdb.Publish(ctx, s.channel, data).Err())
+		}
+	}
+
+	s.mu.Lock()
+	s.recent = append(s.recent, found...)
+	if n := len(s.recent); n > maxRecentDiscrepancies {
+		s.recent = append([]reconcile.Discrepancy(nil), s.recent[n-maxRecentDiscrepancies:]...)
+	}
+	s.mu.Unlock()
+
+	if s.onRepair != nil {
+		s.onRepair(ctx)
+	}
+}
+
+// ServeHTTP 实现 UDS 路由 /api/reconcile：GET 返回本地挂单与最近差异，POST 立即触发一轮对账
+func (s *reconcileSync) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		recent := append([]reconcile.Discrepancy{}, s.recent...)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"working_orders":       s.reconciler.WorkingOrders(),
+			"recent_discrepancies": recent,
+		})
+	case http.MethodPost:
+		s.Trigger()
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "Only GET/POST allowed", http.StatusMethodNotAllowed)
+	}
+}
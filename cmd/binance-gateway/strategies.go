@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/ledger"
+	"BinanceAutoBot2/internal/strategy"
+)
+
+// newStrategyRegistry 把配置中的策略注册表转换为 strategy.Registry；未配置时注册表不启用
+func newStrategyRegistry(cfgs []config.StrategyConfig) *strategy.Registry {
+	limits := make([]strategy.Limits, 0, len(cfgs))
+	for _, c := range cfgs {
+		limits = append(limits, strategy.Limits{
+			Name:        c.Name,
+			MaxPosition: c.MaxPosition,
+			MaxNotional: c.MaxNotional,
+			Symbols:     c.Symbols,
+			Accounts:    c.Accounts,
+			MaxOrders1m: c.MaxOrders1m,
+		})
+		mainLog.Info("策略已注册", "strategy", c.Name, "max_position", c.MaxPosition, "max_notional", c.MaxNotional,
+			"symbols", c.Symbols, "accounts", c.Accounts, "max_orders_1m", c.MaxOrders1m)
+	}
+	return strategy.NewRegistry(limits)
+}
+
+// seedStrategies 重启后恢复账户上各策略的持仓与挂单敞口：持仓由账本中按 clientOrderId 前缀归属的成交累计，
+// 挂单取对账首轮接管的现有挂单 (同时登记到下单 Key 池)。账本未开启或任一查询失败时不恢复，
+// 该账户上设置了 max_position 的策略拒单 (state_not_seeded)，不按从零开始的持仓放行
+func seedStrategies(reg *strategy.Registry, a *tradingAccount) error {
+	if !reg.Enabled() {
+		return nil
+	}
+	if a.journal.store == nil {
+		return errors.New("未配置 ledger.path，无法从账本恢复策略持仓")
+	}
+	entries, err := a.journal.store.Query(ledger.Filter{Account: a.journal.account, Kind: ledger.KindFill})
+	if err != nil {
+		return fmt.Errorf("账本查询失败: %w", err)
+	}
+	// 同一笔成交可能既有推送记录又有对账补记，按 (symbol, tradeId) 去重
+	seen := make(map[string]bool, len(entries))
+	fills := make([]strategy.Fill, 0, len(entries))
+	for _, e := range entries {
+		if e.TradeID != 0 {
+			id := e.Symbol + "|" + strconv.FormatInt(e.TradeID, 10)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		fills = append(fills, strategy.Fill{Symbol: e.Symbol, Side: e.Side, ClientOrderID: e.ClientOrderID, Qty: e.FilledQty})
+	}
+
+	if _, err := a.reconciler.Reconcile(a.symbols); err != nil {
+		return fmt.Errorf("现有挂单查询失败: %w", err)
+	}
+	working := a.reconciler.WorkingOrders()
+	orders := make([]strategy.OpenOrder, 0, len(working))
+	for _, o := range working {
+		a.keys.orders.Adopt(o.ClientOrderID)
+		orders = append(orders, strategy.OpenOrder{Symbol: o.Symbol, Side: o.Side, ClientOrderID: o.ClientOrderID,
+			Quantity: o.OrigQty, Filled: o.FilledQty})
+	}
+	reg.Seed(a.name, fills, orders)
+	a.log.Info("策略持仓与挂单已恢复", "fills", len(fills), "working_orders", len(orders))
+	return nil
+}
+
+// clientOrderIDPrefix 网关代为生成 clientOrderId 时的前缀：声明了策略时为策略名，保证成交能归属回策略
+func clientOrderIDPrefix(strategyID string) string {
+	if strategyID != "" {
+		return strategyID
+	}
+	return "bot"
+}
+
+// writeStrategyError 策略校验失败：频率超限返回 429 (retryable)，其余返回 403 (non_retryable)，
+// 响应体与 writeOrderError 保持同样的 error / category 字段，另带 reason 区分具体限额
+func writeStrategyError(w http.ResponseWriter, action string, err error) {
+	var v *strategy.Violation
+	if !errors.As(err, &v) {
+		writeOrderError(w, err)
+		return
+	}
+	metrics.StrategyRejected(v.Strategy, v.Reason)
+	udsLog.Warn("策略校验拒绝", "action", action, "strategy", v.Strategy, "reason", v.Reason, "detail", v.Detail)
+
+	status, category := http.StatusForbidden, binance.CategoryNonRetryable
+	if v.Retryable() {
+		status, category = http.StatusTooManyRequests, binance.CategoryRetryable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":    err.Error(),
+		"category": category.String(),
+		"reason":   v.Reason,
+	})
+}
+
+// strategiesHandler UDS 路由 /api/strategies：返回各策略的限额、一分钟内下单数、持仓与拒单统计
+func strategiesHandler(reg *strategy.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reg.Stats())
+	}
+}
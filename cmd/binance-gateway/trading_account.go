@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/events"
+	"BinanceAutoBot2/internal/ledger"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/pnl"
+	"BinanceAutoBot2/internal/reconcile"
+	"BinanceAutoBot2/internal/strategy"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stateSyncInterval 定时 REST 兜底同步 (余额、仓位、账户状态、挂单对账) 的间隔
+const stateSyncInterval = 5 * time.Minute
+
+// tradingAccount 一个交易账户在网关内的全部状态：API Key、持仓、账户模型、盈亏、挂单对账与私有流
+//
+// 多账户模式下该账户写入的 Redis Key 一律加上 "<account>:" 前缀 (如 alpha:Position:BTCUSDT、
+// alpha:Wallet:USDT、alpha:Stream:Orders)，单账户模式前缀为空，Key 与之前完全一致
+type tradingAccount struct {
+	name     string
+	prefix   string // Redis Key 前缀
+	multi    bool   // 是否为多账户模式 (决定交易对白名单与健康组件命名)
+	envName  string
+	env      config.EnvConfig
+	settings map[string]config.SymbolConfig
+	symbols  []string // 持仓盘点与挂单对账覆盖的交易对
+	log      *slog.Logger
+
+	keys       *apiKeys
+	api        *binance.APIClient // 只读请求 (keys.read)
+	orders     binance.OrderClient
+	rdb        *redis.Client
+	streams    *events.Publisher
+	journal    *ledgerSync
+	positions  *positionBook
+	accounts   *accountSync
+	pnl        *pnlSync
+	reconciler *reconcile.Reconciler
+	recon      *reconcileSync
+	userStream *binance.UserStreamManager
+}
+
+// newTradingAccount 创建账户的 API Key 与各同步器，并完成启动时的 REST 盘点 (余额、持仓模式、杠杆对齐、持仓、账户状态)
+// symbol 为网关行情交易对：单账户模式下它与 binance.symbols 一起纳入盘点；多账户模式只盘点账户自己的 symbols
+func newTradingAccount(ctx context.Context, ta config.TradingAccount, multi bool, symbol string, rdb *redis.Client,
+	streams *events.Publisher, journal *ledgerSync, strategies *strategy.Registry, lat *latencyTracker) (*tradingAccount, error) {
+	a := &tradingAccount{
+		name:     ta.Name,
+		multi:    multi,
+		envName:  ta.EnvName,
+		env:      ta.Env,
+		settings: ta.Symbols,
+		rdb:      rdb,
+		log:      mainLog.With("account", ta.Name),
+	}
+	if multi {
+		a.prefix = ta.Name + ":"
+		a.streams = streams.WithAccount(ta.Name)
+		a.journal = journal.WithAccount(ta.Name)
+	} else {
+		a.streams = streams
+		a.journal = journal
+		a.symbols = append(a.symbols, symbol)
+	}
+	var extra []string
+	for sym := range ta.Symbols {
+		if multi || sym != symbol {
+			extra = append(extra, sym)
+		}
+	}
+	sort.Strings(extra)
+	a.symbols = append(a.symbols, extra...)
+
+	// 🔑 按角色拆分 API Key：只读请求走 read Key，下单流量在各下单 Key 之间分散，共用账户的一分钟下单额度
+	keys, err := newAPIKeys(ta.Name, ta.Env, func(rt binance.RoundTrip) {
+		lat.OnRoundTrip(rt)
+		metrics.OnRoundTrip(rt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := keys.verifySingleAccount(); err != nil {
+		return nil, fmt.Errorf("账户 %s 的 API Key 配置无效: %w", ta.Name, err)
+	}
+	a.keys, a.api, a.orders = keys, keys.read, keys.orders
+
+	// 🌟 启动时主动拉取一次真实余额，避免 Redis 里没有资金数据的真空期
+	if bal, err := a.api.GetUSDTBalance(); err == nil {
+		metrics.RedisWrite("Wallet", rdb.Set(ctx, a.key("Wallet:USDT"), formatFloat(bal.Balance), 0).Err())
+		a.log.Info("初始资金盘点完成", "asset", "USDT", "balance", bal.Balance, "available", bal.AvailableBalance)
+	} else {
+		a.log.Warn("初始资金盘点失败", logging.Err(err))
+	}
+
+	// 识别持仓模式 (单向 / 双向)，决定仓位按 BOTH 还是 LONG/SHORT 分腿维护；
+	// 查询已按 RetryPolicy 重试，仍失败时拒绝启动：猜错模式会让下单被拒 (-4061) 且仓位写错 Redis Key
+	dualSide, err := a.api.GetPositionMode()
+	if err != nil {
+		return nil, fmt.Errorf("账户 %s 持仓模式查询失败: %w", ta.Name, err)
+	}
+	a.log.Info("当前持仓模式", "dual_side", dualSide)
+	a.positions = newPositionBook(rdb, a.prefix, dualSide)
+
+	// ⚙️ 校验各交易对的杠杆与保证金模式，不一致 (未开启 apply_symbol_settings 或无法修正) 时拒绝启动
+	if err := enforceSymbolSettings(a.api, ta.Symbols, ta.ApplySettings); err != nil {
+		return nil, fmt.Errorf("账户 %s 设置与配置不一致: %w", ta.Name, err)
+	}
+	a.RefreshPositions(ctx)
+
+	// 账户全量状态模型 (全部资产、保证金率、强平价格)
+	a.accounts = newAccountSync(a.api, rdb, a.prefix, a.streams)
+	if err := a.accounts.Refresh(ctx); err != nil {
+		a.log.Warn("初始账户状态盘点失败", logging.Err(err))
+	} else {
+		snap := a.accounts.account.Snapshot()
+		a.log.Info("账户状态盘点完成", "margin_balance", snap.TotalMarginBalance,
+			"maint_margin", snap.TotalMaintMargin, "margin_ratio", snap.MarginRatio)
+	}
+
+	// 实时盈亏引擎 (已实现 / 未实现 / 手续费 / 资金费)；启动前已有的持仓按 positionRisk 载入，
+	// 否则重启后第一笔平仓成交会被当成反向开仓
+	a.pnl = newPnLSync(rdb, a.prefix)
+	if risks, err := a.api.GetPositions(""); err == nil {
+		a.pnl.Seed(risks)
+	} else {
+		a.log.Warn("盈亏引擎载入启动前持仓失败，重启前开的仓位在平仓时会被记为反向开仓", logging.Err(err))
+	}
+
+	// 🔍 挂单与成交对账，漏接的成交补记到盈亏引擎、账本、策略持仓与 Stream
+	a.reconciler = reconcile.New(a.api)
+	a.reconciler.OnMissedFill = func(trade binance.UserTrade, clientOrderID string) {
+		a.pnl.engine.OnFill(pnl.FillFromUserTrade(trade, clientOrderID))
+		a.pnl.Publish(ctx)
+		a.journal.Record(ledger.FromUserTrade(trade, clientOrderID))
+		strategies.ApplyTrade(a.name, trade, clientOrderID)
+		metrics.RedisWrite("stream", a.streams.PublishFill(ctx, events.FillEventFromTrade(trade, clientOrderID)))
+	}
+	a.recon = newReconcileSync(a.reconciler, a.keys.orders, rdb, a.prefix, a.symbols, func(ctx context.Context) {
+		// 有差异说明推送有遗漏，仓位与账户同样需要以 REST 为准刷新
+		a.RefreshPositions(ctx)
+		a.accounts.RequestRefresh()
+	})
+
+	// 🧾 策略持仓与挂单只在进程内统计，重启后从账本与现有挂单恢复；恢复前设置了 max_position 的策略在该账户上拒单
+	if err := seedStrategies(strategies, a); err != nil {
+		a.log.Error("策略持仓恢复失败，设置了 max_position 的策略在该账户上将拒单", logging.Err(err))
+	}
+
+	// ListenKey 的创建 / 续期 / 失效重建与私有流重连统一由 UserStreamManager 负责
+	a.userStream = binance.NewUserStreamManager(a.api, userStreamBaseURL(ta.EnvName))
+	a.userStream.Handler = &userStreamHandler{
+		ctx:        ctx,
+		account:    a,
+		strategies: strategies,
+		lat:        lat,
+		reconciler: a.reconciler,
+		recon:      a.recon,
+		accounts:   a.accounts,
+		pnl:        a.pnl,
+		journal:    a.journal,
+		positions:  a.positions,
+		streams:    a.streams,
+	}
+	return a, nil
+}
+
+// userStreamBaseURL 私有流地址随账户所在环境
+func userStreamBaseURL(envName string) string {
+	if envName == "mainnet" {
+		return "wss://fstream.binance.com/ws/" // 主网
+	}
+	return "wss://stream.binancefuture.com/ws/" // 测试网
+}
+
+// key 返回加上账户前缀的 Redis Key
+func (a *tradingAccount) key(name string) string {
+	return a.prefix + name
+}
+
+// component 返回健康检查 / 指标中该账户组件的名称：单账户模式为 base，多账户模式为 base:<account>
+func (a *tradingAccount) component(base string) string {
+	if !a.multi {
+		return base
+	}
+	return base + ":" + a.name
+}
+
+// Allows 多账户模式下只允许交易账户 symbols 中声明的交易对，防止策略在错误的账户上下单、撤单或调整设置
+func (a *tradingAccount) Allows(symbol string) bool {
+	if !a.multi {
+		return true
+	}
+	_, ok := a.settings[symbol]
+	return ok
+}
+
+// RefreshPositions 通过 REST 覆写账户全部交易对的持仓
+func (a *tradingAccount) RefreshPositions(ctx context.Context) {
+	for _, sym := range a.symbols {
+		if err := a.positions.Refresh(ctx, a.api, sym); err != nil {
+			a.log.Warn("仓位盘点失败", "symbol", sym, logging.Err(err))
+		}
+	}
+}
+
+// Run 启动该账户的 WebSocket API 连接、REST 保温、账户刷新、对账、私有流与定时兜底同步
+func (a *tradingAccount) Run(ctx context.Context, health *healthMonitor) {
+	a.keys.Run(ctx, health, a.component("ws_api"))
+	// 🔥 REST 连接保温：同一账户的各 Key 共用连接池，只需只读客户端定时 ping
+	go a.api.KeepWarm(ctx, binance.DefaultWarmInterval)
+	go a.accounts.Run(ctx)
+	go a.recon.Run(ctx)
+
+	onConnect, onDisconnect, onListenKey := health.UserStreamEnabled(a.component("user_stream"), a.component("listen_key"))
+	a.userStream.OnConnect = metrics.ConnectHook(a.component("user"), func() {
+		onConnect()
+		a.recon.Trigger() // 每次 (重) 连接后立即对账，补齐断线期间漏掉的推送
+	})
+	a.userStream.OnDisconnect = onDisconnect
+	a.userStream.OnListenKey = func(err error) {
+		metrics.ListenKeyRenewal(err)
+		onListenKey(err)
+	}
+	go a.userStream.Run(ctx)
+	go a.syncLoop(ctx)
+}
+
+// syncLoop 🛡️ 定时 REST 兜底同步，防止 WS 漏接导致的「幽灵仓位」(频率不要太高，以免消耗 API 权重)
+func (a *tradingAccount) syncLoop(ctx context.Context) {
+	ticker := time.NewTicker(stateSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reconcileLog.Info("定时 REST 状态兜底同步", "account", a.name)
+
+		// 1. 强制核对并覆写钱包余额
+		if bal, err := a.api.GetUSDTBalance(); err == nil {
+			metrics.RedisWrite("Wallet", a.rdb.Set(ctx, a.key("Wallet:USDT"), formatFloat(bal.Balance), 0).Err())
+		} else {
+			reconcileLog.Warn("定时余额同步失败", "account", a.name, logging.Err(err))
+		}
+		// 2. 强制核对并覆写真实仓位与均价
+		a.RefreshPositions(ctx)
+		// 3. 全量刷新账户状态 (保证金率、强平价格)
+		a.accounts.RequestRefresh()
+		// 4. 挂单与成交对账
+		a.recon.Trigger()
+	}
+}
+
+// accountSet 网关管理的全部交易账户，负责把 UDS 请求路由到声明的账户
+type accountSet struct {
+	multi  bool
+	list   []*tradingAccount
+	byName map[string]*tradingAccount
+}
+
+func newAccountSet(multi bool, list []*tradingAccount) *accountSet {
+	s := &accountSet{multi: multi, list: list, byName: make(map[string]*tradingAccount, len(list))}
+	for _, a := range list {
+		s.byName[a.name] = a
+	}
+	return s
+}
+
+// Resolve 按请求中的 account 选出账户：多账户模式下必填且必须是已配置的账户；单账户模式下可省略
+func (s *accountSet) Resolve(name string) (*tradingAccount, error) {
+	if name == "" {
+		if s.multi {
+			return nil, fmt.Errorf("account is required")
+		}
+		return s.list[0], nil
+	}
+	a, ok := s.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %q", name)
+	}
+	return a, nil
+}
+
+// resolve 解析失败时直接返回 400
+func (s *accountSet) resolve(w http.ResponseWriter, name string) (*tradingAccount, bool) {
+	a, err := s.Resolve(name)
+	if err != nil {
+		udsLog.Warn("拒绝请求: 账户无效", "account", name, logging.Err(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return a, true
+}
+
+// resolveSymbol 解析账户并确认 symbol 在该账户的 symbols 中；失败时已写入 400 响应
+func (s *accountSet) resolveSymbol(w http.ResponseWriter, name, symbol string) (*tradingAccount, bool) {
+	a, ok := s.resolve(w, name)
+	if !ok {
+		return nil, false
+	}
+	if !a.Allows(symbol) {
+		udsLog.Warn("拒绝请求: 交易对不在账户 symbols 中", "account", a.name, "symbol", symbol)
+		http.Error(w, "symbol is not configured for account "+a.name, http.StatusBadRequest)
+		return nil, false
+	}
+	return a, true
+}
+
+// Route 把按账户区分的查询路由 (如 /api/pnl) 分发到 ?account= 指定账户的处理器
+func (s *accountSet) Route(handler func(a *tradingAccount) http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := s.resolve(w, r.URL.Query().Get("account"))
+		if !ok {
+			return
+		}
+		handler(a).ServeHTTP(w, r)
+	})
+}
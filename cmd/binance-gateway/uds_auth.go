@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/udsauth"
+)
+
+var auditLog = logging.For("audit")
+
+// auditedRoutes 影响订单或持仓的 UDS 路由：除 readOnlyRoutes 中的只读查询外，任何方法的请求都需通过策略鉴权，
+// 并逐条写入审计日志
+var auditedRoutes = map[string]bool{
+	"/api/order":           true,
+	"/api/cancel":          true,
+	"/api/modify":          true,
+	"/api/position-mode":   true,
+	"/api/leverage":        true,
+	"/api/margin-type":     true,
+	"/api/position-margin": true,
+}
+
+// readOnlyRoutes auditedRoutes 中允许免鉴权的只读查询 (方法 + 路径)；其余方法即使路由本身会拒绝也照常鉴权并审计
+var readOnlyRoutes = map[string]bool{
+	http.MethodGet + " /api/position-mode": true,
+}
+
+// maxUDSBody 下单类请求体上限，防止异常客户端占满内存
+const maxUDSBody = 64 << 10
+
+// maxAuditError 审计日志中记录的错误响应体长度上限
+const maxAuditError = 512
+
+// udsAllowedUIDs 允许连接 UDS 的 UID，未配置时只允许网关自身的 UID
+func udsAllowedUIDs(cfg config.UDSConfig) []uint32 {
+	if len(cfg.AllowedUIDs) > 0 {
+		return cfg.AllowedUIDs
+	}
+	return []uint32{uint32(os.Getuid())}
+}
+
+// udsSocketPerm 只允许自身 UID 时 socket 文件为 0600 (属组不变，gid 为 -1)；放行其他 UID 时必须配置 uds.group，
+// socket 改为 0660 并归属该组，连接仍由 SO_PEERCRED 按 UID 把关
+func udsSocketPerm(cfg config.UDSConfig, uids []uint32) (os.FileMode, int, error) {
+	foreign := slices.ContainsFunc(uids, func(uid uint32) bool { return uid != uint32(os.Getuid()) })
+	if !foreign {
+		return 0600, -1, nil
+	}
+	if cfg.Group == "" {
+		return 0, -1, errors.New("uds.allowed_uids 包含网关以外的 UID 时必须配置 uds.group")
+	}
+	g, err := user.LookupGroup(cfg.Group)
+	if err != nil {
+		if g, err = user.LookupGroupId(cfg.Group); err != nil {
+			return 0, -1, fmt.Errorf("uds.group %q 不存在: %w", cfg.Group, err)
+		}
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil {
+		return 0, -1, fmt.Errorf("uds.group %q 的 GID 无效: %w", cfg.Group, err)
+	}
+	return 0660, gid, nil
+}
+
+// newUDSListener 在 Accept 时按 SO_PEERCRED 校验对端 UID，不允许的连接直接关闭；
+// 不支持读取对端身份的平台上只依赖 socket 文件权限
+func newUDSListener(l net.Listener, uids []uint32) net.Listener {
+	if !udsauth.Supported {
+		udsLog.Warn("当前平台不支持 SO_PEERCRED，UDS 仅依赖 socket 文件权限")
+		return l
+	}
+	return &udsauth.Listener{
+		Listener: l,
+		Allow: func(cred udsauth.Cred, ok bool) bool {
+			return ok && slices.Contains(uids, cred.UID)
+		},
+		OnReject: func(cred udsauth.Cred, ok bool, err error) {
+			if !ok {
+				metrics.UDSAuthRejected("peer_unknown")
+				udsLog.Warn("拒绝 UDS 连接: 无法读取对端身份", logging.Err(err))
+				return
+			}
+			metrics.UDSAuthRejected("peer_uid")
+			udsLog.Warn("拒绝 UDS 连接: 对端 UID 不在允许列表中", "pid", cred.PID, "uid", cred.UID, "allowed_uids", uids)
+		},
+	}
+}
+
+// udsGuard 包装 UDS 路由：下单类请求先按声明的策略校验 Token / HMAC，再交给原路由处理，
+// 无论成功与否都写入一条审计记录 (对端 PID、策略、请求体、结果)
+type udsGuard struct {
+	next     http.Handler
+	verifier *udsauth.Verifier
+	audit    *udsauth.AuditLog // nil 时审计记录写入 audit 组件日志
+}
+
+// newUDSGuard 按配置创建 udsGuard；配置了 audit_log_path 时打开审计日志文件
+func newUDSGuard(cfg config.UDSConfig, strategies []config.StrategyConfig, next http.Handler) (*udsGuard, error) {
+	creds := make(map[string]udsauth.Credential, len(strategies))
+	for _, s := range strategies {
+		if s.Token != "" || s.HMACSecret != "" {
+			creds[s.Name] = udsauth.Credential{Token: s.Token, HMACSecret: s.HMACSecret}
+		}
+	}
+	g := &udsGuard{next: next, verifier: udsauth.NewVerifier(creds, cfg.RequireAuth)}
+	if cfg.AuditLogPath != "" {
+		audit, err := udsauth.OpenAuditLog(cfg.AuditLogPath)
+		if err != nil {
+			return nil, err
+		}
+		g.audit = audit
+	}
+	udsLog.Info("UDS 访问控制已启用", "credentialed_strategies", len(creds), "require_auth", cfg.RequireAuth,
+		"audit_log", cfg.AuditLogPath)
+	return g, nil
+}
+
+func (g *udsGuard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !auditedRoutes[r.URL.Path] || readOnlyRoutes[r.Method+" "+r.URL.Path] {
+		g.next.ServeHTTP(w, r)
+		return
+	}
+
+	start := time.Now()
+	entry := udsauth.AuditEntry{Time: start.UnixMilli(), Method: r.Method, Path: r.URL.Path, Auth: udsauth.SchemeNone}
+	if cred, ok := udsauth.FromContext(r.Context()); ok {
+		entry.PeerPID, entry.PeerUID = cred.PID, cred.UID
+	}
+	rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		entry.Status = rec.status
+		entry.Outcome = udsauth.Outcome(rec.status)
+		if entry.Error == "" && rec.status >= 400 {
+			entry.Error = string(bytes.TrimSpace(rec.body.Bytes()))
+		}
+		entry.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		g.record(entry)
+	}()
+
+	body, err := io.ReadAll(http.MaxBytesReader(rec, r.Body, maxUDSBody))
+	if err != nil {
+		http.Error(rec, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	entry.Payload = udsauth.Payload(body)
+
+	// 只取出策略与账户用于鉴权和审计，JSON 格式错误交由各路由返回 400
+	var ident struct {
+		Strategy string `json:"strategy"`
+		Account  string `json:"account"`
+	}
+	_ = json.Unmarshal(body, &ident)
+	entry.Strategy, entry.Account = ident.Strategy, ident.Account
+
+	scheme, err := g.verifier.Verify(ident.Strategy, r, body)
+	entry.Auth = scheme
+	if err != nil {
+		reason := "unauthorized"
+		var authErr *udsauth.AuthError
+		if errors.As(err, &authErr) {
+			reason = authErr.Reason
+		}
+		entry.Error = reason
+		metrics.UDSAuthRejected(reason)
+		udsLog.Warn("UDS 鉴权失败", "path", r.URL.Path, "strategy", ident.Strategy, "reason", reason,
+			"pid", entry.PeerPID, "uid", entry.PeerUID)
+		rec.Header().Set("Content-Type", "application/json")
+		rec.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(rec).Encode(map[string]string{
+			"error":    err.Error(),
+			"category": binance.CategoryNonRetryable.String(),
+			"reason":   reason,
+		})
+		return
+	}
+	g.next.ServeHTTP(rec, r)
+}
+
+// record 写入审计记录；文件写入失败时退回组件日志，保证每次调用都有迹可查
+func (g *udsGuard) record(e udsauth.AuditEntry) {
+	if g.audit != nil {
+		err := g.audit.Record(e)
+		if err == nil {
+			return
+		}
+		auditLog.Error("审计日志写入失败", logging.Err(err))
+	}
+	auditLog.Info("UDS 调用", "path", e.Path, "method", e.Method, "peer_pid", e.PeerPID, "peer_uid", e.PeerUID,
+		"strategy", e.Strategy, "account", e.Account, "auth", e.Auth, "payload", string(e.Payload),
+		"status", e.Status, "outcome", e.Outcome, "error", e.Error, "duration_ms", e.DurationMs)
+}
+
+// Close 关闭审计日志文件
+func (g *udsGuard) Close() {
+	if g.audit != nil {
+		g.audit.Close()
+	}
+}
+
+// auditRecorder 记录响应状态码，错误响应另保留前 maxAuditError 字节用于审计
+type auditRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *auditRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *auditRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	if r.status >= 400 && r.body.Len() < maxAuditError {
+		r.body.Write(p[:min(len(p), maxAuditError-r.body.Len())])
+	}
+	return r.ResponseWriter.Write(p)
+}
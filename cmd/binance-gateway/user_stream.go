@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/events"
+	"BinanceAutoBot2/internal/logging"
+	"BinanceAutoBot2/internal/reconcile"
+	"BinanceAutoBot2/internal/strategy"
+)
+
+var userStreamLog = logging.For("user_stream")
+
+// userStreamHandler 网关订阅的私有流事件：账户 / 订单推送驱动账户模型、盈亏、账本与 Redis，
+// 其余事件用于告警与触发对账；未实现的事件由 BaseUserStreamHandler 忽略
+type userStreamHandler struct {
+	binance.BaseUserStreamHandler
+
+	ctx        context.Context
+	account    *tradingAccount
+	strategies *strategy.Registry
+	lat        *latencyTracker
+	reconciler *reconcile.Reconciler
+	recon      *reconcileSync
+	accounts   *accountSync
+	pnl        *pnlSync
+	journal    *ledgerSync
+	positions  *positionBook
+	streams    *events.Publisher
+}
+
+func (h *userStreamHandler) OnAccountUpdate(event binance.UserDataEvent) {
+	h.apply(event)
+
+	// 1. 同步最新钱包余额
+	for _, bal := range event.Account.Balances {
+		if bal.Asset == "USDT" {
+			metrics.RedisWrite("Wallet", h.account.rdb.Set(h.ctx, h.account.key("Wallet:USDT"), bal.Balance, 0).Err())
+			accountLog.Debug("余额已同步", "account", h.account.name, "asset", "USDT", "balance", bal.Balance)
+		}
+	}
+
+	// 2. 同步最新仓位与均价 (双向持仓时 ps 区分 LONG / SHORT)
+	for _, pos := range event.Account.Positions {
+		if !slices.Contains(h.account.symbols, pos.Symbol) {
+			continue
+		}
+		amt, _ := strconv.ParseFloat(pos.Amount, 64)
+		ep, _ := strconv.ParseFloat(pos.EntryPrice, 64)
+		h.positions.Update(h.ctx, pos.Symbol, pos.PositionSide, amt, ep)
+		positionLog.Debug("仓位已同步", "account", h.account.name, "symbol", pos.Symbol, "position_side", pos.PositionSide,
+			"amount", pos.Amount, "entry_price", pos.EntryPrice)
+	}
+}
+
+func (h *userStreamHandler) OnMarginCall(event binance.UserDataEvent) { h.apply(event) }
+
+func (h *userStreamHandler) OnOrderTradeUpdate(event binance.UserDataEvent) {
+	h.lat.OnOrderUpdate(event.Order)
+	h.apply(event)
+}
+
+// apply 账户模型、盈亏引擎与账本各自按事件类型消费
+func (h *userStreamHandler) apply(event binance.UserDataEvent) {
+	// 对账器先维护挂单状态；已由对账补记过的成交只推送订单状态，不再重复计入盈亏、账本与 Stream:Fills
+	fresh := true
+	if event.EventType == "ORDER_TRADE_UPDATE" {
+		fresh = h.reconciler.ApplyOrderUpdate(event.Order)
+		// 成交按 clientOrderId 前缀计入所属策略的持仓，终态订单释放策略挂单敞口
+		h.strategies.ApplyOrderUpdate(h.account.name, event.Order, fresh)
+		// 网关之外或重启前下的挂单登记到下单 Key 池，终态订单移除记录
+		switch event.Order.Status {
+		case "NEW", "PARTIALLY_FILLED":
+			h.account.keys.orders.Adopt(event.Order.ClientOrderID)
+		default:
+			h.account.keys.orders.Forget(event.Order.ClientOrderID)
+		}
+	}
+
+	// 账户模型同时消费 ACCOUNT_UPDATE 与 MARGIN_CALL
+	h.accounts.ApplyEvent(h.ctx, event)
+	if !fresh {
+		// 重复的成交只更新订单状态：Stream:Fills、盈亏与账本已由对账补记
+		metrics.RedisWrite("stream", h.streams.PublishOrderOnly(h.ctx, events.OrderEventFromUpdate(event.Order)))
+		return
+	}
+	// 盈亏引擎消费成交与资金费
+	h.pnl.ApplyEvent(h.ctx, event)
+	// 账本记录成交与撤销
+	h.journal.ApplyEvent(event)
+	// 订单与成交推送到 Stream:Orders / Stream:Fills
+	if event.EventType == "ORDER_TRADE_UPDATE" {
+		metrics.RedisWrite("stream", h.streams.PublishOrder(h.ctx, events.OrderEventFromUpdate(event.Order)))
+	}
+}
+
+// OnAccountConfigUpdate 杠杆或联合保证金模式在网关之外被修改 (网页端、其他程序)：
+// 与配置不一致时告警，并刷新账户状态 (杠杆影响保证金与强平价格)
+func (h *userStreamHandler) OnAccountConfigUpdate(event binance.AccountConfigUpdateEvent) {
+	if lv := event.Leverage; lv != nil {
+		if want := h.account.settings[lv.Symbol].Leverage; want > 0 && want != lv.Leverage {
+			userStreamLog.Warn("杠杆已被修改，与配置不一致", "account", h.account.name, "symbol", lv.Symbol,
+				"leverage", lv.Leverage, "configured", want)
+		} else {
+			userStreamLog.Info("杠杆已变更", "account", h.account.name, "symbol", lv.Symbol, "leverage", lv.Leverage)
+		}
+	}
+	if am := event.AssetMode; am != nil {
+		userStreamLog.Warn("联合保证金模式已变更", "account", h.account.name, "multi_assets", am.MultiAssets)
+	}
+	h.accounts.RequestRefresh()
+}
+
+func (h *userStreamHandler) OnTradeLite(event binance.TradeLiteEvent) {
+	userStreamLog.Debug("精简成交推送", "symbol", event.Symbol, "client_order_id", event.ClientOrderID,
+		"order_id", event.OrderID, "price", event.LastFilledPrice, "qty", event.LastFilledQty)
+}
+
+func (h *userStreamHandler) OnStrategyUpdate(event binance.StrategyUpdateEvent) {
+	s := event.Strategy
+	userStreamLog.Info("交易所策略状态变更", "symbol", s.Symbol, "strategy_id", s.StrategyID,
+		"strategy_type", s.StrategyType, "status", s.Status, "code", s.OpCode)
+}
+
+func (h *userStreamHandler) OnGridUpdate(event binance.GridUpdateEvent) {
+	g := event.Grid
+	userStreamLog.Debug("网格策略更新", "symbol", g.Symbol, "strategy_id", g.StrategyID,
+		"status", g.Status, "realized_pnl", g.RealizedPnL)
+}
+
+// OnConditionalOrderTriggerReject 止损 / 止盈单触发后被拒绝意味着保护失效，告警并立即对账
+func (h *userStreamHandler) OnConditionalOrderTriggerReject(event binance.ConditionalOrderTriggerRejectEvent) {
+	userStreamLog.Error("条件单触发被拒绝", "account", h.account.name, "symbol", event.Order.Symbol, "order_id", event.Order.OrderID,
+		"reason", event.Order.Reason)
+	h.recon.Trigger()
+}
+
+func (h *userStreamHandler) OnUnknownEvent(eventType string, _ []byte) {
+	userStreamLog.Debug("忽略未处理的私有流事件", "event", eventType)
+}
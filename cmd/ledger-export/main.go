@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"BinanceAutoBot2/internal/config"
+	"BinanceAutoBot2/internal/ledger"
+)
+
+// 交易账本 CSV 导出工具
+//
+//	go run ./cmd/ledger-export -strategy grid -from 2026-01-01T00:00:00Z -out grid.csv
+//
+// 网关运行中账本文件被独占锁定，此时加 -sock /tmp/quant_engine.sock 通过 UDS 查询接口导出
+func main() {
+	cfgPath := flag.String("config", "config.json", "配置文件路径 (读取 ledger.path)")
+	dbPath := flag.String("db", "", "账本文件路径，默认取配置中的 ledger.path")
+	sock := flag.String("sock", "", "网关 UDS 路径；指定后通过 /api/ledger 查询而不是直接读文件")
+	account := flag.String("account", "", "按账户过滤 (多账户模式)")
+	strategy := flag.String("strategy", "", "按策略过滤")
+	symbol := flag.String("symbol", "", "按交易对过滤")
+	kind := flag.String("kind", "", "按记录类型过滤 (order_request / order_ack / fill / ...)")
+	from := flag.String("from", "", "起始时间 (毫秒时间戳或 RFC3339)")
+	to := flag.String("to", "", "结束时间 (毫秒时间戳或 RFC3339)")
+	out := flag.String("out", "", "输出文件，默认标准输出")
+	flag.Parse()
+
+	filter := ledger.Filter{Account: *account, Strategy: *strategy, Symbol: *symbol, Kind: *kind}
+	var err error
+	if filter.From, err = ledger.ParseTime(*from); err != nil {
+		log.Fatalf("❌ -from 格式错误: %v", err)
+	}
+	if filter.To, err = ledger.ParseTime(*to); err != nil {
+		log.Fatalf("❌ -to 格式错误: %v", err)
+	}
+
+	var entries []ledger.Entry
+	if *sock != "" {
+		entries, err = queryUDS(*sock, filter)
+	} else {
+		entries, err = queryFile(*cfgPath, *dbPath, filter)
+	}
+	if err != nil {
+		log.Fatalf("❌ 读取账本失败: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("❌ 创建输出文件失败: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := ledger.WriteCSV(w, entries); err != nil {
+		log.Fatalf("❌ 写出 CSV 失败: %v", err)
+	}
+	log.Printf("✅ 已导出 %d 条记录", len(entries))
+}
+
+func queryFile(cfgPath, dbPath string, filter ledger.Filter) ([]ledger.Entry, error) {
+	if dbPath == "" {
+		cfg, err := config.LoadConfig(cfgPath)
+		if err != nil {
+			return nil, err
+		}
+		dbPath = cfg.Ledger.Path
+	}
+	if dbPath == "" {
+		return nil, fmt.Errorf("未指定账本路径 (-db 或 ledger.path)")
+	}
+	store, err := ledger.Open(dbPath, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w (网关运行中请改用 -sock)", err)
+	}
+	defer store.Close()
+	return store.Query(filter)
+}
+
+func queryUDS(sock string, filter ledger.Filter) ([]ledger.Entry, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+			},
+		},
+	}
+
+	q := url.Values{}
+	if filter.Account != "" {
+		q.Set("account", filter.Account)
+	}
+	if filter.Strategy != "" {
+		q.Set("strategy", filter.Strategy)
+	}
+	if filter.Symbol != "" {
+		q.Set("symbol", filter.Symbol)
+	}
+	if filter.Kind != "" {
+		q.Set("kind", filter.Kind)
+	}
+	if filter.From > 0 {
+		q.Set("from", strconv.FormatInt(filter.From, 10))
+	}
+	if filter.To > 0 {
+		q.Set("to", strconv.FormatInt(filter.To, 10))
+	}
+
+	resp, err := client.Get("http://unix/api/ledger?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	var entries []ledger.Entry
+	err = json.NewDecoder(resp.Body).Decode(&entries)
+	return entries, err
+}
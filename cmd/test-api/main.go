@@ -30,10 +30,12 @@ func main() {
 
 	// 4. 发起余额查询测试
 	log.Printf("Fetching Balance from: %s", client.BaseURL)
-	balanceJSON, err := client.GetAccountBalance()
+	balances, err := client.GetAccountBalance()
 	if err != nil {
 		log.Fatalf("Failed to get balance: %v", err)
 	}
 
-	log.Println("Account Balance Response:", balanceJSON)
+	for _, b := range balances {
+		log.Printf("Asset %s: balance=%v available=%v crossUnPnl=%v", b.Asset, b.Balance, b.AvailableBalance, b.CrossUnPnl)
+	}
 }
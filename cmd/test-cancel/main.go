@@ -31,10 +31,10 @@ func main() {
 	log.Printf("🗑️ 准备撤销订单: Symbol=%s, ClientOrderID=%s", cancelReq.Symbol, cancelReq.OrigClientOrderID)
 
 	// 4. 执行撤单调用
-	resultJSON, err := client.CancelOrder(cancelReq)
+	order, err := client.CancelOrder(cancelReq)
 	if err != nil {
 		log.Fatalf("❌ 撤单失败: %v", err)
 	}
 
-	log.Printf("✅ 撤单成功！交易所返回结果:\n%s\n", resultJSON)
+	log.Printf("✅ 撤单成功！OrderID: %d | 状态: %s | 已成交: %v / %v", order.OrderID, order.Status, order.ExecutedQty, order.OrigQty)
 }
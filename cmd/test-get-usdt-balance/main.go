@@ -29,5 +29,5 @@ func main() {
 	}
 
 	log.Printf("✅ 查询成功！")
-	log.Printf("💰 当前可用 USDT 余额: %s", balance)
+	log.Printf("💰 当前 USDT 余额: %v | 可用: %v", balance.Balance, balance.AvailableBalance)
 }
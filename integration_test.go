@@ -2,6 +2,7 @@ package integration
 
 import (
 	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/events"
 	"BinanceAutoBot2/internal/orderbook"
 	"context"
 	"encoding/json"
@@ -117,6 +118,51 @@ func TestRedisPositionAndBalance(t *testing.T) {
 	}
 }
 
+// ---- Redis Streams 事件推送集成测试 ----
+
+func TestEventStreamsToRedis(t *testing.T) {
+	if !redisAvailable() {
+		t.Skip("Redis not available, skipping integration test")
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379", DB: 15})
+	defer rdb.FlushDB(context.Background())
+	defer rdb.Close()
+
+	ctx := context.Background()
+	pub := events.NewPublisher(rdb, 100, nil)
+
+	fill := events.OrderEvent{Symbol: "BTCUSDT", ClientOrderID: "grid_1", ExecutionType: "TRADE", Status: "FILLED", LastFilledQty: 0.01}
+	if err := pub.PublishOrder(ctx, fill); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	// 成交同时出现在 Stream:Orders 与 Stream:Fills
+	res, err := rdb.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{events.StreamOrders, events.StreamFills, "0", "0"},
+		Block:   time.Second,
+	}).Result()
+	if err != nil {
+		t.Fatalf("XREAD failed: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("expected both streams to have messages, got %d", len(res))
+	}
+	for _, s := range res {
+		msg := s.Messages[0].Values
+		if msg["v"] != events.SchemaVersion || msg["symbol"] != "BTCUSDT" {
+			t.Errorf("unexpected message fields in %s: %v", s.Stream, msg)
+		}
+		var got events.OrderEvent
+		if err := json.Unmarshal([]byte(msg["data"].(string)), &got); err != nil || got.ClientOrderID != "grid_1" {
+			t.Errorf("unexpected payload in %s: %v (%v)", s.Stream, msg["data"], err)
+		}
+	}
+	if res[0].Messages[0].Values["type"] != events.TypeOrder || res[1].Messages[0].Values["type"] != events.TypeFill {
+		t.Errorf("unexpected event types: %v / %v", res[0].Messages[0].Values["type"], res[1].Messages[0].Values["type"])
+	}
+}
+
 // ---- UDS HTTP 集成测试 ----
 
 func TestUDSOrderEndpoint(t *testing.T) {
@@ -266,14 +312,14 @@ func TestAPIClientFullOrderFlow(t *testing.T) {
 	if err != nil {
 		t.Fatalf("PlaceOrder failed: %v", err)
 	}
-	if result == "" {
-		t.Error("PlaceOrder result should not be empty")
+	if result.OrderID != 99999 || result.Status != "NEW" {
+		t.Errorf("unexpected PlaceOrder result: %+v", result)
 	}
 
 	// 4. 查询仓位
-	amt, ep, err := c.GetPosition("BTCUSDT")
-	if err != nil || amt != "0.01" || ep != "50000.0" {
-		t.Fatalf("GetPosition failed: %v, amt=%s, ep=%s", err, amt, ep)
+	pos, err := c.GetPosition("BTCUSDT")
+	if err != nil || pos.PositionAmt != 0.01 || pos.EntryPrice != 50000.0 {
+		t.Fatalf("GetPosition failed: %v, pos=%+v", err, pos)
 	}
 
 	// 5. 撤单
@@ -284,7 +330,7 @@ func TestAPIClientFullOrderFlow(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CancelOrder failed: %v", err)
 	}
-	if cancelResult == "" {
-		t.Error("CancelOrder result should not be empty")
+	if cancelResult.Status != "CANCELED" {
+		t.Errorf("expected CANCELED, got %s", cancelResult.Status)
 	}
 }
@@ -0,0 +1,301 @@
+package account
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// SettlementAsset 单资产模式下的保证金结算币种，账户汇总值以它为准
+const SettlementAsset = "USDT"
+
+// AssetState 单个资产的余额状态
+type AssetState struct {
+	Asset              string  `json:"asset"`
+	WalletBalance      float64 `json:"wallet_balance"`
+	CrossWalletBalance float64 `json:"cross_wallet_balance"`
+	UnrealizedProfit   float64 `json:"unrealized_profit"`
+	MarginBalance      float64 `json:"margin_balance"`
+	AvailableBalance   float64 `json:"available_balance"`
+	MaintMargin        float64 `json:"maint_margin"`
+}
+
+// PositionState 单条持仓腿的风险状态
+type PositionState struct {
+	Symbol           string  `json:"symbol"`
+	PositionSide     string  `json:"position_side"` // BOTH / LONG / SHORT
+	Amount           float64 `json:"amount"`
+	EntryPrice       float64 `json:"entry_price"`
+	BreakEvenPrice   float64 `json:"break_even_price"`
+	MarkPrice        float64 `json:"mark_price"`
+	UnrealizedProfit float64 `json:"unrealized_profit"`
+	MarginType       string  `json:"margin_type"` // cross / isolated
+	IsolatedWallet   float64 `json:"isolated_wallet"`
+	Leverage         int     `json:"leverage"`
+	MaintMargin      float64 `json:"maint_margin"`
+	LiquidationPrice float64 `json:"liquidation_price"`
+}
+
+// Snapshot 某一时刻账户状态的完整副本，整体写入 Redis 保证读取端看到的是一致的文档
+type Snapshot struct {
+	UpdateTime            int64           `json:"update_time"`
+	TotalWalletBalance    float64         `json:"total_wallet_balance"`
+	TotalUnrealizedProfit float64         `json:"total_unrealized_profit"`
+	TotalMarginBalance    float64         `json:"total_margin_balance"`
+	TotalMaintMargin      float64         `json:"total_maint_margin"`
+	AvailableBalance      float64         `json:"available_balance"`
+	MarginRatio           float64         `json:"margin_ratio"` // 维持保证金 / 保证金余额，达到 1 即触发强平
+	Assets                []AssetState    `json:"assets"`
+	Positions             []PositionState `json:"positions"`
+}
+
+// Account 账户状态模型：启动时由 REST 全量初始化，之后由 ACCOUNT_UPDATE / MARGIN_CALL 增量更新
+type Account struct {
+	mu               sync.RWMutex
+	updateTime       int64
+	availableBalance float64
+	assets           map[string]*AssetState
+	positions        map[string]*PositionState // key: symbol:positionSide
+	pushed           map[string]int64          // 资产 / 持仓腿 → 最近一次推送的时间，REST 中更早的数据不得覆盖
+}
+
+// NewAccount 创建空的账户模型
+func NewAccount() *Account {
+	return &Account{
+		assets:    make(map[string]*AssetState),
+		positions: make(map[string]*PositionState),
+		pushed:    make(map[string]int64),
+	}
+}
+
+func assetPushKey(asset string) string { return "asset:" + asset }
+
+func positionPushKey(symbol, positionSide string) string {
+	return "position:" + positionKey(symbol, positionSide)
+}
+
+// stale REST 返回的数据 (updateTime) 是否早于同一资产 / 持仓腿最近一次推送
+func (a *Account) stale(key string, restTime int64) bool {
+	t, ok := a.pushed[key]
+	return ok && restTime < t
+}
+
+func positionKey(symbol, positionSide string) string {
+	if positionSide == "" {
+		positionSide = "BOTH"
+	}
+	return symbol + ":" + positionSide
+}
+
+func (a *Account) position(symbol, positionSide string) *PositionState {
+	key := positionKey(symbol, positionSide)
+	p, ok := a.positions[key]
+	if !ok {
+		if positionSide == "" {
+			positionSide = "BOTH"
+		}
+		p = &PositionState{Symbol: symbol, PositionSide: positionSide}
+		a.positions[key] = p
+	}
+	return p
+}
+
+func (a *Account) asset(name string) *AssetState {
+	s, ok := a.assets[name]
+	if !ok {
+		s = &AssetState{Asset: name}
+		a.assets[name] = s
+	}
+	return s
+}
+
+// LoadFromREST 用 /fapi/v2/account 与 /fapi/v2/positionRisk 的结果全量覆写账户状态
+// positionRisk 提供 account 接口没有的标记价格与强平价格；updateTime 早于最近一次推送的资产与持仓腿
+// 保留推送的状态 (REST 请求慢于推送时返回的是旧数据)
+func (a *Account) LoadFromREST(info *binance.AccountInfo, risks []binance.PositionRisk) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.updateTime = info.UpdateTime
+	a.availableBalance = info.AvailableBalance
+	assets := make(map[string]*AssetState, len(info.Assets))
+	for _, as := range info.Assets {
+		if a.stale(assetPushKey(as.Asset), as.UpdateTime) {
+			if old, ok := a.assets[as.Asset]; ok {
+				assets[as.Asset] = old
+			}
+			continue
+		}
+		assets[as.Asset] = &AssetState{
+			Asset:              as.Asset,
+			WalletBalance:      as.WalletBalance,
+			CrossWalletBalance: as.CrossWalletBalance,
+			UnrealizedProfit:   as.UnrealizedProfit,
+			MarginBalance:      as.MarginBalance,
+			AvailableBalance:   as.AvailableBalance,
+			MaintMargin:        as.MaintMargin,
+		}
+	}
+	a.assets = assets
+
+	old := a.positions
+	a.positions = make(map[string]*PositionState)
+	for _, p := range info.Positions {
+		if a.stale(positionPushKey(p.Symbol, p.PositionSide), p.UpdateTime) {
+			if pos, ok := old[positionKey(p.Symbol, p.PositionSide)]; ok {
+				a.positions[positionKey(p.Symbol, p.PositionSide)] = pos
+			}
+			continue
+		}
+		if p.PositionAmt == 0 {
+			continue
+		}
+		pos := a.position(p.Symbol, p.PositionSide)
+		pos.Amount = p.PositionAmt
+		pos.EntryPrice = p.EntryPrice
+		pos.UnrealizedProfit = p.UnrealizedProfit
+		pos.Leverage = p.Leverage
+		pos.MaintMargin = p.MaintMargin
+		pos.IsolatedWallet = p.IsolatedWallet
+		pos.MarginType = "cross"
+		if p.Isolated {
+			pos.MarginType = "isolated"
+		}
+	}
+	a.applyPositionRisk(risks)
+}
+
+// ApplyPositionRisk 用 positionRisk 刷新标记价格、强平价格等风险字段
+func (a *Account) ApplyPositionRisk(risks []binance.PositionRisk) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.applyPositionRisk(risks)
+}
+
+func (a *Account) applyPositionRisk(risks []binance.PositionRisk) {
+	for _, r := range risks {
+		if a.stale(positionPushKey(r.Symbol, r.PositionSide), r.UpdateTime) {
+			continue
+		}
+		key := positionKey(r.Symbol, r.PositionSide)
+		if r.PositionAmt == 0 {
+			delete(a.positions, key)
+			continue
+		}
+		pos := a.position(r.Symbol, r.PositionSide)
+		pos.Amount = r.PositionAmt
+		pos.EntryPrice = r.EntryPrice
+		pos.MarkPrice = r.MarkPrice
+		pos.UnrealizedProfit = r.UnRealizedProfit
+		pos.LiquidationPrice = r.LiquidationPrice
+		pos.Leverage = r.Leverage
+		pos.MarginType = r.MarginType
+		pos.IsolatedWallet = r.IsolatedWallet
+		if r.UpdateTime > a.updateTime {
+			a.updateTime = r.UpdateTime
+		}
+	}
+}
+
+// ApplyEvent 处理用户数据流推送的 ACCOUNT_UPDATE 与 MARGIN_CALL
+func (a *Account) ApplyEvent(event binance.UserDataEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// ACCOUNT_UPDATE 的撮合时间 T 与 REST 的 updateTime 同源；MARGIN_CALL 没有 T，按事件时间
+	pushed := event.TransactionTime
+	if pushed == 0 {
+		pushed = event.EventTime
+	}
+	switch event.EventType {
+	case "ACCOUNT_UPDATE":
+		for _, b := range event.Account.Balances {
+			a.pushed[assetPushKey(b.Asset)] = pushed
+			as := a.asset(b.Asset)
+			as.WalletBalance = parseFloat(b.Balance)
+			as.CrossWalletBalance = parseFloat(b.CrossWalletBalance)
+		}
+		for _, p := range event.Account.Positions {
+			a.pushed[positionPushKey(p.Symbol, p.PositionSide)] = pushed
+			amount := parseFloat(p.Amount)
+			if amount == 0 {
+				delete(a.positions, positionKey(p.Symbol, p.PositionSide))
+				continue
+			}
+			pos := a.position(p.Symbol, p.PositionSide)
+			pos.Amount = amount
+			pos.EntryPrice = parseFloat(p.EntryPrice)
+			pos.BreakEvenPrice = parseFloat(p.BreakEvenPrice)
+			pos.UnrealizedProfit = parseFloat(p.UnrealizedPnL)
+			pos.MarginType = strings.ToLower(p.MarginType)
+			pos.IsolatedWallet = parseFloat(p.IsolatedWallet)
+		}
+	case "MARGIN_CALL":
+		if event.CrossWalletBalance != "" {
+			a.pushed[assetPushKey(SettlementAsset)] = pushed
+			a.asset(SettlementAsset).CrossWalletBalance = parseFloat(event.CrossWalletBalance)
+		}
+		for _, p := range event.MarginCallPositions {
+			a.pushed[positionPushKey(p.Symbol, p.PositionSide)] = pushed
+			pos := a.position(p.Symbol, p.PositionSide)
+			pos.Amount = parseFloat(p.Amount)
+			pos.MarkPrice = parseFloat(p.MarkPrice)
+			pos.UnrealizedProfit = parseFloat(p.UnrealizedPnL)
+			pos.MaintMargin = parseFloat(p.MaintMargin)
+			pos.MarginType = strings.ToLower(p.MarginType)
+			pos.IsolatedWallet = parseFloat(p.IsolatedWallet)
+		}
+	default:
+		return
+	}
+
+	if event.EventTime > a.updateTime {
+		a.updateTime = event.EventTime
+	}
+}
+
+// Snapshot 返回当前账户状态的一致副本，汇总值在同一把锁内计算
+func (a *Account) Snapshot() Snapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	snap := Snapshot{
+		UpdateTime:       a.updateTime,
+		AvailableBalance: a.availableBalance,
+		Assets:           make([]AssetState, 0, len(a.assets)),
+		Positions:        make([]PositionState, 0, len(a.positions)),
+	}
+
+	for _, p := range a.positions {
+		snap.Positions = append(snap.Positions, *p)
+		snap.TotalUnrealizedProfit += p.UnrealizedProfit
+		snap.TotalMaintMargin += p.MaintMargin
+	}
+	for _, as := range a.assets {
+		snap.Assets = append(snap.Assets, *as)
+	}
+	if settle, ok := a.assets[SettlementAsset]; ok {
+		snap.TotalWalletBalance = settle.WalletBalance
+	}
+	snap.TotalMarginBalance = snap.TotalWalletBalance + snap.TotalUnrealizedProfit
+	if snap.TotalMarginBalance > 0 {
+		snap.MarginRatio = snap.TotalMaintMargin / snap.TotalMarginBalance
+	}
+
+	// map 遍历顺序随机，排序后保证输出稳定，方便下游做 diff
+	sort.Slice(snap.Assets, func(i, j int) bool { return snap.Assets[i].Asset < snap.Assets[j].Asset })
+	sort.Slice(snap.Positions, func(i, j int) bool {
+		return positionKey(snap.Positions[i].Symbol, snap.Positions[i].PositionSide) <
+			positionKey(snap.Positions[j].Symbol, snap.Positions[j].PositionSide)
+	})
+	return snap
+}
+
+// parseFloat 币安推送的数值均为字符串，解析失败按 0 处理
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
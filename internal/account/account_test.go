@@ -0,0 +1,168 @@
+package account
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func makeAccountInfo() *binance.AccountInfo {
+	return &binance.AccountInfo{
+		AvailableBalance: 900,
+		UpdateTime:       1000,
+		Assets: []binance.AccountAsset{
+			{Asset: "USDT", WalletBalance: 1000, CrossWalletBalance: 1000, AvailableBalance: 900},
+			{Asset: "BNB", WalletBalance: 2},
+		},
+		Positions: []binance.AccountPosition{
+			{Symbol: "BTCUSDT", PositionSide: "BOTH", PositionAmt: 0.1, EntryPrice: 50000, MaintMargin: 20, Leverage: 5},
+			{Symbol: "ETHUSDT", PositionSide: "BOTH", PositionAmt: 0},
+		},
+	}
+}
+
+func TestLoadFromREST(t *testing.T) {
+	a := NewAccount()
+	a.LoadFromREST(makeAccountInfo(), []binance.PositionRisk{
+		{Symbol: "BTCUSDT", PositionSide: "BOTH", PositionAmt: 0.1, EntryPrice: 50000, MarkPrice: 51000,
+			UnRealizedProfit: 100, LiquidationPrice: 41000, Leverage: 5, MarginType: "cross"},
+	})
+
+	snap := a.Snapshot()
+	if len(snap.Assets) != 2 {
+		t.Errorf("expected 2 assets, got %d", len(snap.Assets))
+	}
+	if len(snap.Positions) != 1 {
+		t.Fatalf("flat positions should be skipped, got %d", len(snap.Positions))
+	}
+	pos := snap.Positions[0]
+	if pos.LiquidationPrice != 41000 || pos.MarkPrice != 51000 || pos.MaintMargin != 20 {
+		t.Errorf("unexpected position state: %+v", pos)
+	}
+	if snap.TotalWalletBalance != 1000 || snap.AvailableBalance != 900 {
+		t.Errorf("unexpected totals: %+v", snap)
+	}
+	if !almostEqual(snap.TotalMarginBalance, 1100) {
+		t.Errorf("expected margin balance 1100, got %v", snap.TotalMarginBalance)
+	}
+	if !almostEqual(snap.MarginRatio, 20.0/1100) {
+		t.Errorf("expected margin ratio %v, got %v", 20.0/1100, snap.MarginRatio)
+	}
+}
+
+func TestApplyEvent_AccountUpdate(t *testing.T) {
+	a := NewAccount()
+	a.LoadFromREST(makeAccountInfo(), nil)
+
+	var event binance.UserDataEvent
+	raw := `{"e":"ACCOUNT_UPDATE","E":2000,"a":{"m":"ORDER",
+		"B":[{"a":"USDT","wb":"990.5","cw":"990.5","bc":"0"}],
+		"P":[{"s":"BTCUSDT","pa":"0","ep":"0","up":"0","mt":"cross","iw":"0","ps":"BOTH"},
+		     {"s":"ETHUSDT","pa":"-1.5","ep":"3000","bep":"2999","up":"-12.5","mt":"isolated","iw":"300","ps":"BOTH"}]}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+	a.ApplyEvent(event)
+
+	snap := a.Snapshot()
+	if snap.UpdateTime != 2000 {
+		t.Errorf("expected update time 2000, got %d", snap.UpdateTime)
+	}
+	if snap.TotalWalletBalance != 990.5 {
+		t.Errorf("expected wallet 990.5, got %v", snap.TotalWalletBalance)
+	}
+	if len(snap.Positions) != 1 || snap.Positions[0].Symbol != "ETHUSDT" {
+		t.Fatalf("closed BTC position should be removed, got %+v", snap.Positions)
+	}
+	eth := snap.Positions[0]
+	if eth.Amount != -1.5 || eth.MarginType != "isolated" || eth.IsolatedWallet != 300 || eth.BreakEvenPrice != 2999 {
+		t.Errorf("unexpected ETH position: %+v", eth)
+	}
+	if snap.TotalUnrealizedProfit != -12.5 {
+		t.Errorf("expected total upnl -12.5, got %v", snap.TotalUnrealizedProfit)
+	}
+}
+
+func TestApplyEvent_MarginCall(t *testing.T) {
+	a := NewAccount()
+	a.LoadFromREST(makeAccountInfo(), nil)
+
+	var event binance.UserDataEvent
+	raw := `{"e":"MARGIN_CALL","E":3000,"cw":"3.16","p":[
+		{"s":"BTCUSDT","ps":"BOTH","pa":"0.1","mt":"CROSSED","iw":"0","mp":"45000","up":"-500","mm":"450"}]}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+	a.ApplyEvent(event)
+
+	snap := a.Snapshot()
+	pos := snap.Positions[0]
+	if pos.MarkPrice != 45000 || pos.MaintMargin != 450 || pos.UnrealizedProfit != -500 {
+		t.Errorf("unexpected position after margin call: %+v", pos)
+	}
+	if !almostEqual(snap.MarginRatio, 450.0/500) {
+		t.Errorf("expected margin ratio 0.9, got %v", snap.MarginRatio)
+	}
+}
+
+func TestApplyEvent_HedgeModeLegs(t *testing.T) {
+	a := NewAccount()
+
+	var event binance.UserDataEvent
+	raw := `{"e":"ACCOUNT_UPDATE","E":1,"a":{"m":"ORDER","B":[],
+		"P":[{"s":"BTCUSDT","pa":"0.2","ep":"50000","ps":"LONG"},
+		     {"s":"BTCUSDT","pa":"-0.1","ep":"51000","ps":"SHORT"}]}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+	a.ApplyEvent(event)
+
+	snap := a.Snapshot()
+	if len(snap.Positions) != 2 {
+		t.Fatalf("expected LONG and SHORT legs, got %d", len(snap.Positions))
+	}
+	if snap.Positions[0].PositionSide != "LONG" || snap.Positions[1].PositionSide != "SHORT" {
+		t.Errorf("positions should be sorted by key: %+v", snap.Positions)
+	}
+}
+
+func TestLoadFromREST_KeepsNewerPushedState(t *testing.T) {
+	a := NewAccount()
+	var event binance.UserDataEvent
+	raw := `{"e":"ACCOUNT_UPDATE","E":5001,"T":5000,"a":{"m":"ORDER",
+		"B":[{"a":"USDT","wb":"950","cw":"950"}],
+		"P":[{"s":"BTCUSDT","pa":"0.3","ep":"50500","up":"0","mt":"cross","iw":"0","ps":"BOTH"}]}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+	a.ApplyEvent(event)
+
+	// 慢 REST 响应：USDT 与 BTCUSDT 的 updateTime 早于推送，不得覆盖；BNB 没有推送，正常写入
+	info := makeAccountInfo()
+	info.Assets[0].UpdateTime, info.Assets[1].UpdateTime = 4000, 4000
+	info.Positions[0].UpdateTime = 4000
+	a.LoadFromREST(info, []binance.PositionRisk{
+		{Symbol: "BTCUSDT", PositionSide: "BOTH", PositionAmt: 0.1, MarkPrice: 51000, UpdateTime: 4000},
+	})
+	snap := a.Snapshot()
+	if snap.TotalWalletBalance != 950 || len(snap.Assets) != 2 {
+		t.Errorf("stale REST balance should not overwrite the pushed one: %+v", snap.Assets)
+	}
+	if len(snap.Positions) != 1 || snap.Positions[0].Amount != 0.3 || snap.Positions[0].EntryPrice != 50500 {
+		t.Errorf("stale REST position should not overwrite the pushed one: %+v", snap.Positions)
+	}
+
+	// updateTime 不早于推送的 REST 数据照常覆写
+	info.Assets[0].UpdateTime, info.Positions[0].UpdateTime = 5000, 6000
+	a.LoadFromREST(info, nil)
+	snap = a.Snapshot()
+	if snap.TotalWalletBalance != 1000 || snap.Positions[0].Amount != 0.1 {
+		t.Errorf("fresh REST data should be applied: %+v", snap)
+	}
+}
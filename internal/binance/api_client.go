@@ -9,7 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	_ "strings"
+	"strconv"
 	"time"
 )
 
@@ -19,17 +19,32 @@ type APIClient struct {
 	APIKey     string
 	APISecret  string
 	HTTPClient *http.Client
+	Retry      RetryPolicy // 失败请求的自动重试策略
+	// OnRoundTrip 每次 HTTP 请求 (含重试的每一次) 完成后回调，用于延迟统计，可为 nil
+	OnRoundTrip func(rt RoundTrip)
+}
+
+// RoundTrip 一次 HTTP 请求的观测数据
+type RoundTrip struct {
+	Method   string
+	Endpoint string        // 不含查询参数，如 /fapi/v1/order
+	Duration time.Duration // 发出请求到读完响应体
+	Status   int           // HTTP 状态码，网络错误时为 0
+	Err      error
+	// UsedWeight1m / OrderCount1m 取自响应头 X-MBX-USED-WEIGHT-1M / X-MBX-ORDER-COUNT-1M，
+	// 即当前 IP 一分钟内已用的请求权重与当前账户一分钟内的下单数；响应未携带时为 -1
+	UsedWeight1m int
+	OrderCount1m int
 }
 
 // NewAPIClient 初始化客户端
 func NewAPIClient(apiKey, apiSecret string) *APIClient {
 	return &APIClient{
 		// BaseURL:   "https://fapi.binance.com", // U本位合约实盘地址
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-		HTTPClient: &http.Client{
-			Timeout: 5 * time.Second, // 交易请求必须有超时控制
-		},
+		APIKey:     apiKey,
+		APISecret:  apiSecret,
+		HTTPClient: DefaultHTTPClient, // 共用调优过的连接池
+		Retry:      DefaultRetryPolicy,
 	}
 }
 
@@ -40,112 +55,157 @@ func (c *APIClient) createSignature(queryString string) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// GetUSDTBalance 主动调用 REST API 查询合约账户的 USDT 余额
-func (c *APIClient) GetUSDTBalance() (string, error) {
-	// 1. 构造请求参数
-	params := url.Values{}
-	params.Add("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	params.Add("recvWindow", "5000") // 允许 5 秒的网络延迟窗口
-
-	queryString := params.Encode()
-
-	// 2. HMAC-SHA256 签名
-	mac := hmac.New(sha256.New, []byte(c.APISecret))
-	mac.Write([]byte(queryString))
-	signature := hex.EncodeToString(mac.Sum(nil))
-
-	// 3. 拼接最终请求 URL (币安 U本位合约查询余额接口为 /fapi/v2/balance)
-	reqURL := fmt.Sprintf("%s/fapi/v2/balance?%s&signature=%s", c.BaseURL, queryString, signature)
-
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+// do 发送请求并读取响应体，非 2xx 响应统一转换为 *APIError
+func (c *APIClient) do(method, endpoint, fullURL string) ([]byte, error) {
+	req, err := http.NewRequest(method, fullURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	// 注入 API Key
 	req.Header.Set("X-MBX-APIKEY", c.APIKey)
 
-	// 4. 发送请求并解析
+	start := time.Now()
+	status, usedWeight, orderCount := 0, -1, -1
+	if c.OnRoundTrip != nil {
+		defer func() {
+			c.OnRoundTrip(RoundTrip{Method: method, Endpoint: endpoint, Duration: time.Since(start), Status: status, Err: err,
+				UsedWeight1m: usedWeight, OrderCount1m: orderCount})
+		}()
+	}
+
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	status = resp.StatusCode
+	usedWeight = headerInt(resp.Header, "X-MBX-USED-WEIGHT-1M")
+	orderCount = headerInt(resp.Header, "X-MBX-ORDER-COUNT-1M")
 
-	body, _ := io.ReadAll(resp.Body)
-
-	// 如果状态码不是 200 OK，说明报错了 (比如签名错误、IP限制)
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API 报错 [%d]: %s", resp.StatusCode, string(body))
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-
-	// 5. 解析 JSON 数组
-	var balances []map[string]interface{}
-	if err := json.Unmarshal(body, &balances); err != nil {
-		return "", fmt.Errorf("JSON 解析失败: %s", string(body))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = newAPIError(resp.StatusCode, body)
+		return nil, err
 	}
+	return body, nil
+}
 
-	// 6. 遍历寻找 USDT 资产
-	for _, b := range balances {
-		if asset, ok := b["asset"].(string); ok && asset == "USDT" {
-			if bal, ok := b["balance"].(string); ok {
-				return bal, nil
-			}
-		}
+// headerInt 解析整数响应头，缺失或格式错误时返回 -1
+func headerInt(h http.Header, key string) int {
+	v, err := strconv.Atoi(h.Get(key))
+	if err != nil {
+		return -1
 	}
-
-	return "0.00", fmt.Errorf("未找到 USDT 资产信息")
+	return v
 }
 
-// GetAccountBalance 获取合约账户余额 (安全测试鉴权的最佳接口)
-func (c *APIClient) GetAccountBalance() (string, error) {
-	endpoint := "/fapi/v2/balance"
-
-	// 1. 构造请求参数
-	params := url.Values{}
+// signedRequest 为参数追加 timestamp/recvWindow 并签名后发送一次
+// 每次调用都会生成新的时间戳，因此重试时不会因 -1021 反复失败
+func (c *APIClient) signedRequest(method, endpoint string, params url.Values) ([]byte, error) {
+	q := url.Values{}
+	for k, v := range params {
+		q[k] = v
+	}
 	// 必须带上 timestamp，防重放攻击
-	params.Add("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	// 可选：recvWindow 防止网络延迟导致的请求过期，默认 5000ms
-	params.Add("recvWindow", "5000")
+	q.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
+	// recvWindow 防止网络延迟导致的请求过期
+	q.Set("recvWindow", "5000")
 
-	// 2. 生成签名
-	queryString := params.Encode()
+	queryString := q.Encode()
 	signature := c.createSignature(queryString)
+	return c.do(method, endpoint, fmt.Sprintf("%s%s?%s&signature=%s", c.BaseURL, endpoint, queryString, signature))
+}
 
-	// 3. 拼接最终请求 URL (包含签名)
-	fullURL := fmt.Sprintf("%s%s?%s&signature=%s", c.BaseURL, endpoint, queryString, signature)
+// keyedRequest 只需 API Key、无需签名的接口 (如 listenKey 管理)
+func (c *APIClient) keyedRequest(method, endpoint string, params url.Values) ([]byte, error) {
+	fullURL := c.BaseURL + endpoint
+	if len(params) > 0 {
+		fullURL += "?" + params.Encode()
+	}
+	return c.do(method, endpoint, fullURL)
+}
 
-	// 4. 构造 HTTP 请求
-	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
-	if err != nil {
-		return "", err
+// withRetry 按 RetryPolicy 重试 fn
+// idempotent 为 true 时 (查询类接口)，结果未知的错误也可直接重发；否则只重试确定未执行的错误
+func (c *APIClient) withRetry(idempotent bool, fn func() ([]byte, error)) ([]byte, error) {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		body, err := fn()
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		category := ClassifyError(err)
+		if category == CategoryUnknownOutcome && idempotent {
+			category = CategoryRetryable
+		}
+		if category != CategoryRetryable || attempt >= c.Retry.MaxAttempts {
+			return nil, lastErr
+		}
+		time.Sleep(c.Retry.delay(attempt))
 	}
+}
 
-	// 5. 极其重要：在 Header 中注入 API Key
-	req.Header.Add("X-MBX-APIKEY", c.APIKey)
+// decodeJSON 解析响应体，失败时把原文带进错误信息方便排查
+func decodeJSON(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("解析币安返回值失败: %v, 原始数据: %s", err, string(body))
+	}
+	return nil
+}
 
-	// 6. 发送请求
-	resp, err := c.HTTPClient.Do(req)
+// GetUSDTBalance 主动调用 REST API 查询合约账户的 USDT 余额
+func (c *APIClient) GetUSDTBalance() (*Balance, error) {
+	balances, err := c.GetAccountBalance()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// 7. 读取并返回结果
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// 遍历寻找 USDT 资产
+	for i := range balances {
+		if balances[i].Asset == "USDT" {
+			return &balances[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("未找到 USDT 资产信息")
+}
+
+// GetAccountBalance 获取合约账户全部资产余额 (安全测试鉴权的最佳接口)
+func (c *APIClient) GetAccountBalance() ([]Balance, error) {
+	// 币安 U本位合约查询余额接口为 /fapi/v2/balance
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v2/balance", url.Values{})
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API Error: Status %d, Response: %s", resp.StatusCode, string(bodyBytes))
+	var balances []Balance
+	if err := decodeJSON(body, &balances); err != nil {
+		return nil, err
 	}
+	return balances, nil
+}
 
-	// 这里为了直观，我们先直接返回一段格式化好的 JSON 字符串
-	var prettyJSON interface{}
-	json.Unmarshal(bodyBytes, &prettyJSON)
-	output, _ := json.MarshalIndent(prettyJSON, "", "  ")
+// GetAccountInfo 查询账户全量信息 (资产、保证金、持仓)
+func (c *APIClient) GetAccountInfo() (*AccountInfo, error) {
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v2/account", url.Values{})
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return string(output), nil
+	var info AccountInfo
+	if err := decodeJSON(body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
 }
 
 // OrderRequest 包含发单所需的所有核心参数
@@ -160,58 +220,152 @@ type OrderRequest struct {
 	NewClientOrderID string  // [极度重要] 客户端自定义的唯一订单ID，用于防重发和回溯
 }
 
-// PlaceOrder 发送限价单，并返回完整的币安响应数据和错误信息
-func (c *APIClient) PlaceOrder(symbol, side, orderType string, quantity, price float64) (map[string]interface{}, error) {
+// NewClientOrderID 生成带前缀的客户端订单 ID，满足币安 36 字符上限
+func NewClientOrderID(prefix string) string {
+	return fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano())
+}
+
+// params 将 OrderRequest 转换为币安下单参数
+func (r OrderRequest) params() url.Values {
 	params := url.Values{}
-	params.Add("symbol", symbol)
-	params.Add("side", side)
-	params.Add("type", orderType)
-	params.Add("quantity", fmt.Sprintf("%.3f", quantity)) // 注意币安的精度要求
-	params.Add("price", fmt.Sprintf("%.2f", price))
-	params.Add("timeInForce", "GTC")
-	params.Add("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-
-	queryString := params.Encode()
-	mac := hmac.New(sha256.New, []byte(c.APISecret))
-	mac.Write([]byte(queryString))
-	signature := hex.EncodeToString(mac.Sum(nil))
+	params.Add("symbol", r.Symbol)
+	params.Add("side", r.Side)
+	params.Add("type", r.Type)
+	params.Add("quantity", fmt.Sprintf("%.3f", r.Quantity)) // 注意币安的精度要求
+	if r.Price > 0 {
+		params.Add("price", fmt.Sprintf("%.2f", r.Price))
+	}
+	if r.TimeInForce != "" {
+		params.Add("timeInForce", r.TimeInForce)
+	}
+	if r.PositionSide != "" {
+		params.Add("positionSide", r.PositionSide)
+	}
+	params.Add("newClientOrderId", r.NewClientOrderID)
+	return params
+}
 
-	// ==========================================
-	// 🚨 核心修复：不走 Body，直接把所有参数暴力拼接在 URL 后面！
-	// ==========================================
-	reqURL := fmt.Sprintf("%s/fapi/v1/order?%s&signature=%s", c.BaseURL, queryString, signature)
+// PlaceOrder 发送订单，并返回完整的币安响应数据和错误信息
+// 确定未执行的错误 (限流、时间戳) 会自动重发；超时等结果未知的错误，先按 clientOrderId 查询订单，
+// 只有确认订单不存在时才重发，避免重复下单
+func (c *APIClient) PlaceOrder(req OrderRequest) (*OrderResponse, error) {
+	return c.placeOrder(req, func(params url.Values) ([]byte, error) {
+		// 🚨 不走 Body，直接把所有参数拼接在 URL 后面
+		return c.signedRequest(http.MethodPost, "/fapi/v1/order", params)
+	})
+}
+
+// normalize 补齐下单默认值：LIMIT / GTC / 自动生成 clientOrderId
+func (r *OrderRequest) normalize() {
+	if r.Type == "" {
+		r.Type = "LIMIT"
+	}
+	if r.Type == "LIMIT" && r.TimeInForce == "" {
+		r.TimeInForce = "GTC"
+	}
+	if r.NewClientOrderID == "" {
+		r.NewClientOrderID = NewClientOrderID("bot")
+	}
+}
+
+// placeOrder PlaceOrder 的重试与查单逻辑，send 负责发出一次下单 (REST 或 WebSocket API)
+func (c *APIClient) placeOrder(req OrderRequest, send func(params url.Values) ([]byte, error)) (*OrderResponse, error) {
+	req.normalize()
+	params := req.params()
+
+	for attempt := 1; ; attempt++ {
+		body, err := send(params)
+		if err == nil {
+			var order OrderResponse
+			if err := decodeJSON(body, &order); err != nil {
+				return nil, err
+			}
+			return &order, nil
+		}
+
+		switch ClassifyError(err) {
+		case CategoryRetryable:
+		case CategoryUnknownOutcome:
+			// 订单可能已落地：先查询，查到就直接返回，查不到才允许重发
+			order, qErr := c.QueryOrder(req.Symbol, req.NewClientOrderID)
+			if qErr == nil {
+				return order, nil
+			}
+			if !IsAPIErrorCode(qErr, CodeNoSuchOrder) {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+		if attempt >= c.Retry.MaxAttempts {
+			return nil, err
+		}
+		time.Sleep(c.Retry.delay(attempt))
+	}
+}
+
+// QueryOrder 按 clientOrderId 查询订单当前状态
+func (c *APIClient) QueryOrder(symbol, origClientOrderID string) (*OrderResponse, error) {
+	params := url.Values{}
+	params.Add("symbol", symbol)
+	params.Add("origClientOrderId", origClientOrderID)
 
-	// 注意这里：Body 直接传 nil
-	req, err := http.NewRequest(http.MethodPost, reqURL, nil)
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v1/order", params)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-MBX-APIKEY", c.APIKey)
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	// ==========================================
+	var order OrderResponse
+	if err := decodeJSON(body, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// GetOpenOrders 查询指定交易对当前全部挂单
+func (c *APIClient) GetOpenOrders(symbol string) ([]OrderResponse, error) {
+	params := url.Values{}
+	params.Add("symbol", symbol)
+
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v1/openOrders", params)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	var orders []OrderResponse
+	if err := decodeJSON(body, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
 
-	// 🚨 核心逻辑：无论成功失败，都尝试把返回的 JSON 解析成 map
-	var respData map[string]interface{}
-	if err := json.Unmarshal(body, &respData); err != nil {
-		return nil, fmt.Errorf("解析币安返回值失败: %s", string(body))
+// GetUserTrades 查询指定交易对自 startTime (毫秒) 起的成交记录，limit 最大 1000
+func (c *APIClient) GetUserTrades(symbol string, startTime int64, limit int) ([]UserTrade, error) {
+	params := url.Values{}
+	params.Add("symbol", symbol)
+	if startTime > 0 {
+		params.Add("startTime", strconv.FormatInt(startTime, 10))
+	}
+	if limit > 0 {
+		params.Add("limit", strconv.Itoa(limit))
 	}
 
-	// 如果 HTTP 状态码不是 200，说明发单被拒，把币安的原始报错提取出来抛给 main.go
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d - %s", resp.StatusCode, string(body))
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v1/userTrades", params)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 成功则返回订单信息 map
-	return respData, nil
+	var trades []UserTrade
+	if err := decodeJSON(body, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
 }
 
 // CancelOrderRequest 撤单请求参数
@@ -220,75 +374,79 @@ type CancelOrderRequest struct {
 	OrigClientOrderID string // [极其核心] 你发单时自定义的那个 ID
 }
 
-// CancelOrder 撤销指定的 U 本位合约订单
-func (c *APIClient) CancelOrder(req CancelOrderRequest) (string, error) {
-	endpoint := "/fapi/v1/order"
-
-	// 1. 构造请求参数
+// params 将 CancelOrderRequest 转换为币安撤单参数
+func (r CancelOrderRequest) params() url.Values {
 	params := url.Values{}
-	params.Add("symbol", req.Symbol)
+	params.Add("symbol", r.Symbol)
 	// 使用 origClientOrderId 来指定要撤销的订单
-	params.Add("origClientOrderId", req.OrigClientOrderID)
-
-	// 核心安全参数
-	params.Add("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	params.Add("recvWindow", "5000")
-
-	// 2. 签名与拼接
-	queryString := params.Encode()
-	signature := c.createSignature(queryString)
-	fullURL := fmt.Sprintf("%s%s?%s&signature=%s", c.BaseURL, endpoint, queryString, signature)
+	params.Add("origClientOrderId", r.OrigClientOrderID)
+	return params
+}
 
-	// 3. 极其重要：撤单必须是 HTTP DELETE 请求！
-	httpReq, err := http.NewRequest(http.MethodDelete, fullURL, nil)
+// CancelOrder 撤销指定的 U 本位合约订单
+func (c *APIClient) CancelOrder(req CancelOrderRequest) (*OrderResponse, error) {
+	// 撤单必须是 HTTP DELETE 请求；重复撤单会返回 -2011，所以只重试确定未执行的错误
+	body, err := c.withRetry(false, func() ([]byte, error) {
+		return c.signedRequest(http.MethodDelete, "/fapi/v1/order", req.params())
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	httpReq.Header.Add("X-MBX-APIKEY", c.APIKey)
-
-	// 4. 执行请求
-	resp, err := c.HTTPClient.Do(httpReq)
-	if err != nil {
-		return "", err
+	var order OrderResponse
+	if err := decodeJSON(body, &order); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &order, nil
+}
 
-	// 5. 解析结果
-	bodyBytes, err := io.ReadAll(resp.Body)
+// ModifyOrderRequest 改单请求参数，仅限价单可改，数量与价格都必须给出
+type ModifyOrderRequest struct {
+	Symbol            string
+	OrigClientOrderID string
+	Side              string  // 必须与原订单一致
+	Quantity          float64 // 修改后的总数量
+	Price             float64 // 修改后的价格
+}
+
+// params 将 ModifyOrderRequest 转换为币安改单参数
+func (r ModifyOrderRequest) params() url.Values {
+	params := url.Values{}
+	params.Add("symbol", r.Symbol)
+	params.Add("origClientOrderId", r.OrigClientOrderID)
+	params.Add("side", r.Side)
+	params.Add("quantity", fmt.Sprintf("%.3f", r.Quantity))
+	params.Add("price", fmt.Sprintf("%.2f", r.Price))
+	return params
+}
+
+// ModifyOrder 修改挂单的价格与数量 (PUT /fapi/v1/order)，订单保留原 orderId 与 clientOrderId
+func (c *APIClient) ModifyOrder(req ModifyOrderRequest) (*OrderResponse, error) {
+	// 改单与撤单一样不可盲目重发，只重试确定未执行的错误
+	body, err := c.withRetry(false, func() ([]byte, error) {
+		return c.signedRequest(http.MethodPut, "/fapi/v1/order", req.params())
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API Error: Status %d, Response: %s", resp.StatusCode, string(bodyBytes))
+	var order OrderResponse
+	if err := decodeJSON(body, &order); err != nil {
+		return nil, err
 	}
-
-	var prettyJSON interface{}
-	json.Unmarshal(bodyBytes, &prettyJSON)
-	output, _ := json.MarshalIndent(prettyJSON, "", "  ")
-
-	return string(output), nil
+	return &order, nil
 }
 
 // GetListenKey 向币安申请专属的私有 WebSocket 监听令牌
 func (c *APIClient) GetListenKey() (string, error) {
-	url := c.BaseURL + "/fapi/v1/listenKey"
-	req, err := http.NewRequest(http.MethodPost, url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	// 私有推送只需在 Header 验证 API Key
-	req.Header.Set("X-MBX-APIKEY", c.APIKey)
-
-	resp, err := c.HTTPClient.Do(req)
+	// 私有推送只需在 Header 验证 API Key；有效期内重复申请返回同一个 key，可安全重试
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.keyedRequest(http.MethodPost, "/fapi/v1/listenKey", nil)
+	})
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
 	var result map[string]interface{}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return "", err
@@ -300,75 +458,234 @@ func (c *APIClient) GetListenKey() (string, error) {
 	return "", fmt.Errorf("未找到 listenKey: %s", string(body))
 }
 
+// GetServerTime 查询交易所服务器时间 (毫秒)
+func (c *APIClient) GetServerTime() (int64, error) {
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.keyedRequest(http.MethodGet, "/fapi/v1/time", nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+	if err := decodeJSON(body, &result); err != nil {
+		return 0, err
+	}
+	return result.ServerTime, nil
+}
+
+// ClockOffset 估算交易所时钟相对本机的偏差 (正数表示交易所时钟更快)，以请求往返的中点作为本机时刻
+// 偏差接近 recvWindow (5000ms) 时签名请求会被 -1021 拒绝
+func (c *APIClient) ClockOffset() (time.Duration, error) {
+	start := time.Now()
+	serverTime, err := c.GetServerTime()
+	if err != nil {
+		return 0, err
+	}
+	mid := start.Add(time.Since(start) / 2)
+	return time.UnixMilli(serverTime).Sub(mid), nil
+}
+
 // GetPosition 主动调用 REST API 查询指定交易对的当前真实持仓
-func (c *APIClient) GetPosition(symbol string) (string, string, error) {
+// 无持仓记录时返回数量为 0 的 PositionRisk
+func (c *APIClient) GetPosition(symbol string) (*PositionRisk, error) {
 	params := url.Values{}
 	params.Add("symbol", symbol)
-	params.Add("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
-	params.Add("recvWindow", "5000")
-
-	queryString := params.Encode()
-	mac := hmac.New(sha256.New, []byte(c.APISecret))
-	mac.Write([]byte(queryString))
-	signature := hex.EncodeToString(mac.Sum(nil))
 
 	// 币安 U本位合约查询仓位风险接口为 /fapi/v2/positionRisk
-	reqURL := fmt.Sprintf("%s/fapi/v2/positionRisk?%s&signature=%s", c.BaseURL, queryString, signature)
-
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v2/positionRisk", params)
+	})
 	if err != nil {
-		return "0.0", "0.0", err
+		return nil, err
 	}
-	req.Header.Set("X-MBX-APIKEY", c.APIKey)
 
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return "0.0", "0.0", err
+	var positions []PositionRisk
+	if err := decodeJSON(body, &positions); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != http.StatusOK {
-		return "0.0", "0.0", fmt.Errorf("API 报错: %s", string(body))
+	if len(positions) > 0 {
+		return &positions[0], nil
 	}
+	return &PositionRisk{Symbol: symbol}, nil
+}
 
-	var positions []map[string]interface{}
-	if err := json.Unmarshal(body, &positions); err != nil {
-		return "0.0", "0.0", fmt.Errorf("JSON 解析失敗")
+// GetPositions 查询指定交易对的全部持仓腿
+// 单向持仓模式下只有一条 BOTH；双向持仓模式下分别返回 LONG 与 SHORT
+func (c *APIClient) GetPositions(symbol string) ([]PositionRisk, error) {
+	params := url.Values{}
+	if symbol != "" {
+		params.Add("symbol", symbol)
 	}
 
-	// 🌟 同時提取 positionAmt (持倉數量) 和 entryPrice (開倉均價)
-	if len(positions) > 0 {
-		amt, _ := positions[0]["positionAmt"].(string)
-		ep, _ := positions[0]["entryPrice"].(string)
-		return amt, ep, nil
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v2/positionRisk", params)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return "0.0", "0.0", nil
+	var positions []PositionRisk
+	if err := decodeJSON(body, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
 }
 
-// RenewListenKey 续期 ListenKey，必须每 30 分钟调用一次，否则连接在 60 分钟后失效
-func (c *APIClient) RenewListenKey(listenKey string) error {
-	reqURL := fmt.Sprintf("%s/fapi/v1/listenKey", c.BaseURL)
-	req, err := http.NewRequest(http.MethodPut, reqURL, nil)
+// GetPositionMode 查询当前持仓模式，true 表示双向持仓 (Hedge Mode)
+func (c *APIClient) GetPositionMode() (bool, error) {
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodGet, "/fapi/v1/positionSide/dual", url.Values{})
+	})
 	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		DualSidePosition bool `json:"dualSidePosition"`
+	}
+	if err := decodeJSON(body, &result); err != nil {
+		return false, err
+	}
+	return result.DualSidePosition, nil
+}
+
+// SetPositionMode 切换持仓模式；有持仓或挂单时币安会拒绝切换 (-4068 / -4067)
+// 目标模式与当前一致 (-4059) 视为成功
+func (c *APIClient) SetPositionMode(dualSide bool) error {
+	params := url.Values{}
+	params.Add("dualSidePosition", strconv.FormatBool(dualSide))
+
+	_, err := c.withRetry(false, func() ([]byte, error) {
+		return c.signedRequest(http.MethodPost, "/fapi/v1/positionSide/dual", params)
+	})
+	if err != nil && !IsAPIErrorCode(err, CodeNoNeedToChangePositionSide) {
 		return err
 	}
-	req.Header.Set("X-MBX-APIKEY", c.APIKey)
+	return nil
+}
 
-	q := req.URL.Query()
-	q.Add("listenKey", listenKey)
-	req.URL.RawQuery = q.Encode()
+// LeverageResponse 对应 /fapi/v1/leverage 的返回
+type LeverageResponse struct {
+	Symbol           string  `json:"symbol"`
+	Leverage         int     `json:"leverage"`
+	MaxNotionalValue float64 `json:"maxNotionalValue,string"` // 当前杠杆下允许的最大名义价值
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// ChangeLeverage 调整指定交易对的开仓杠杆 (1-125)
+func (c *APIClient) ChangeLeverage(symbol string, leverage int) (*LeverageResponse, error) {
+	params := url.Values{}
+	params.Add("symbol", symbol)
+	params.Add("leverage", strconv.Itoa(leverage))
+
+	// 设置杠杆本身幂等，结果未知时可直接重发
+	body, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodPost, "/fapi/v1/leverage", params)
+	})
 	if err != nil {
+		return nil, err
+	}
+
+	var result LeverageResponse
+	if err := decodeJSON(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// 保证金模式，对应 /fapi/v1/marginType 的 marginType 参数
+const (
+	MarginTypeIsolated = "ISOLATED"
+	MarginTypeCrossed  = "CROSSED"
+)
+
+// ChangeMarginType 切换逐仓 / 全仓；目标模式与当前一致 (-4046) 视为成功
+// 有持仓或挂单时币安会拒绝切换
+func (c *APIClient) ChangeMarginType(symbol, marginType string) error {
+	params := url.Values{}
+	params.Add("symbol", symbol)
+	params.Add("marginType", marginType)
+
+	_, err := c.withRetry(true, func() ([]byte, error) {
+		return c.signedRequest(http.MethodPost, "/fapi/v1/marginType", params)
+	})
+	if err != nil && !IsAPIErrorCode(err, CodeNoNeedToChangeMarginType) {
 		return err
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("RenewListenKey 失败 [%d]: %s", resp.StatusCode, string(body))
+// PositionMarginRequest 逐仓保证金调整参数
+type PositionMarginRequest struct {
+	Symbol       string
+	PositionSide string  // 双向持仓模式下必填：LONG 或 SHORT
+	Amount       float64 // 调整金额 (USDT)
+	Add          bool    // true 增加保证金，false 减少保证金
+}
+
+// PositionMarginResponse 对应 /fapi/v1/positionMargin 的返回
+type PositionMarginResponse struct {
+	Amount float64 `json:"amount"`
+	Code   int     `json:"code"`
+	Msg    string  `json:"msg"`
+	Type   int     `json:"type"` // 1 增加，2 减少
+}
+
+// ModifyPositionMargin 调整逐仓仓位的保证金
+func (c *APIClient) ModifyPositionMargin(req PositionMarginRequest) (*PositionMarginResponse, error) {
+	params := url.Values{}
+	params.Add("symbol", req.Symbol)
+	params.Add("amount", strconv.FormatFloat(req.Amount, 'f', -1, 64))
+	if req.Add {
+		params.Add("type", "1")
+	} else {
+		params.Add("type", "2")
+	}
+	if req.PositionSide != "" {
+		params.Add("positionSide", req.PositionSide)
+	}
+
+	// 非幂等：重复执行会重复划转保证金，只重试确定未执行的错误
+	body, err := c.withRetry(false, func() ([]byte, error) {
+		return c.signedRequest(http.MethodPost, "/fapi/v1/positionMargin", params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result PositionMarginResponse
+	if err := decodeJSON(body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RenewListenKey 续期 ListenKey，必须每 30 分钟调用一次，否则连接在 60 分钟后失效
+func (c *APIClient) RenewListenKey(listenKey string) error {
+	params := url.Values{}
+	params.Add("listenKey", listenKey)
+
+	_, err := c.withRetry(true, func() ([]byte, error) {
+		return c.keyedRequest(http.MethodPut, "/fapi/v1/listenKey", params)
+	})
+	if err != nil {
+		return fmt.Errorf("RenewListenKey 失败: %w", err)
+	}
+	return nil
+}
+
+// CloseListenKey 关闭 ListenKey，对应的私有流连接随之断开；网关退出时调用
+func (c *APIClient) CloseListenKey(listenKey string) error {
+	params := url.Values{}
+	params.Add("listenKey", listenKey)
+
+	_, err := c.withRetry(true, func() ([]byte, error) {
+		return c.keyedRequest(http.MethodDelete, "/fapi/v1/listenKey", params)
+	})
+	if err != nil {
+		return fmt.Errorf("CloseListenKey 失败: %w", err)
 	}
 	return nil
 }
@@ -2,6 +2,7 @@ package binance
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -139,8 +140,8 @@ func TestPlaceOrder_Success(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if result == "" {
-		t.Error("result should not be empty")
+	if result.OrderID != 12345 || result.ClientOrderID != "bot_test" || result.Status != "NEW" {
+		t.Errorf("unexpected order response: %+v", result)
 	}
 }
 
@@ -160,6 +161,60 @@ func TestPlaceOrder_InsufficientBalance(t *testing.T) {
 	}
 }
 
+// ---- CancelOrder ----
+
+func TestCancelOrder_ParsesResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("expected DELETE, got %s", r.Method)
+		}
+		w.Write([]byte(`{"orderId":99,"clientOrderId":"bot_x","status":"CANCELED","origQty":"0.010","executedQty":"0.004","price":"50000.00"}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	order, err := c.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: "bot_x"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Status != "CANCELED" || order.OrigQty != 0.01 || order.ExecutedQty != 0.004 || order.Price != 50000 {
+		t.Errorf("unexpected cancel response: %+v", order)
+	}
+}
+
+// ---- GetAccountBalance / GetUSDTBalance ----
+
+func TestGetUSDTBalance(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"asset":"BNB","balance":"1.5","availableBalance":"1.5","crossUnPnl":"0"},
+			{"asset":"USDT","balance":"1234.56","availableBalance":"1000.00","crossUnPnl":"-3.2"}
+		]`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	balances, err := c.GetAccountBalance()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(balances) != 2 {
+		t.Fatalf("expected 2 balances, got %d", len(balances))
+	}
+
+	usdt, err := c.GetUSDTBalance()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usdt.Balance != 1234.56 || usdt.AvailableBalance != 1000 || usdt.CrossUnPnl != -3.2 {
+		t.Errorf("unexpected USDT balance: %+v", usdt)
+	}
+}
+
 // ---- GetPosition ----
 
 func TestGetPosition_WithPosition(t *testing.T) {
@@ -174,15 +229,15 @@ func TestGetPosition_WithPosition(t *testing.T) {
 	c := NewAPIClient("key", "secret")
 	c.BaseURL = srv.URL
 
-	amt, ep, err := c.GetPosition("BTCUSDT")
+	pos, err := c.GetPosition("BTCUSDT")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if amt != "0.01" {
-		t.Errorf("expected 0.01, got %s", amt)
+	if pos.PositionAmt != 0.01 {
+		t.Errorf("expected 0.01, got %v", pos.PositionAmt)
 	}
-	if ep != "50000.0" {
-		t.Errorf("expected 50000.0, got %s", ep)
+	if pos.EntryPrice != 50000.0 {
+		t.Errorf("expected 50000.0, got %v", pos.EntryPrice)
 	}
 }
 
@@ -196,12 +251,12 @@ func TestGetPosition_Empty(t *testing.T) {
 	c := NewAPIClient("key", "secret")
 	c.BaseURL = srv.URL
 
-	amt, ep, err := c.GetPosition("BTCUSDT")
+	pos, err := c.GetPosition("BTCUSDT")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if amt != "0.0" || ep != "0.0" {
-		t.Errorf("expected 0.0/0.0 for empty position, got %s/%s", amt, ep)
+	if pos.PositionAmt != 0 || pos.EntryPrice != 0 {
+		t.Errorf("expected 0/0 for empty position, got %v/%v", pos.PositionAmt, pos.EntryPrice)
 	}
 }
 
@@ -219,3 +274,244 @@ func TestNewAPIClient(t *testing.T) {
 		t.Errorf("expected 5s timeout, got %v", c.HTTPClient.Timeout)
 	}
 }
+
+// ---- 双向持仓 ----
+
+func TestGetPositions_HedgeMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"symbol":"BTCUSDT","positionSide":"LONG","positionAmt":"0.02","entryPrice":"50000"},
+			{"symbol":"BTCUSDT","positionSide":"SHORT","positionAmt":"-0.01","entryPrice":"51000"}
+		]`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	positions, err := c.GetPositions("BTCUSDT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("expected 2 legs, got %d", len(positions))
+	}
+	if positions[0].PositionSide != "LONG" || positions[0].PositionAmt != 0.02 {
+		t.Errorf("unexpected LONG leg: %+v", positions[0])
+	}
+	if positions[1].PositionSide != "SHORT" || positions[1].PositionAmt != -0.01 || positions[1].EntryPrice != 51000 {
+		t.Errorf("unexpected SHORT leg: %+v", positions[1])
+	}
+}
+
+func TestGetPositionMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/positionSide/dual" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"dualSidePosition":true}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	dual, err := c.GetPositionMode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !dual {
+		t.Error("expected hedge mode")
+	}
+}
+
+func TestSetPositionMode_NoNeedToChangeIsSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if r.URL.Query().Get("dualSidePosition") != "true" {
+			t.Errorf("expected dualSidePosition=true in query")
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-4059,"msg":"No need to change position side."}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	if err := c.SetPositionMode(true); err != nil {
+		t.Errorf("-4059 should be treated as success, got %v", err)
+	}
+}
+
+// ---- 杠杆与保证金 ----
+
+func TestChangeLeverage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/fapi/v1/leverage" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.URL.Query().Get("leverage") != "20" {
+			t.Errorf("expected leverage=20 in query")
+		}
+		w.Write([]byte(`{"leverage":20,"maxNotionalValue":"1000000","symbol":"BTCUSDT"}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	resp, err := c.ChangeLeverage("BTCUSDT", 20)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Leverage != 20 || resp.MaxNotionalValue != 1000000 {
+		t.Errorf("unexpected leverage response: %+v", resp)
+	}
+}
+
+func TestChangeMarginType_NoNeedToChangeIsSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("marginType") != MarginTypeIsolated {
+			t.Errorf("expected marginType=ISOLATED in query")
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-4046,"msg":"No need to change margin type."}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	if err := c.ChangeMarginType("BTCUSDT", MarginTypeIsolated); err != nil {
+		t.Errorf("-4046 should be treated as success, got %v", err)
+	}
+}
+
+func TestModifyPositionMargin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("type") != "2" || q.Get("amount") != "12.5" || q.Get("positionSide") != "LONG" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"amount":12.5,"code":200,"msg":"Successfully modify position margin.","type":2}`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	resp, err := c.ModifyPositionMargin(PositionMarginRequest{Symbol: "BTCUSDT", PositionSide: "LONG", Amount: 12.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Amount != 12.5 || resp.Type != 2 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetOpenOrders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/openOrders" || r.URL.Query().Get("symbol") != "BTCUSDT" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`[{"orderId":1,"clientOrderId":"grid_1","symbol":"BTCUSDT","status":"PARTIALLY_FILLED",
+			"origQty":"0.02","executedQty":"0.01","price":"50000","updateTime":123}]`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	orders, err := c.GetOpenOrders("BTCUSDT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orders) != 1 || orders[0].ClientOrderID != "grid_1" || orders[0].ExecutedQty != 0.01 || orders[0].UpdateTime != 123 {
+		t.Errorf("unexpected open orders: %+v", orders)
+	}
+}
+
+func TestGetUserTrades(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/fapi/v1/userTrades" || q.Get("startTime") != "1000" || q.Get("limit") != "500" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`[{"id":9,"orderId":1,"symbol":"BTCUSDT","side":"BUY","positionSide":"BOTH","price":"50000",
+			"qty":"0.01","quoteQty":"500","realizedPnl":"0","commission":"0.2","commissionAsset":"USDT","maker":true,"time":1500}]`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	trades, err := c.GetUserTrades("BTCUSDT", 1000, 500)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trades) != 1 || trades[0].ID != 9 || trades[0].Qty != 0.01 || trades[0].Commission != 0.2 || !trades[0].Maker {
+		t.Errorf("unexpected trades: %+v", trades)
+	}
+}
+
+func TestOnRoundTrip_ObservesEachAttempt(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "42")
+		w.Header().Set("X-MBX-ORDER-COUNT-1M", "7")
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+	c.Retry = RetryPolicy{MaxAttempts: 2}
+	var trips []RoundTrip
+	c.OnRoundTrip = func(rt RoundTrip) { trips = append(trips, rt) }
+
+	if _, err := c.GetOpenOrders("BTCUSDT"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trips) != 2 {
+		t.Fatalf("expected one observation per attempt, got %d", len(trips))
+	}
+	if trips[0].Status != http.StatusServiceUnavailable || trips[0].Err == nil {
+		t.Errorf("first attempt should report the 503: %+v", trips[0])
+	}
+	if trips[0].UsedWeight1m != -1 || trips[0].OrderCount1m != -1 {
+		t.Errorf("missing usage headers should be reported as -1: %+v", trips[0])
+	}
+	if trips[1].Endpoint != "/fapi/v1/openOrders" || trips[1].Method != http.MethodGet || trips[1].Status != 200 ||
+		trips[1].Err != nil || trips[1].Duration <= 0 || trips[1].UsedWeight1m != 42 || trips[1].OrderCount1m != 7 {
+		t.Errorf("unexpected round trip: %+v", trips[1])
+	}
+}
+
+func TestClockOffset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/time" || r.URL.RawQuery != "" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprintf(w, `{"serverTime":%d}`, time.Now().Add(2*time.Second).UnixMilli())
+	}))
+	defer srv.Close()
+
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+
+	offset, err := c.ClockOffset()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if offset < 1900*time.Millisecond || offset > 2100*time.Millisecond {
+		t.Errorf("expected an offset of about 2s, got %v", offset)
+	}
+}
@@ -0,0 +1,120 @@
+package binance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorCategory 描述一次失败请求在重试语义上的归类
+type ErrorCategory int
+
+const (
+	// CategoryNonRetryable 交易所明确拒绝 (保证金不足、参数错误等)，重试也不会成功
+	CategoryNonRetryable ErrorCategory = iota
+	// CategoryRetryable 请求确定未被执行 (限流、时间戳过期、服务繁忙)，可安全重发
+	CategoryRetryable
+	// CategoryUnknownOutcome 请求可能已被撮合引擎接收 (超时、5xx)，重发前必须先查询状态
+	CategoryUnknownOutcome
+)
+
+// String 返回可直接写入 JSON 的分类名，供 Python 端判断
+func (c ErrorCategory) String() string {
+	switch c {
+	case CategoryRetryable:
+		return "retryable"
+	case CategoryUnknownOutcome:
+		return "unknown_outcome"
+	default:
+		return "non_retryable"
+	}
+}
+
+// 常用的币安错误码，完整列表见 https://developers.binance.com/docs/derivatives/usds-margined-futures/error-code
+const (
+	CodeUnknown          = -1000 // 未知错误，订单可能已生效
+	CodeDisconnected     = -1001 // 内部连接断开
+	CodeTooManyRequests  = -1003 // 请求权重超限
+	CodeUnexpectedResp   = -1006 // 撮合引擎返回异常，订单状态未知
+	CodeTimeout          = -1007 // 等待撮合引擎回报超时，订单状态未知
+	CodeServerBusy       = -1008 // 服务器繁忙
+	CodeTooManyOrders    = -1015 // 一分钟内下单数超限
+	CodeInvalidTimestamp = -1021 // 时间戳超出 recvWindow
+	CodeNoSuchOrder      = -2013 // 订单不存在
+	CodeMarginNotEnough  = -2019 // 保证金不足
+	CodeListenKeyInvalid = -1125 // listenKey 不存在或已过期
+
+	CodeNoNeedToChangeMarginType   = -4046 // 保证金模式无需变更
+	CodeNoNeedToChangePositionSide = -4059 // 持仓模式无需变更
+)
+
+// APIError 币安 REST 接口返回的结构化错误
+type APIError struct {
+	HTTPStatus int    // HTTP 状态码
+	Code       int    `json:"code"` // 币安业务错误码 (负数)，无法解析时为 0
+	Msg        string `json:"msg"`  // 币安原始错误信息
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("binance API error: HTTP %d, code %d, msg %s", e.HTTPStatus, e.Code, e.Msg)
+}
+
+// Category 根据错误码和 HTTP 状态对错误进行归类
+func (e *APIError) Category() ErrorCategory {
+	switch e.Code {
+	case CodeUnknown, CodeUnexpectedResp, CodeTimeout:
+		return CategoryUnknownOutcome
+	case CodeDisconnected, CodeTooManyRequests, CodeServerBusy, CodeInvalidTimestamp:
+		return CategoryRetryable
+	}
+
+	switch {
+	case e.HTTPStatus == http.StatusTooManyRequests:
+		return CategoryRetryable
+	case e.HTTPStatus >= 500:
+		// 5xx 时币安文档明确说明执行状态未知
+		return CategoryUnknownOutcome
+	}
+	return CategoryNonRetryable
+}
+
+// newAPIError 从非 2xx 响应中提取 code/msg；响应体不是标准格式时把原文放进 Msg
+func newAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{HTTPStatus: status}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Msg == "" {
+		apiErr.Msg = string(body)
+	}
+	return apiErr
+}
+
+// ClassifyError 对任意错误归类：APIError 按错误码判断，网络层错误视为结果未知
+func ClassifyError(err error) ErrorCategory {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Category()
+	}
+	// 请求可能已经发出但响应丢失，只能当作结果未知处理
+	return CategoryUnknownOutcome
+}
+
+// IsAPIErrorCode 判断 err 是否为指定币安错误码
+func IsAPIErrorCode(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// RetryPolicy 控制 REST 请求的自动重试
+type RetryPolicy struct {
+	MaxAttempts int           // 包含首次请求在内的最大尝试次数，<=1 表示不重试
+	Backoff     time.Duration // 首次重试前的等待时间，之后每次翻倍
+}
+
+// DefaultRetryPolicy 交易请求的默认重试策略：最多 3 次，200ms 起步指数退避
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: 200 * time.Millisecond}
+
+// delay 返回第 attempt 次重试 (从 1 开始) 前的等待时间
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.Backoff << (attempt - 1)
+}
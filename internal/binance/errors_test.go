@@ -0,0 +1,161 @@
+package binance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ---- APIError 分类 ----
+
+func TestAPIErrorCategory(t *testing.T) {
+	cases := []struct {
+		status int
+		code   int
+		want   ErrorCategory
+	}{
+		{http.StatusBadRequest, CodeMarginNotEnough, CategoryNonRetryable},
+		{http.StatusBadRequest, CodeInvalidTimestamp, CategoryRetryable},
+		{http.StatusTooManyRequests, CodeTooManyRequests, CategoryRetryable},
+		{http.StatusRequestTimeout, CodeTimeout, CategoryUnknownOutcome},
+		{http.StatusServiceUnavailable, 0, CategoryUnknownOutcome},
+		{http.StatusTeapot, 0, CategoryNonRetryable},
+	}
+	for _, tc := range cases {
+		e := &APIError{HTTPStatus: tc.status, Code: tc.code}
+		if got := e.Category(); got != tc.want {
+			t.Errorf("status=%d code=%d: expected %v, got %v", tc.status, tc.code, tc.want, got)
+		}
+	}
+}
+
+func TestNewAPIError_ParsesBody(t *testing.T) {
+	e := newAPIError(http.StatusBadRequest, []byte(`{"code":-2019,"msg":"Margin is insufficient."}`))
+	if e.Code != CodeMarginNotEnough || e.Msg != "Margin is insufficient." {
+		t.Errorf("unexpected parse result: %+v", e)
+	}
+
+	// 非 JSON 响应体保留原文
+	e = newAPIError(http.StatusBadGateway, []byte("<html>bad gateway</html>"))
+	if e.Code != 0 || e.Msg != "<html>bad gateway</html>" {
+		t.Errorf("unexpected parse result: %+v", e)
+	}
+}
+
+func TestClassifyError_NetworkErrorIsUnknown(t *testing.T) {
+	if got := ClassifyError(errors.New("connection reset")); got != CategoryUnknownOutcome {
+		t.Errorf("expected unknown_outcome, got %v", got)
+	}
+}
+
+// ---- 重试策略 ----
+
+func newRetryTestClient(url string) *APIClient {
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = url
+	c.Retry = RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	return c
+}
+
+func TestPlaceOrder_RetriesOnTimestampError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1021,"msg":"Timestamp for this request is outside of the recvWindow."}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"orderId": 1, "status": "NEW"})
+	}))
+	defer srv.Close()
+
+	c := newRetryTestClient(srv.URL)
+	if _, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.01, Price: 50000}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestPlaceOrder_NoRetryOnRejection(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-2019,"msg":"Margin is insufficient."}`))
+	}))
+	defer srv.Close()
+
+	c := newRetryTestClient(srv.URL)
+	_, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 999, Price: 50000})
+	if !IsAPIErrorCode(err, CodeMarginNotEnough) {
+		t.Fatalf("expected -2019 APIError, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("rejected order must not be retried, got %d calls", calls)
+	}
+}
+
+func TestPlaceOrder_UnknownOutcomeQueriesBeforeRetry(t *testing.T) {
+	var posts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			atomic.AddInt32(&posts, 1)
+			w.WriteHeader(http.StatusRequestTimeout)
+			w.Write([]byte(`{"code":-1007,"msg":"Timeout waiting for response from backend server."}`))
+		case http.MethodGet:
+			// 订单其实已经落地
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"orderId":       7,
+				"clientOrderId": r.URL.Query().Get("origClientOrderId"),
+				"status":        "NEW",
+			})
+		}
+	}))
+	defer srv.Close()
+
+	c := newRetryTestClient(srv.URL)
+	result, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.01, Price: 50000, NewClientOrderID: "bot_timeout"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ClientOrderID != "bot_timeout" {
+		t.Errorf("expected order found by clientOrderId, got %v", result)
+	}
+	if posts != 1 {
+		t.Errorf("order already exists, must not resubmit; got %d POSTs", posts)
+	}
+}
+
+func TestPlaceOrder_UnknownOutcomeResubmitsWhenOrderMissing(t *testing.T) {
+	var posts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if atomic.AddInt32(&posts, 1) == 1 {
+				w.WriteHeader(http.StatusRequestTimeout)
+				w.Write([]byte(`{"code":-1007,"msg":"Timeout waiting for response from backend server."}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"orderId": 8, "status": "NEW"})
+		case http.MethodGet:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-2013,"msg":"Order does not exist."}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := newRetryTestClient(srv.URL)
+	if _, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.01, Price: 50000}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if posts != 2 {
+		t.Errorf("expected resubmit after -2013, got %d POSTs", posts)
+	}
+}
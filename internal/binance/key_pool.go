@@ -0,0 +1,272 @@
+package binance
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/logging"
+)
+
+const (
+	// DefaultOrderLimit1m U 本位合约每个账户一分钟内的下单数上限
+	DefaultOrderLimit1m = 1200
+	// maxTrackedOwners 记住下单 Key 的 clientOrderId 数量上限，超出后淘汰最早的记录；
+	// 终态订单经 Forget 移除，上限只在挂单异常多时起作用
+	maxTrackedOwners = 4096
+)
+
+var keyPoolLog = logging.For("binance.key_pool")
+
+// ErrOrderBudgetExhausted 账户本分钟的下单额度已用完，请求未发出 (HTTP 429 归类为可重试)
+var ErrOrderBudgetExhausted = &APIError{
+	HTTPStatus: http.StatusTooManyRequests,
+	Code:       CodeTooManyOrders,
+	Msg:        "account has exhausted its 1m order budget",
+}
+
+// ErrUnknownOrderKey 多 Key 池中的 clientOrderId 既不是经由本池下单、也未经 Adopt 登记，
+// 无法确定应由哪个 Key 发出撤单 / 改单 / 查单；请求未发出
+var ErrUnknownOrderKey = &APIError{
+	HTTPStatus: http.StatusBadRequest,
+	Msg:        "client order id is not tracked by any key in the pool",
+}
+
+// KeyPool 在多个 API Key 之间分配下单流量。币安的下单数按账户计算，池内所有 Key 共享同一份一分钟下单额度，
+// 多个 Key 只用于分散 IP / 连接上的负载：新订单发往本分钟发出最少的 Key，撤单 / 改单 / 查单发往下这笔单的 Key。
+// 池内所有 Key 必须属于同一账户。实现 OrderClient
+type KeyPool struct {
+	mu     sync.Mutex
+	keys   []*poolKey
+	owners map[string]*poolKey // clientOrderId → 下单 Key
+	order  []string            // owners 的插入顺序，用于淘汰
+	limit  int                 // 账户一分钟下单额度
+	used   int                 // 账户本分钟已用下单数：本地计数与响应头 X-MBX-ORDER-COUNT-1M 取较大值
+	minute int64               // used 与各 Key sent 所属的分钟 (Unix 分钟数)，跨分钟时清零
+	now    func() time.Time
+}
+
+type poolKey struct {
+	name   string
+	client OrderClient
+	sent   int // 本分钟经该 Key 发出的下单 / 改单数，用于分散负载
+}
+
+// PoolStats 账户本分钟的下单额度使用情况与各下单 Key 分担的请求数
+type PoolStats struct {
+	OrderLimit1m int        `json:"order_limit_1m"`
+	OrderCount1m int        `json:"order_count_1m"`
+	Keys         []KeyStats `json:"keys"`
+}
+
+// KeyStats 单个下单 Key 本分钟发出的下单 / 改单数
+type KeyStats struct {
+	Name   string `json:"name"`
+	Sent1m int    `json:"sent_1m"`
+}
+
+// NewKeyPool 创建空的 Key 池，limit 为账户一分钟下单额度 (<=0 时为 DefaultOrderLimit1m)，通过 AddKey 加入下单 Key
+func NewKeyPool(limit int) *KeyPool {
+	if limit <= 0 {
+		limit = DefaultOrderLimit1m
+	}
+	return &KeyPool{
+		owners: make(map[string]*poolKey),
+		limit:  limit,
+		now:    time.Now,
+	}
+}
+
+// AddKey 加入一个下单 Key；client 可以是 APIClient 或 WSAPIClient
+func (p *KeyPool) AddKey(name string, client OrderClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, &poolKey{name: name, client: client})
+}
+
+// Len 返回下单 Key 数量
+func (p *KeyPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+// ObserveRoundTrip 用交易所返回的下单计数 (账户级) 校准账户已用额度，
+// 挂在各下单 Key 的 APIClient / WSAPIClient 的 OnRoundTrip 上
+func (p *KeyPool) ObserveRoundTrip(rt RoundTrip) {
+	if rt.OrderCount1m < 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roll()
+	if rt.OrderCount1m > p.used {
+		p.used = rt.OrderCount1m
+	}
+}
+
+// Stats 返回账户本分钟的下单额度使用情况与各下单 Key 分担的请求数
+func (p *KeyPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roll()
+	stats := PoolStats{OrderLimit1m: p.limit, OrderCount1m: p.used, Keys: make([]KeyStats, 0, len(p.keys))}
+	for _, k := range p.keys {
+		stats.Keys = append(stats.Keys, KeyStats{Name: k.name, Sent1m: k.sent})
+	}
+	return stats
+}
+
+// roll 跨分钟后清零已用额度与各 Key 的发出数 (币安的下单计数按自然分钟重置)；调用方持有 mu
+func (p *KeyPool) roll() {
+	minute := p.now().Unix() / 60
+	if p.minute == minute {
+		return
+	}
+	p.minute, p.used = minute, 0
+	for _, k := range p.keys {
+		k.sent = 0
+	}
+}
+
+// reserve 预占一次账户下单额度并计入 k 的发出数；额度用完时返回 false
+func (p *KeyPool) reserve(k *poolKey) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roll()
+	if p.used >= p.limit {
+		return false
+	}
+	p.used++
+	k.sent++
+	return true
+}
+
+// acquire 预占一次账户下单额度并选出本分钟发出最少的 Key；额度用完或池为空时返回 nil
+func (p *KeyPool) acquire() *poolKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roll()
+	if p.used >= p.limit || len(p.keys) == 0 {
+		return nil
+	}
+	best := p.keys[0]
+	for _, k := range p.keys[1:] {
+		if k.sent < best.sent {
+			best = k
+		}
+	}
+	p.used++
+	best.sent++
+	return best
+}
+
+// remember 记录 clientOrderId 由哪个 Key 下单
+func (p *KeyPool) remember(clientOrderID string, k *poolKey) {
+	if clientOrderID == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.track(clientOrderID, k)
+}
+
+// track 记录 clientOrderId 的下单 Key，超出 maxTrackedOwners 时淘汰最早的记录；调用方持有 mu
+func (p *KeyPool) track(clientOrderID string, k *poolKey) {
+	if _, ok := p.owners[clientOrderID]; !ok {
+		p.order = append(p.order, clientOrderID)
+		if len(p.order) > maxTrackedOwners {
+			delete(p.owners, p.order[0])
+			p.order = p.order[1:]
+		}
+	}
+	p.owners[clientOrderID] = k
+}
+
+// Adopt 登记一笔不是经由本池下的单 (重启前的挂单、私有流或对账发现的订单)，之后的撤单 / 改单 / 查单
+// 发往第一个 Key；池内 Key 属于同一账户，任一 Key 都能操作该账户的订单。已登记的订单不受影响
+func (p *KeyPool) Adopt(clientOrderID string) {
+	if clientOrderID == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.owners[clientOrderID]; ok || len(p.keys) == 0 {
+		return
+	}
+	p.track(clientOrderID, p.keys[0])
+}
+
+// Forget 订单进入终态后移除 clientOrderId 的记录
+func (p *KeyPool) Forget(clientOrderID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.owners[clientOrderID]; !ok {
+		return
+	}
+	delete(p.owners, clientOrderID)
+	if i := slices.Index(p.order, clientOrderID); i >= 0 {
+		p.order = slices.Delete(p.order, i, i+1)
+	}
+}
+
+// owner 返回下这笔单的 Key；未记录的订单在只有一个 Key 时发往该 Key，多个 Key 时返回 ErrUnknownOrderKey，
+// 避免把请求发往可能无权操作该订单的 Key
+func (p *KeyPool) owner(clientOrderID string) (*poolKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.owners[clientOrderID]; ok {
+		return k, nil
+	}
+	if len(p.keys) == 1 {
+		return p.keys[0], nil
+	}
+	keyPoolLog.Warn("订单不属于 Key 池中任何已记录的 Key，拒绝发送", "client_order_id", clientOrderID)
+	return nil, ErrUnknownOrderKey
+}
+
+// PlaceOrder 从本分钟发出最少的 Key 下单；未填 clientOrderId 时在此生成，以便后续撤单 / 改单路由回同一个 Key
+func (p *KeyPool) PlaceOrder(req OrderRequest) (*OrderResponse, error) {
+	k := p.acquire()
+	if k == nil {
+		keyPoolLog.Warn("账户本分钟下单额度已用完", "symbol", req.Symbol, "client_order_id", req.NewClientOrderID)
+		return nil, ErrOrderBudgetExhausted
+	}
+	if req.NewClientOrderID == "" {
+		req.NewClientOrderID = NewClientOrderID("bot")
+	}
+	p.remember(req.NewClientOrderID, k)
+	return k.client.PlaceOrder(req)
+}
+
+// CancelOrder 撤单不计入下单额度，发往下单的 Key
+func (p *KeyPool) CancelOrder(req CancelOrderRequest) (*OrderResponse, error) {
+	k, err := p.owner(req.OrigClientOrderID)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.CancelOrder(req)
+}
+
+// ModifyOrder 改单计入账户下单额度，额度用完时与下单一样本地拒绝；必须由下单的 Key 发出
+func (p *KeyPool) ModifyOrder(req ModifyOrderRequest) (*OrderResponse, error) {
+	k, err := p.owner(req.OrigClientOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.reserve(k) {
+		keyPoolLog.Warn("账户本分钟下单额度已用完，拒绝改单", "symbol", req.Symbol, "client_order_id", req.OrigClientOrderID)
+		return nil, ErrOrderBudgetExhausted
+	}
+	return k.client.ModifyOrder(req)
+}
+
+// QueryOrder 查单发往下单的 Key
+func (p *KeyPool) QueryOrder(symbol, origClientOrderID string) (*OrderResponse, error) {
+	k, err := p.owner(origClientOrderID)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.QueryOrder(symbol, origClientOrderID)
+}
@@ -0,0 +1,138 @@
+package binance
+
+import (
+	"testing"
+	"time"
+)
+
+// countingOrderClient 记录每种请求的次数，下单直接返回成功
+type countingOrderClient struct {
+	places, cancels, modifies, queries int
+}
+
+func (c *countingOrderClient) PlaceOrder(req OrderRequest) (*OrderResponse, error) {
+	c.places++
+	return &OrderResponse{ClientOrderID: req.NewClientOrderID, Status: "NEW"}, nil
+}
+
+func (c *countingOrderClient) CancelOrder(req CancelOrderRequest) (*OrderResponse, error) {
+	c.cancels++
+	return &OrderResponse{ClientOrderID: req.OrigClientOrderID, Status: "CANCELED"}, nil
+}
+
+func (c *countingOrderClient) ModifyOrder(req ModifyOrderRequest) (*OrderResponse, error) {
+	c.modifies++
+	return &OrderResponse{ClientOrderID: req.OrigClientOrderID}, nil
+}
+
+func (c *countingOrderClient) QueryOrder(symbol, origClientOrderID string) (*OrderResponse, error) {
+	c.queries++
+	return &OrderResponse{ClientOrderID: origClientOrderID}, nil
+}
+
+func TestKeyPool_SharesAccountBudget(t *testing.T) {
+	a, b := &countingOrderClient{}, &countingOrderClient{}
+	p := NewKeyPool(10)
+	now := time.Unix(1_700_000_000, 0)
+	p.now = func() time.Time { return now }
+	p.AddKey("a", a)
+	p.AddKey("b", b)
+
+	// 交易所回报账户本分钟已下 6 单：两个 Key 共用剩下的 4 单额度，交替分担
+	p.ObserveRoundTrip(RoundTrip{OrderCount1m: 6, UsedWeight1m: -1})
+	var last *OrderResponse
+	for i := 0; i < 4; i++ {
+		order, err := p.PlaceOrder(OrderRequest{Symbol: "BTCUSDT"})
+		if err != nil {
+			t.Fatalf("place %d: %v", i, err)
+		}
+		last = order
+	}
+	if a.places != 2 || b.places != 2 {
+		t.Errorf("expected a=2 b=2, got a=%d b=%d", a.places, b.places)
+	}
+
+	// 额度按账户计算，不随 Key 数量翻倍：用完后下单与改单都本地拒绝，不再发往交易所
+	if _, err := p.PlaceOrder(OrderRequest{Symbol: "BTCUSDT"}); err != ErrOrderBudgetExhausted || ClassifyError(err) != CategoryRetryable {
+		t.Fatalf("expected retryable ErrOrderBudgetExhausted, got %v", err)
+	}
+	if _, err := p.ModifyOrder(ModifyOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: last.ClientOrderID}); err != ErrOrderBudgetExhausted {
+		t.Fatalf("modify should also be rejected when the budget is exhausted, got %v", err)
+	}
+	if a.places+b.places != 4 || a.modifies+b.modifies != 0 {
+		t.Errorf("exhausted pool must not send requests: places=%d modifies=%d", a.places+b.places, a.modifies+b.modifies)
+	}
+
+	// 跨入下一分钟后额度重置
+	now = now.Add(time.Minute)
+	if _, err := p.ModifyOrder(ModifyOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: last.ClientOrderID}); err != nil || b.modifies != 1 {
+		t.Errorf("budget should reset in the next minute: %v", err)
+	}
+	st := p.Stats()
+	if st.OrderLimit1m != 10 || st.OrderCount1m != 1 || len(st.Keys) != 2 || st.Keys[0].Sent1m != 0 || st.Keys[1].Sent1m != 1 {
+		t.Errorf("unexpected stats after reset: %+v", st)
+	}
+}
+
+func TestKeyPool_RoutesFollowUpsToPlacingKey(t *testing.T) {
+	a, b := &countingOrderClient{}, &countingOrderClient{}
+	p := NewKeyPool(0)
+	p.AddKey("a", a)
+	p.AddKey("b", b)
+	p.PlaceOrder(OrderRequest{Symbol: "BTCUSDT"})
+
+	order, err := p.PlaceOrder(OrderRequest{Symbol: "BTCUSDT"})
+	if err != nil || order.ClientOrderID == "" || b.places != 1 {
+		t.Fatalf("order should go to b with a generated clientOrderId: %+v %v", order, err)
+	}
+	p.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: order.ClientOrderID})
+	p.QueryOrder("BTCUSDT", order.ClientOrderID)
+	if b.cancels != 1 || b.queries != 1 || a.cancels+a.queries != 0 {
+		t.Errorf("cancel / query must use the placing key: a=%+v b=%+v", a, b)
+	}
+
+	// 不是经由 Key 池下、也未登记的单不猜测 Key，直接拒绝且不发往交易所
+	if _, err := p.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: "unknown"}); err != ErrUnknownOrderKey ||
+		ClassifyError(err) != CategoryNonRetryable {
+		t.Errorf("unknown orders should be rejected as non-retryable, got %v", err)
+	}
+	if a.cancels != 0 || b.cancels != 1 {
+		t.Errorf("unknown orders must not reach any key: a=%+v b=%+v", a, b)
+	}
+}
+
+func TestKeyPool_AdoptAndForget(t *testing.T) {
+	a, b := &countingOrderClient{}, &countingOrderClient{}
+	p := NewKeyPool(0)
+	p.AddKey("a", a)
+	p.AddKey("b", b)
+	first, _ := p.PlaceOrder(OrderRequest{Symbol: "BTCUSDT"})
+	p.Forget(first.ClientOrderID)
+	order, _ := p.PlaceOrder(OrderRequest{Symbol: "BTCUSDT"})
+
+	// 私有流 / 对账发现的挂单 (如重启前下的单) 登记后发往第一个 Key；已记录下单 Key 的订单不被覆盖
+	p.Adopt("restored")
+	p.Adopt(order.ClientOrderID)
+	p.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: "restored"})
+	p.QueryOrder("BTCUSDT", order.ClientOrderID)
+	if a.cancels != 1 || b.queries != 1 || a.queries != 0 {
+		t.Errorf("adopted orders go to the first key, placed orders keep their key: a=%+v b=%+v", a, b)
+	}
+
+	// 终态后移除记录
+	p.Forget(order.ClientOrderID)
+	if _, err := p.QueryOrder("BTCUSDT", order.ClientOrderID); err != ErrUnknownOrderKey {
+		t.Errorf("forgotten orders should no longer be routed, got %v", err)
+	}
+	if len(p.owners) != 1 || len(p.order) != 1 {
+		t.Errorf("forget should drop the tracking entry: owners=%d order=%d", len(p.owners), len(p.order))
+	}
+
+	// 只有一个 Key 时不存在歧义，未记录的订单直接发往该 Key
+	single := &countingOrderClient{}
+	one := NewKeyPool(0)
+	one.AddKey("only", single)
+	if _, err := one.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: "unknown"}); err != nil || single.cancels != 1 {
+		t.Errorf("single-key pool should route unknown orders to its key: %v", err)
+	}
+}
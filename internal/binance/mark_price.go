@@ -0,0 +1,82 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"BinanceAutoBot2/internal/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+var markPriceLog = logging.For("binance.mark_price")
+
+// StartMarkPriceStream 订阅标记价格推送 (如 wss://fstream.binance.com/ws/btcusdt@markPrice@1s)，
+// 断线后按指数退避自动重连，直到 ctx 被取消；onConnect 在每次 (重) 连接成功后回调，可为 nil
+func StartMarkPriceStream(ctx context.Context, wsURL string, onMark func(MarkPriceEvent), onConnect func()) {
+	const initialBackoff = 2 * time.Second
+	const maxBackoff = 60 * time.Second
+	backoff := initialBackoff
+
+	for {
+		connected := false
+		err := readMarkPrice(ctx, wsURL, onMark, func() {
+			connected = true
+			if onConnect != nil {
+				onConnect()
+			}
+		})
+		if connected {
+			// 本次成功连接过，断线后从初始间隔重新退避，避免长时间运行后每次重连都等最大间隔
+			backoff = initialBackoff
+		}
+		if err != nil {
+			markPriceLog.Warn("连接断开，稍后重连", logging.Err(err), "backoff", backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+func readMarkPrice(ctx context.Context, wsURL string, onMark func(MarkPriceEvent), onConnect func()) error {
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	onConnect()
+
+	// 监听 ctx 取消，主动关闭连接让 ReadMessage 返回
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		var event MarkPriceEvent
+		if err := json.Unmarshal(message, &event); err != nil {
+			markPriceLog.Error("标记价格解析失败", logging.Err(err), "raw", string(message))
+			continue
+		}
+		onMark(event)
+	}
+}
@@ -0,0 +1,132 @@
+package binance
+
+// 币安 REST 接口的数值字段全部以字符串返回，这里统一用 `,string` 直接解析为 float64，
+// 调用方无需再做类型断言或 strconv 转换
+
+// OrderResponse 对应 /fapi/v1/order 下单、查单、撤单的返回结构
+type OrderResponse struct {
+	OrderID       int64   `json:"orderId"`
+	ClientOrderID string  `json:"clientOrderId"`
+	Symbol        string  `json:"symbol"`
+	Side          string  `json:"side"`
+	PositionSide  string  `json:"positionSide"`
+	Type          string  `json:"type"`
+	TimeInForce   string  `json:"timeInForce"`
+	Status        string  `json:"status"` // NEW / PARTIALLY_FILLED / FILLED / CANCELED / EXPIRED
+	Price         float64 `json:"price,string"`
+	AvgPrice      float64 `json:"avgPrice,string"`
+	OrigQty       float64 `json:"origQty,string"`
+	ExecutedQty   float64 `json:"executedQty,string"`
+	CumQuote      float64 `json:"cumQuote,string"` // 累计成交金额
+	ReduceOnly    bool    `json:"reduceOnly"`
+	UpdateTime    int64   `json:"updateTime"`
+}
+
+// UserTrade 对应 /fapi/v1/userTrades 返回数组中的单笔成交
+// 注意：成交记录只带 orderId，不带 clientOrderId
+type UserTrade struct {
+	ID              int64   `json:"id"`
+	OrderID         int64   `json:"orderId"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	PositionSide    string  `json:"positionSide"`
+	Price           float64 `json:"price,string"`
+	Qty             float64 `json:"qty,string"`
+	QuoteQty        float64 `json:"quoteQty,string"`
+	RealizedPnl     float64 `json:"realizedPnl,string"`
+	Commission      float64 `json:"commission,string"`
+	CommissionAsset string  `json:"commissionAsset"`
+	Buyer           bool    `json:"buyer"`
+	Maker           bool    `json:"maker"`
+	Time            int64   `json:"time"`
+}
+
+// Balance 对应 /fapi/v2/balance 返回数组中的单个资产
+type Balance struct {
+	AccountAlias       string  `json:"accountAlias"`
+	Asset              string  `json:"asset"`
+	Balance            float64 `json:"balance,string"` // 钱包余额
+	CrossWalletBalance float64 `json:"crossWalletBalance,string"`
+	CrossUnPnl         float64 `json:"crossUnPnl,string"` // 全仓持仓未实现盈亏
+	AvailableBalance   float64 `json:"availableBalance,string"`
+	MaxWithdrawAmount  float64 `json:"maxWithdrawAmount,string"`
+	MarginAvailable    bool    `json:"marginAvailable"`
+	UpdateTime         int64   `json:"updateTime"`
+}
+
+// PositionRisk 对应 /fapi/v2/positionRisk 返回数组中的单个持仓
+type PositionRisk struct {
+	Symbol           string  `json:"symbol"`
+	PositionSide     string  `json:"positionSide"` // 单向持仓为 BOTH，双向持仓为 LONG / SHORT
+	PositionAmt      float64 `json:"positionAmt,string"`
+	EntryPrice       float64 `json:"entryPrice,string"`
+	MarkPrice        float64 `json:"markPrice,string"`
+	UnRealizedProfit float64 `json:"unRealizedProfit,string"`
+	LiquidationPrice float64 `json:"liquidationPrice,string"`
+	Leverage         int     `json:"leverage,string"`
+	MaxNotionalValue float64 `json:"maxNotionalValue,string"`
+	MarginType       string  `json:"marginType"` // cross / isolated
+	IsolatedMargin   float64 `json:"isolatedMargin,string"`
+	IsAutoAddMargin  bool    `json:"isAutoAddMargin,string"`
+	Notional         float64 `json:"notional,string"`
+	IsolatedWallet   float64 `json:"isolatedWallet,string"`
+	UpdateTime       int64   `json:"updateTime"`
+}
+
+// AccountAsset 对应 /fapi/v2/account 中 assets 数组的单个资产
+type AccountAsset struct {
+	Asset                  string  `json:"asset"`
+	WalletBalance          float64 `json:"walletBalance,string"`
+	UnrealizedProfit       float64 `json:"unrealizedProfit,string"`
+	MarginBalance          float64 `json:"marginBalance,string"`
+	MaintMargin            float64 `json:"maintMargin,string"`
+	InitialMargin          float64 `json:"initialMargin,string"`
+	PositionInitialMargin  float64 `json:"positionInitialMargin,string"`
+	OpenOrderInitialMargin float64 `json:"openOrderInitialMargin,string"`
+	CrossWalletBalance     float64 `json:"crossWalletBalance,string"`
+	CrossUnPnl             float64 `json:"crossUnPnl,string"`
+	AvailableBalance       float64 `json:"availableBalance,string"`
+	MaxWithdrawAmount      float64 `json:"maxWithdrawAmount,string"`
+	MarginAvailable        bool    `json:"marginAvailable"`
+	UpdateTime             int64   `json:"updateTime"`
+}
+
+// AccountPosition 对应 /fapi/v2/account 中 positions 数组的单个持仓
+type AccountPosition struct {
+	Symbol                 string  `json:"symbol"`
+	PositionSide           string  `json:"positionSide"`
+	PositionAmt            float64 `json:"positionAmt,string"`
+	EntryPrice             float64 `json:"entryPrice,string"`
+	UnrealizedProfit       float64 `json:"unrealizedProfit,string"`
+	InitialMargin          float64 `json:"initialMargin,string"`
+	MaintMargin            float64 `json:"maintMargin,string"`
+	PositionInitialMargin  float64 `json:"positionInitialMargin,string"`
+	OpenOrderInitialMargin float64 `json:"openOrderInitialMargin,string"`
+	Leverage               int     `json:"leverage,string"`
+	Isolated               bool    `json:"isolated"`
+	IsolatedWallet         float64 `json:"isolatedWallet,string"`
+	MaxNotional            float64 `json:"maxNotional,string"`
+	UpdateTime             int64   `json:"updateTime"`
+}
+
+// AccountInfo 对应 /fapi/v2/account 返回的账户全量信息
+type AccountInfo struct {
+	FeeTier                     int               `json:"feeTier"`
+	CanTrade                    bool              `json:"canTrade"`
+	CanDeposit                  bool              `json:"canDeposit"`
+	CanWithdraw                 bool              `json:"canWithdraw"`
+	TotalInitialMargin          float64           `json:"totalInitialMargin,string"`
+	TotalMaintMargin            float64           `json:"totalMaintMargin,string"`
+	TotalWalletBalance          float64           `json:"totalWalletBalance,string"`
+	TotalUnrealizedProfit       float64           `json:"totalUnrealizedProfit,string"`
+	TotalMarginBalance          float64           `json:"totalMarginBalance,string"`
+	TotalPositionInitialMargin  float64           `json:"totalPositionInitialMargin,string"`
+	TotalOpenOrderInitialMargin float64           `json:"totalOpenOrderInitialMargin,string"`
+	TotalCrossWalletBalance     float64           `json:"totalCrossWalletBalance,string"`
+	TotalCrossUnPnl             float64           `json:"totalCrossUnPnl,string"`
+	AvailableBalance            float64           `json:"availableBalance,string"`
+	MaxWithdrawAmount           float64           `json:"maxWithdrawAmount,string"`
+	UpdateTime                  int64             `json:"updateTime"`
+	Assets                      []AccountAsset    `json:"assets"`
+	Positions                   []AccountPosition `json:"positions"`
+}
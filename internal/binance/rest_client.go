@@ -3,8 +3,6 @@ package binance
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"time"
 )
 
 // GetDepthSnapshot 通过 REST API 拉取指定深度的全量快照
@@ -17,8 +15,8 @@ func GetDepthSnapshot(baseURL, symbol string, limit int) (*RestDepthSnapshot, er
 	// 动态拼接环境 URL
 	url := fmt.Sprintf("%s/fapi/v1/depth?symbol=%s&limit=%d", baseURL, symbol, limit)
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(url)
+	// 与 APIClient 共用连接池，重同步时不必重新握手
+	resp, err := DefaultHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
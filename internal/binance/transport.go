@@ -0,0 +1,152 @@
+package binance
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/logging"
+)
+
+const (
+	// dnsCacheTTL 域名解析结果的缓存时长；拨号全部失败时立即作废
+	dnsCacheTTL = time.Minute
+	// DefaultWarmInterval 保温请求的默认间隔，需小于服务端空闲连接的回收时间
+	DefaultWarmInterval = 30 * time.Second
+)
+
+var transportLog = logging.For("binance.transport")
+
+// DefaultHTTPClient 网关内全部 REST 请求 (APIClient 与 GetDepthSnapshot) 共用的客户端，
+// 连接池在各请求间复用，只有第一次请求需要 TCP + TLS 握手
+var DefaultHTTPClient = &http.Client{
+	Timeout:   5 * time.Second, // 交易请求必须有超时控制
+	Transport: NewTransport(),
+}
+
+// NewTransport 针对少量固定域名、低延迟请求调优的 Transport：长连接、HTTP/2、
+// 带 DNS 缓存与 TCP_NODELAY 的拨号器
+func NewTransport() *http.Transport {
+	dialer := newCachingDialer(&net.Dialer{
+		Timeout:   3 * time.Second,
+		KeepAlive: 30 * time.Second, // TCP keepalive 探测，及早发现被中间设备静默丢弃的连接
+	})
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true, // 自定义 DialContext 后需显式开启
+		MaxIdleConns:          64,
+		MaxIdleConnsPerHost:   16, // 默认只有 2，并发下单时多余的连接会被关闭，下次又要重新握手
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   3 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// cachingDialer 缓存域名解析结果，省去每次新建连接时的 DNS 查询
+type cachingDialer struct {
+	dialer *net.Dialer
+	ttl    time.Duration
+	lookup func(ctx context.Context, host string) ([]string, error)
+
+	mu    sync.Mutex
+	cache map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+func newCachingDialer(d *net.Dialer) *cachingDialer {
+	return &cachingDialer{
+		dialer: d,
+		ttl:    dnsCacheTTL,
+		lookup: net.DefaultResolver.LookupHost,
+		cache:  make(map[string]dnsEntry),
+	}
+}
+
+// DialContext 依次尝试缓存中的地址，全部失败时作废该域名的缓存
+func (d *cachingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dial(ctx, network, address)
+	}
+	addrs, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range addrs {
+		conn, err := d.dial(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	d.mu.Lock()
+	delete(d.cache, host)
+	d.mu.Unlock()
+	return nil, lastErr
+}
+
+func (d *cachingDialer) resolve(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	entry, ok := d.cache[host]
+	d.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		if ok {
+			// 解析失败时沿用过期结果，DNS 短暂故障不影响下单
+			transportLog.Warn("域名解析失败，沿用缓存地址", "host", host, logging.Err(err))
+			return entry.addrs, nil
+		}
+		return nil, err
+	}
+	d.mu.Lock()
+	d.cache[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(d.ttl)}
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+func (d *cachingDialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	// Go 默认已开启 TCP_NODELAY，这里显式设置，避免小包 (下单请求) 被 Nagle 算法攒批延迟
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.SetNoDelay(true)
+	}
+	return conn, nil
+}
+
+// Warm 发送一次 /fapi/v1/ping (权重 1)，建立或保活连接池中的连接
+func (c *APIClient) Warm() error {
+	_, err := c.keyedRequest(http.MethodGet, "/fapi/v1/ping", nil)
+	return err
+}
+
+// KeepWarm 立即预热一次，之后每隔 interval 发送一次 ping，直到 ctx 取消；
+// 空闲一段时间后的第一笔订单不必再付出 TCP + TLS 握手的代价
+func (c *APIClient) KeepWarm(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Warm(); err != nil {
+			transportLog.Warn("REST 连接预热失败", logging.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package binance
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTLSOrderServer 模拟交易所的 HTTPS 服务 (开启 HTTP/2)，统计新建连接数
+func newTLSOrderServer(tb testing.TB) (*httptest.Server, *atomic.Int32) {
+	tb.Helper()
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fapi/v1/ping" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"orderId":1,"clientOrderId":"c","status":"NEW","price":"100.00","origQty":"0.010"}`))
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.StartTLS()
+	tb.Cleanup(srv.Close)
+	return srv, &conns
+}
+
+// tunedClient 使用 NewTransport，并信任测试服务器的自签证书
+func tunedClient(srv *httptest.Server) *http.Client {
+	tr := NewTransport()
+	tr.TLSClientConfig = &tls.Config{RootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+	return &http.Client{Timeout: 5 * time.Second, Transport: tr}
+}
+
+func TestTransport_WarmReusesConnection(t *testing.T) {
+	srv, conns := newTLSOrderServer(t)
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+	c.HTTPClient = tunedClient(srv)
+
+	if err := c.Warm(); err != nil {
+		t.Fatalf("warm: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.01, Price: 100}); err != nil {
+			t.Fatalf("place: %v", err)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("orders after warm-up should reuse the pre-warmed connection, got %d connections", n)
+	}
+	resp, err := c.HTTPClient.Get(srv.URL + "/fapi/v1/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+}
+
+func TestCachingDialer_CachesAndInvalidates(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	var lookups atomic.Int32
+	addr := "127.0.0.1"
+	d := newCachingDialer(&net.Dialer{Timeout: time.Second})
+	d.lookup = func(ctx context.Context, host string) ([]string, error) {
+		lookups.Add(1)
+		return []string{addr}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("exchange.test", port))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conn.Close()
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("expected 1 lookup within TTL, got %d", n)
+	}
+
+	// 缓存的地址拨号失败后作废，下一次重新解析
+	ln.Close()
+	if _, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("exchange.test", port)); err == nil {
+		t.Fatal("dial to a closed port should fail")
+	}
+	d.DialContext(context.Background(), "tcp", net.JoinHostPort("exchange.test", port))
+	if n := lookups.Load(); n != 2 {
+		t.Errorf("failed dial should invalidate the cache, got %d lookups", n)
+	}
+}
+
+func TestCachingDialer_StaleOnLookupFailure(t *testing.T) {
+	d := newCachingDialer(&net.Dialer{})
+	d.cache["exchange.test"] = dnsEntry{addrs: []string{"10.0.0.1"}, expires: time.Now().Add(-time.Second)}
+	d.lookup = func(ctx context.Context, host string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+	addrs, err := d.resolve(context.Background(), "exchange.test")
+	if err != nil || len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+		t.Errorf("expired entry should be used when lookup fails, got %v %v", addrs, err)
+	}
+}
+
+// ---- 基准：同一个 fake HTTPS 服务器上的下单往返 ----
+
+func benchmarkPlaceOrder(b *testing.B, newClient func(srv *httptest.Server) *http.Client, perRequest bool) {
+	srv, _ := newTLSOrderServer(b)
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+	c.HTTPClient = newClient(srv)
+	req := OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.01, Price: 100, NewClientOrderID: "c"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if perRequest {
+			// 模拟旧实现 / 空闲后连接已被回收：每次都要重新 TCP + TLS 握手
+			b.StopTimer()
+			c.HTTPClient = newClient(srv)
+			b.StartTimer()
+		}
+		if _, err := c.PlaceOrder(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPlaceOrder_ColdConnection 每次下单都新建连接 (TLS 握手)
+func BenchmarkPlaceOrder_ColdConnection(b *testing.B) {
+	benchmarkPlaceOrder(b, tunedClient, true)
+}
+
+// BenchmarkPlaceOrder_WarmConnection 共用预热过的连接池
+func BenchmarkPlaceOrder_WarmConnection(b *testing.B) {
+	benchmarkPlaceOrder(b, func(srv *httptest.Server) *http.Client {
+		client := tunedClient(srv)
+		c := &APIClient{BaseURL: srv.URL, HTTPClient: client}
+		if err := c.Warm(); err != nil {
+			b.Fatal(err)
+		}
+		return client
+	}, false)
+}
+
+// BenchmarkPlaceOrder_DefaultTransport 标准库默认 Transport (HTTP/1.1 over TLS，每个 host 仅保留 2 个空闲连接)
+func BenchmarkPlaceOrder_DefaultTransport(b *testing.B) {
+	benchmarkPlaceOrder(b, func(srv *httptest.Server) *http.Client { return srv.Client() }, false)
+}
@@ -1,5 +1,7 @@
 package binance
 
+import "time"
+
 // WSDepthEvent 对应币安 U本位合约增量深度推送的 JSON 结构
 type WSDepthEvent struct {
 	EventType       string     `json:"e"` // [新增避雷针] 专门吸收 "e" ("depthUpdate")，防止 Go 乱匹配
@@ -11,6 +13,7 @@ type WSDepthEvent struct {
 	PrevFinalUpdID  int64      `json:"pu"`
 	Bids            [][]string `json:"b"`
 	Asks            [][]string `json:"a"`
+	ReceivedAt      time.Time  `json:"-"` // 网关收到该消息的本地时间，由 WSClient 填充
 }
 
 // RestDepthSnapshot 对应币安 U本位合约 REST API 返回的全量深度快照
@@ -28,9 +31,53 @@ type PriceLevel struct {
 
 // OrderBookSnapshot 供策略端读取的最终标准切片
 type OrderBookSnapshot struct {
-	Symbol       string       `json:"s"`
-	LastUpdateID int64        `json:"u"` // 策略端可以通过校验这个ID，判断切片是否变动
-	Timestamp    int64        `json:"t"` // Redis 写入时间戳
-	Bids         []PriceLevel `json:"b"` // 买盘 (价格从高到低排序)
-	Asks         []PriceLevel `json:"a"` // 卖盘 (价格从低到高排序)
+	Symbol          string       `json:"s"`
+	LastUpdateID    int64        `json:"u"`  // 策略端可以通过校验这个ID，判断切片是否变动
+	Timestamp       int64        `json:"t"`  // 网关发布时间 (毫秒)
+	EventTime       int64        `json:"E"`  // 最后一条已应用深度事件的交易所事件时间
+	TransactionTime int64        `json:"T"`  // 最后一条已应用深度事件的撮合时间
+	ReceiveTime     int64        `json:"r"`  // 网关收到最后一条已应用深度事件的时间
+	State           string       `json:"st"` // SYNCED / RESYNCING / STALE，策略端只应在 SYNCED 时交易
+	Bids            []PriceLevel `json:"b"`  // 买盘 (价格从高到低排序)
+	Asks            []PriceLevel `json:"a"`  // 卖盘 (价格从低到高排序)
+}
+
+// 盘口数据质量状态，对应 OrderBookSnapshot.State
+const (
+	BookStateSynced    = "SYNCED"    // 增量与快照已缝合且持续到达
+	BookStateResyncing = "RESYNCING" // 序列号断层或启动中，正在重新拉取快照，盘口不可信
+	BookStateStale     = "STALE"     // 超过配置时长未收到深度事件，行情可能已冻结
+)
+
+// bookStateCodes 定长二进制布局 (codec / shmbook) 中状态的数值编码，0 表示未知
+var bookStateCodes = []string{"", BookStateSynced, BookStateResyncing, BookStateStale}
+
+// BookStateCode 状态字符串转数值编码
+func BookStateCode(state string) uint8 {
+	for i, s := range bookStateCodes {
+		if s == state {
+			return uint8(i)
+		}
+	}
+	return 0
+}
+
+// BookStateFromCode 数值编码转状态字符串，未知编码返回空串
+func BookStateFromCode(code uint8) string {
+	if int(code) < len(bookStateCodes) {
+		return bookStateCodes[code]
+	}
+	return ""
+}
+
+// MarkPriceEvent 对应 <symbol>@markPrice 推送，标记价格是计算未实现盈亏与强平的基准
+type MarkPriceEvent struct {
+	EventType            string `json:"e"` // "markPriceUpdate"
+	EventTime            int64  `json:"E"`
+	Symbol               string `json:"s"`
+	MarkPrice            string `json:"p"`
+	IndexPrice           string `json:"i"`
+	EstimatedSettlePrice string `json:"P"`
+	FundingRate          string `json:"r"`
+	NextFundingTime      int64  `json:"T"`
 }
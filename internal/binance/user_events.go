@@ -0,0 +1,218 @@
+package binance
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UserEvent 私有流中一条已解码的事件
+type UserEvent interface {
+	// Type 事件类型，即推送中的 e 字段
+	Type() string
+	// Dispatch 回调 h 中与本事件对应的方法
+	Dispatch(h UserStreamHandler)
+}
+
+// UserStreamHandler 私有流事件处理器，每种事件一个类型化回调
+// 嵌入 BaseUserStreamHandler 后只需实现关心的事件；新增事件类型时在此追加方法、
+// 在 BaseUserStreamHandler 补一个空实现并注册解码器，读取循环无需改动
+type UserStreamHandler interface {
+	OnAccountUpdate(UserDataEvent)
+	OnMarginCall(UserDataEvent)
+	OnOrderTradeUpdate(UserDataEvent)
+	OnAccountConfigUpdate(AccountConfigUpdateEvent)
+	OnTradeLite(TradeLiteEvent)
+	OnStrategyUpdate(StrategyUpdateEvent)
+	OnGridUpdate(GridUpdateEvent)
+	OnConditionalOrderTriggerReject(ConditionalOrderTriggerRejectEvent)
+	OnListenKeyExpired(ListenKeyExpiredEvent)
+	// OnUnknownEvent 没有注册解码器的事件类型，raw 为原始消息
+	OnUnknownEvent(eventType string, raw []byte)
+}
+
+// BaseUserStreamHandler 全部事件的空实现，供嵌入以选择性订阅
+type BaseUserStreamHandler struct{}
+
+func (BaseUserStreamHandler) OnAccountUpdate(UserDataEvent)                                      {}
+func (BaseUserStreamHandler) OnMarginCall(UserDataEvent)                                         {}
+func (BaseUserStreamHandler) OnOrderTradeUpdate(UserDataEvent)                                   {}
+func (BaseUserStreamHandler) OnAccountConfigUpdate(AccountConfigUpdateEvent)                     {}
+func (BaseUserStreamHandler) OnTradeLite(TradeLiteEvent)                                         {}
+func (BaseUserStreamHandler) OnStrategyUpdate(StrategyUpdateEvent)                               {}
+func (BaseUserStreamHandler) OnGridUpdate(GridUpdateEvent)                                       {}
+func (BaseUserStreamHandler) OnConditionalOrderTriggerReject(ConditionalOrderTriggerRejectEvent) {}
+func (BaseUserStreamHandler) OnListenKeyExpired(ListenKeyExpiredEvent)                           {}
+func (BaseUserStreamHandler) OnUnknownEvent(string, []byte)                                      {}
+
+// userEventDecoders 事件类型 → 解码器
+var userEventDecoders = map[string]func([]byte) (UserEvent, error){
+	"ACCOUNT_UPDATE":                   decodeUserDataEvent,
+	"MARGIN_CALL":                      decodeUserDataEvent,
+	"ORDER_TRADE_UPDATE":               decodeUserDataEvent,
+	"ACCOUNT_CONFIG_UPDATE":            decodeAs[AccountConfigUpdateEvent],
+	"TRADE_LITE":                       decodeAs[TradeLiteEvent],
+	"STRATEGY_UPDATE":                  decodeAs[StrategyUpdateEvent],
+	"GRID_UPDATE":                      decodeAs[GridUpdateEvent],
+	"CONDITIONAL_ORDER_TRIGGER_REJECT": decodeAs[ConditionalOrderTriggerRejectEvent],
+	"listenKeyExpired":                 decodeAs[ListenKeyExpiredEvent],
+}
+
+// DecodeUserEvent 按 e 字段解码一条私有流消息；未知事件类型返回 (nil, eventType, nil)
+func DecodeUserEvent(raw []byte) (UserEvent, string, error) {
+	// E 必须同时声明，否则会被大小写不敏感地匹配到 e 上
+	var head struct {
+		EventType string `json:"e"`
+		EventTime int64  `json:"E"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, "", err
+	}
+	decode, ok := userEventDecoders[head.EventType]
+	if !ok {
+		return nil, head.EventType, nil
+	}
+	event, err := decode(raw)
+	if err != nil {
+		return nil, head.EventType, fmt.Errorf("%s 解析失败: %w", head.EventType, err)
+	}
+	return event, head.EventType, nil
+}
+
+func decodeAs[T UserEvent](raw []byte) (UserEvent, error) {
+	var event T
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func decodeUserDataEvent(raw []byte) (UserEvent, error) {
+	var event UserDataEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+	if event.EventType == "ORDER_TRADE_UPDATE" && event.Order == nil {
+		return nil, fmt.Errorf("缺少订单对象 o")
+	}
+	return event, nil
+}
+
+// Type 实现 UserEvent
+func (e UserDataEvent) Type() string { return e.EventType }
+
+// Dispatch 实现 UserEvent；UserDataEvent 同时承载 ACCOUNT_UPDATE / MARGIN_CALL / ORDER_TRADE_UPDATE
+func (e UserDataEvent) Dispatch(h UserStreamHandler) {
+	switch e.EventType {
+	case "ACCOUNT_UPDATE":
+		h.OnAccountUpdate(e)
+	case "MARGIN_CALL":
+		h.OnMarginCall(e)
+	case "ORDER_TRADE_UPDATE":
+		h.OnOrderTradeUpdate(e)
+	}
+}
+
+// AccountConfigUpdateEvent 账户配置变更：交易对杠杆 (ac) 或联合保证金模式 (ai)，两者只出现其一
+type AccountConfigUpdateEvent struct {
+	EventType       string `json:"e"`
+	EventTime       int64  `json:"E"`
+	TransactionTime int64  `json:"T"`
+	Leverage        *struct {
+		Symbol   string `json:"s"`
+		Leverage int    `json:"l"`
+	} `json:"ac,omitempty"`
+	AssetMode *struct {
+		MultiAssets bool `json:"j"` // 联合保证金模式是否开启
+	} `json:"ai,omitempty"`
+}
+
+func (e AccountConfigUpdateEvent) Type() string                 { return e.EventType }
+func (e AccountConfigUpdateEvent) Dispatch(h UserStreamHandler) { h.OnAccountConfigUpdate(e) }
+
+// TradeLiteEvent 精简成交推送，比 ORDER_TRADE_UPDATE 更早到达，只含成交核心字段
+// 大小写成对的 key (s/S, l/L, t/T) 必须全部声明，原因同 OrderTradeUpdate
+type TradeLiteEvent struct {
+	EventType       string `json:"e"`
+	EventTime       int64  `json:"E"`
+	TransactionTime int64  `json:"T"`
+	Symbol          string `json:"s"`
+	Side            string `json:"S"`
+	OrigQty         string `json:"q"`
+	Price           string `json:"p"`
+	IsMaker         bool   `json:"m"`
+	ClientOrderID   string `json:"c"`
+	LastFilledPrice string `json:"L"`
+	LastFilledQty   string `json:"l"`
+	TradeID         int64  `json:"t"`
+	OrderID         int64  `json:"i"`
+}
+
+func (e TradeLiteEvent) Type() string                 { return e.EventType }
+func (e TradeLiteEvent) Dispatch(h UserStreamHandler) { h.OnTradeLite(e) }
+
+// StrategyUpdateEvent 交易所策略 (如网格) 状态变化
+type StrategyUpdateEvent struct {
+	EventType       string `json:"e"`
+	EventTime       int64  `json:"E"`
+	TransactionTime int64  `json:"T"`
+	Strategy        struct {
+		StrategyID   int64  `json:"si"`
+		StrategyType string `json:"st"` // GRID ...
+		Status       string `json:"ss"` // NEW / WORKING / CANCELLED / EXPIRED
+		Symbol       string `json:"s"`
+		UpdateTime   int64  `json:"ut"`
+		OpCode       int    `json:"c"` // 状态原因码，如 8001 参数变更、8007 策略已满
+	} `json:"su"`
+}
+
+func (e StrategyUpdateEvent) Type() string                 { return e.EventType }
+func (e StrategyUpdateEvent) Dispatch(h UserStreamHandler) { h.OnStrategyUpdate(e) }
+
+// GridUpdateEvent 网格策略运行数据
+type GridUpdateEvent struct {
+	EventType       string `json:"e"`
+	EventTime       int64  `json:"E"`
+	TransactionTime int64  `json:"T"`
+	Grid            struct {
+		StrategyID   int64  `json:"si"`
+		StrategyType string `json:"st"`
+		Status       string `json:"ss"`
+		Symbol       string `json:"s"`
+		RealizedPnL  string `json:"r"`
+		UnmatchedAvg string `json:"up"` // 未配对成交均价
+		UnmatchedQty string `json:"uq"`
+		UnmatchedFee string `json:"uf"`
+		MatchedPnL   string `json:"mp"`
+		UpdateTime   int64  `json:"ut"`
+	} `json:"gu"`
+}
+
+func (e GridUpdateEvent) Type() string                 { return e.EventType }
+func (e GridUpdateEvent) Dispatch(h UserStreamHandler) { h.OnGridUpdate(e) }
+
+// ConditionalOrderTriggerRejectEvent 条件单 (止损 / 止盈) 触发后被拒绝
+type ConditionalOrderTriggerRejectEvent struct {
+	EventType       string `json:"e"`
+	EventTime       int64  `json:"E"`
+	TransactionTime int64  `json:"T"`
+	Order           struct {
+		Symbol  string `json:"s"`
+		OrderID int64  `json:"i"`
+		Reason  string `json:"r"`
+	} `json:"or"`
+}
+
+func (e ConditionalOrderTriggerRejectEvent) Type() string { return e.EventType }
+func (e ConditionalOrderTriggerRejectEvent) Dispatch(h UserStreamHandler) {
+	h.OnConditionalOrderTriggerReject(e)
+}
+
+// ListenKeyExpiredEvent listenKey 已失效，此后该连接不再有推送
+type ListenKeyExpiredEvent struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	ListenKey string `json:"listenKey"`
+}
+
+func (e ListenKeyExpiredEvent) Type() string                 { return e.EventType }
+func (e ListenKeyExpiredEvent) Dispatch(h UserStreamHandler) { h.OnListenKeyExpired(e) }
@@ -0,0 +1,113 @@
+package binance
+
+import (
+	"testing"
+)
+
+// recordingHandler 记录收到的事件类型，只覆盖部分回调以验证选择性订阅
+type recordingHandler struct {
+	BaseUserStreamHandler
+	got     []UserEvent
+	unknown []string
+}
+
+func (h *recordingHandler) OnMarginCall(e UserDataEvent) { h.got = append(h.got, e) }
+func (h *recordingHandler) OnAccountConfigUpdate(e AccountConfigUpdateEvent) {
+	h.got = append(h.got, e)
+}
+func (h *recordingHandler) OnTradeLite(e TradeLiteEvent)               { h.got = append(h.got, e) }
+func (h *recordingHandler) OnStrategyUpdate(e StrategyUpdateEvent)     { h.got = append(h.got, e) }
+func (h *recordingHandler) OnGridUpdate(e GridUpdateEvent)             { h.got = append(h.got, e) }
+func (h *recordingHandler) OnListenKeyExpired(e ListenKeyExpiredEvent) { h.got = append(h.got, e) }
+func (h *recordingHandler) OnUnknownEvent(eventType string, _ []byte) {
+	h.unknown = append(h.unknown, eventType)
+}
+func (h *recordingHandler) OnConditionalOrderTriggerReject(e ConditionalOrderTriggerRejectEvent) {
+	h.got = append(h.got, e)
+}
+
+func dispatchAll(t *testing.T, h UserStreamHandler, msgs ...string) {
+	t.Helper()
+	for _, m := range msgs {
+		event, eventType, err := DecodeUserEvent([]byte(m))
+		if err != nil {
+			t.Fatalf("decode %s: %v", m, err)
+		}
+		if event == nil {
+			h.OnUnknownEvent(eventType, []byte(m))
+			continue
+		}
+		event.Dispatch(h)
+	}
+}
+
+func TestDecodeUserEvent_TypedEvents(t *testing.T) {
+	h := &recordingHandler{}
+	dispatchAll(t, h,
+		`{"e":"ACCOUNT_CONFIG_UPDATE","E":1,"T":1,"ac":{"s":"BTCUSDT","l":25}}`,
+		`{"e":"ACCOUNT_CONFIG_UPDATE","E":2,"T":2,"ai":{"j":true}}`,
+		`{"e":"TRADE_LITE","E":3,"T":3,"s":"BTCUSDT","q":"0.010","p":"0","m":false,"c":"abc","S":"BUY","L":"64089.20","l":"0.004","t":109100866,"i":8886774}`,
+		`{"e":"STRATEGY_UPDATE","T":4,"E":4,"su":{"si":176054594,"st":"GRID","ss":"NEW","s":"BTCUSDT","ut":4,"c":8007}}`,
+		`{"e":"GRID_UPDATE","T":5,"E":5,"gu":{"si":176057039,"st":"GRID","ss":"WORKING","s":"BTCUSDT","r":"-0.003","up":"16720","uq":"-0.001","uf":"-0.000","mp":"0.0","ut":5}}`,
+		`{"e":"CONDITIONAL_ORDER_TRIGGER_REJECT","E":6,"T":6,"or":{"s":"ETHUSDT","i":155618472834,"r":"would immediately trigger"}}`,
+		`{"e":"listenKeyExpired","E":7,"listenKey":"k1"}`,
+	)
+	if len(h.got) != 7 {
+		t.Fatalf("expected 7 dispatched events, got %d", len(h.got))
+	}
+
+	ac := h.got[0].(AccountConfigUpdateEvent)
+	if ac.Leverage == nil || ac.Leverage.Symbol != "BTCUSDT" || ac.Leverage.Leverage != 25 || ac.AssetMode != nil {
+		t.Errorf("leverage update: %+v", ac)
+	}
+	if ai := h.got[1].(AccountConfigUpdateEvent); ai.AssetMode == nil || !ai.AssetMode.MultiAssets || ai.Leverage != nil {
+		t.Errorf("multi-assets update: %+v", ai)
+	}
+	// 大小写成对的 key 各自落到正确字段
+	tl := h.got[2].(TradeLiteEvent)
+	if tl.Symbol != "BTCUSDT" || tl.Side != "BUY" || tl.LastFilledPrice != "64089.20" || tl.LastFilledQty != "0.004" ||
+		tl.TradeID != 109100866 || tl.TransactionTime != 3 || tl.OrderID != 8886774 || tl.ClientOrderID != "abc" {
+		t.Errorf("trade lite: %+v", tl)
+	}
+	if su := h.got[3].(StrategyUpdateEvent); su.Strategy.StrategyID != 176054594 || su.Strategy.Status != "NEW" || su.Strategy.OpCode != 8007 {
+		t.Errorf("strategy update: %+v", su)
+	}
+	if gu := h.got[4].(GridUpdateEvent); gu.Grid.Status != "WORKING" || gu.Grid.RealizedPnL != "-0.003" {
+		t.Errorf("grid update: %+v", gu)
+	}
+	if rj := h.got[5].(ConditionalOrderTriggerRejectEvent); rj.Order.OrderID != 155618472834 || rj.Order.Reason == "" {
+		t.Errorf("trigger reject: %+v", rj)
+	}
+	if lk := h.got[6].(ListenKeyExpiredEvent); lk.ListenKey != "k1" || lk.Type() != "listenKeyExpired" {
+		t.Errorf("listenKeyExpired: %+v", lk)
+	}
+}
+
+func TestDecodeUserEvent_SelectiveSubscription(t *testing.T) {
+	h := &recordingHandler{}
+	// ACCOUNT_UPDATE / ORDER_TRADE_UPDATE 未覆盖，由 BaseUserStreamHandler 忽略；MARGIN_CALL 已订阅
+	dispatchAll(t, h,
+		`{"e":"ACCOUNT_UPDATE","E":1,"T":1,"a":{"m":"ORDER","B":[],"P":[]}}`,
+		`{"e":"ORDER_TRADE_UPDATE","E":2,"T":2,"o":{"s":"BTCUSDT","c":"x","X":"NEW"}}`,
+		`{"e":"MARGIN_CALL","E":3,"cw":"3.16","p":[{"s":"ETHUSDT","ps":"LONG","pa":"1.327","mt":"CROSSED","mp":"187.17","up":"-1.166","mm":"1.614"}]}`,
+		`{"e":"SOME_NEW_EVENT","E":4}`,
+	)
+	if len(h.got) != 1 || h.got[0].Type() != "MARGIN_CALL" {
+		t.Fatalf("only MARGIN_CALL should reach the handler, got %v", h.got)
+	}
+	if mc := h.got[0].(UserDataEvent); len(mc.MarginCallPositions) != 1 || mc.MarginCallPositions[0].MaintMargin != "1.614" {
+		t.Errorf("margin call positions: %+v", mc.MarginCallPositions)
+	}
+	if len(h.unknown) != 1 || h.unknown[0] != "SOME_NEW_EVENT" {
+		t.Errorf("unregistered event types should go to OnUnknownEvent, got %v", h.unknown)
+	}
+}
+
+func TestDecodeUserEvent_Errors(t *testing.T) {
+	if _, _, err := DecodeUserEvent([]byte(`not json`)); err == nil {
+		t.Error("invalid JSON should return an error")
+	}
+	if _, eventType, err := DecodeUserEvent([]byte(`{"e":"ORDER_TRADE_UPDATE","E":1}`)); err == nil || eventType != "ORDER_TRADE_UPDATE" {
+		t.Errorf("ORDER_TRADE_UPDATE without o should be rejected, got type=%q err=%v", eventType, err)
+	}
+}
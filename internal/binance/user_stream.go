@@ -2,13 +2,16 @@ package binance
 
 import (
 	"context"
-	"encoding/json"
-	"log"
-	"time" // 🌟 记得引入 time 包
+	"errors"
+	"time"
+
+	"BinanceAutoBot2/internal/logging"
 
 	"github.com/gorilla/websocket"
 )
 
+var userStreamLog = logging.For("binance.user_stream")
+
 // internal/binance/user_stream.go
 
 // 🌟 终极防弹版 UserDataEvent 结构体
@@ -16,118 +19,140 @@ type UserDataEvent struct {
 	EventType string `json:"e"` // 严格隔离：接收小写 e (字符串，例如 "ACCOUNT_UPDATE")
 	EventTime int64  `json:"E"` // 严格隔离：吸收大写 E (数字时间戳，防止解析器崩溃)
 	Account   struct {
+		Reason   string `json:"m"` // 事件原因：ORDER / FUNDING_FEE / DEPOSIT / MARGIN_TRANSFER ...
 		Balances []struct {
-			Asset   string `json:"a"`
-			Balance string `json:"wb"`
+			Asset              string `json:"a"`
+			Balance            string `json:"wb"`
+			CrossWalletBalance string `json:"cw"`
+			BalanceChange      string `json:"bc"` // 除盈亏与手续费以外的钱包余额变化量
 		} `json:"B"`
 		Positions []struct {
-			Symbol     string `json:"s"`
-			Amount     string `json:"pa"`
-			EntryPrice string `json:"ep"`
+			Symbol              string `json:"s"`
+			Amount              string `json:"pa"`
+			EntryPrice          string `json:"ep"`
+			BreakEvenPrice      string `json:"bep"`
+			AccumulatedRealized string `json:"cr"` // 累计已实现盈亏
+			UnrealizedPnL       string `json:"up"`
+			MarginType          string `json:"mt"` // cross / isolated
+			IsolatedWallet      string `json:"iw"`
+			PositionSide        string `json:"ps"` // BOTH (单向持仓) / LONG / SHORT (双向持仓)
 		} `json:"P"`
 	} `json:"a"`
+
+	// 以下字段仅 ORDER_TRADE_UPDATE 事件携带
+	TransactionTime int64             `json:"T"`
+	Order           *OrderTradeUpdate `json:"o"`
+
+	// 以下字段仅 MARGIN_CALL 事件携带
+	CrossWalletBalance  string `json:"cw"`
+	MarginCallPositions []struct {
+		Symbol         string `json:"s"`
+		PositionSide   string `json:"ps"`
+		Amount         string `json:"pa"`
+		MarginType     string `json:"mt"`
+		IsolatedWallet string `json:"iw"`
+		MarkPrice      string `json:"mp"`
+		UnrealizedPnL  string `json:"up"`
+		MaintMargin    string `json:"mm"` // 维持保证金
+	} `json:"p"`
 }
 
-// StartUserDataStream 启动私有 WebSocket 连接，并内置断线自动重连机制
-func StartUserDataStream(ctx context.Context, wsURL string, onUpdate func(UserDataEvent)) {
-	dialer := websocket.DefaultDialer
-	backoff := 3 * time.Second
-	const maxBackoff = 60 * time.Second
+// OrderTradeUpdate 对应 ORDER_TRADE_UPDATE 事件中的 "o" 订单对象
+// 注意：encoding/json 会对未声明的 key 做大小写不敏感匹配，所以大小写成对出现的字段 (s/S, x/X, l/L, n/N, t/T, ap/AP) 必须全部声明
+type OrderTradeUpdate struct {
+	Symbol          string `json:"s"`
+	ClientOrderID   string `json:"c"`
+	Side            string `json:"S"` // BUY / SELL
+	OrderType       string `json:"o"`
+	TimeInForce     string `json:"f"`
+	OrigQty         string `json:"q"`
+	Price           string `json:"p"`
+	AvgPrice        string `json:"ap"`
+	ActivationPrice string `json:"AP"` // 仅追踪止损单
+	ExecutionType   string `json:"x"`  // NEW / TRADE / CANCELED / EXPIRED / AMENDMENT / CALCULATED (强平)
+	Status          string `json:"X"`  // NEW / PARTIALLY_FILLED / FILLED / CANCELED / EXPIRED
+	OrderID         int64  `json:"i"`
+	LastFilledQty   string `json:"l"`
+	CumFilledQty    string `json:"z"`
+	LastFilledPrice string `json:"L"`
+	CommissionAsset string `json:"N"`
+	Commission      string `json:"n"`
+	TradeTime       int64  `json:"T"`
+	TradeID         int64  `json:"t"`
+	IsMaker         bool   `json:"m"`
+	ReduceOnly      bool   `json:"R"`
+	PositionSide    string `json:"ps"`
+	RealizedProfit  string `json:"rp"` // 本次成交的已实现盈亏
+}
 
-	// 外层循环：负责断线后的无限重连
-	for {
+// ErrListenKeyExpired 私有流收到 listenKeyExpired 事件，当前 listenKey 已失效，需要重新创建
+var ErrListenKeyExpired = errors.New("listenKey 已过期")
+
+// readUserStream 读取一条已建立的私有流连接直到断开，返回断开原因
+// 每条消息经 DecodeUserEvent 解码后分发给 h；收到 listenKeyExpired 时返回 ErrListenKeyExpired；ctx 取消时主动关闭连接
+func readUserStream(ctx context.Context, conn *websocket.Conn, h UserStreamHandler) error {
+	defer conn.Close()
+
+	// 监听 ctx 取消，主动关闭连接让 ReadMessage 返回
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
 		select {
 		case <-ctx.Done():
-			return
-		default:
+			conn.Close()
+		case <-done:
 		}
+	}()
 
-		log.Printf("[UserStream] 🔄 正在尝试连接私有资产频道...")
-		conn, _, err := dialer.Dial(wsURL, nil)
-		if err != nil {
-			log.Printf("[UserStream] ❌ 连接失败: %v, %s后重试...", err, backoff)
+	// ==========================================
+	// 💓 WebSocket 底层 Ping 协程
+	// 目的：每 60 秒主动发送一个 Ping 帧，防止被 AWS 负载均衡器因“长时间静默”踢下线
+	// ==========================================
+	go func() {
+		pingTicker := time.NewTicker(60 * time.Second)
+		defer pingTicker.Stop()
+		for {
 			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(backoff):
-				backoff *= 2
-				if backoff > maxBackoff {
-					backoff = maxBackoff
+			case <-done:
+				return // 连接断开时，安全退出这个保活协程
+			case <-pingTicker.C:
+				// 发送底层的 Ping 控制消息
+				if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second)); err != nil {
+					return // 写入失败说明连接已断，退出协程
 				}
 			}
-			continue
 		}
-		backoff = 3 * time.Second // 连接成功后重置退避时间
-
-		log.Println("[UserStream] 🛡️ 账户私有资产监听通道已建立！等待资产变动...")
-
-		// ==========================================
-		// 💓 新增：WebSocket 底层 Ping 协程
-		// 目的：每 60 秒主动发送一个 Ping 帧，防止被 AWS 负载均衡器因“长时间静默”踢下线
-		// ==========================================
-		pingTicker := time.NewTicker(60 * time.Second)
-		pingDone := make(chan struct{})
+	}()
 
-		go func() {
-			defer pingTicker.Stop()
-			for {
-				select {
-				case <-pingDone:
-					return // 连接断开时，安全退出这个保活协程
-				case <-pingTicker.C:
-					// 发送底层的 Ping 控制消息
-					if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second)); err != nil {
-						return // 写入失败说明连接已断，退出协程
-					}
-				}
-			}
-		}()
-		// ==========================================
-
-		// 内层循环：持续读取数据
-		// 内层循环：持续读取数据
-		for {
-			_, message, err := conn.ReadMessage()
-			if err != nil {
-				log.Printf("[UserStream] ⚠️ 私有通道异常断开: %v", err)
-				break // 跳出内层循环，触发重连
-			}
-
-			// 🌟 1. 核心修复：先用一个通用的 map 解析，把所有事件的“真实面目”打印出来！
-			var rawMsg map[string]interface{}
-			if err := json.Unmarshal(message, &rawMsg); err != nil {
-				log.Printf("[UserStream] ❌ 无法解析的原始 JSON: %s", string(message))
-				continue
-			}
-
-			eventType, _ := rawMsg["e"].(string)
-
-			// 🌟 2. 捕捉【资产与仓位更新】
-			if eventType == "ACCOUNT_UPDATE" {
-				log.Printf("📥 [UserStream] 收到资产更新 (ACCOUNT_UPDATE)")
-
-				var event UserDataEvent
-				if err := json.Unmarshal(message, &event); err == nil {
-					onUpdate(event) // 将精确的结构体丢给 main.go 处理
-				} else {
-					// 如果解析失败，把红牌亮出来！
-					log.Printf("❌ [UserStream] 结构体解析失败: %v | 原始数据: %s", err, string(message))
-				}
-			} else if eventType == "ORDER_TRADE_UPDATE" {
-				// 🌟 3. 捕捉【订单成交状态更新】(极其重要，这是发单后最早回来的消息)
-				orderData, ok := rawMsg["o"].(map[string]interface{})
-				if ok {
-					status, _ := orderData["X"].(string) // 订单当前状态 (NEW, FILLED, CANCELED)
-					symbol, _ := orderData["s"].(string)
-					log.Printf("🔔 [UserStream] 订单流转 -> [%s] 状态变为: %s", symbol, status)
-				}
+	// 持续读取数据
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
+			return err
 		}
 
-		// 触发重连前的清理工作
-		close(pingDone) // 停止当前连接的 Ping 协程
-		conn.Close()    // 确保旧连接彻底关闭
-		log.Println("[UserStream] ⏳ 准备进行断线重连...")
-		time.Sleep(2 * time.Second)
+		event, eventType, err := DecodeUserEvent(message)
+		if err != nil {
+			userStreamLog.Error("私有流消息解析失败", "event", eventType, logging.Err(err), "raw", string(message))
+			continue
+		}
+		if event == nil {
+			userStreamLog.Debug("未注册的私有流事件", "event", eventType)
+			h.OnUnknownEvent(eventType, message)
+			continue
+		}
+		if u, ok := event.(UserDataEvent); ok && u.Order != nil {
+			userStreamLog.Debug("订单状态更新", "symbol", u.Order.Symbol, "client_order_id", u.Order.ClientOrderID,
+				"order_id", u.Order.OrderID, "status", u.Order.Status)
+		} else {
+			userStreamLog.Debug("收到私有流事件", "event", eventType)
+		}
+		event.Dispatch(h)
+		if _, ok := event.(ListenKeyExpiredEvent); ok {
+			return ErrListenKeyExpired
+		}
 	}
 }
@@ -0,0 +1,220 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+// UserStreamManager 私有流的完整生命周期：创建 / 续期 / 关闭 ListenKey，断线重连，
+// 并在 ListenKey 失效 (listenKeyExpired 推送或 -1125) 时重新创建后换用新地址连接
+//
+// Handler 与回调均可为 nil；断线期间的推送不会补发，调用方应在 OnConnect 中触发对账
+type UserStreamManager struct {
+	client    *APIClient
+	wsBaseURL string // 如 wss://fstream.binance.com/ws/，后接 listenKey
+
+	Handler      UserStreamHandler // 事件处理器，为 nil 时丢弃全部事件
+	OnConnect    func()            // 每次连接 (含重连、换新 ListenKey) 建立后调用
+	OnDisconnect func(err error)   // 已建立的连接断开时调用
+	OnListenKey  func(err error)   // 每次创建 / 续期 ListenKey 后调用，err 为 nil 表示成功
+
+	RenewInterval time.Duration // ListenKey 续期间隔，默认 30 分钟
+	MinBackoff    time.Duration // 获取 ListenKey 与重连的初始退避，默认 3 秒
+	MaxBackoff    time.Duration // 退避上限，默认 60 秒
+
+	mu        sync.Mutex
+	listenKey string
+}
+
+// NewUserStreamManager 创建管理器，调用 Run 后开始工作
+func NewUserStreamManager(client *APIClient, wsBaseURL string) *UserStreamManager {
+	return &UserStreamManager{
+		client:        client,
+		wsBaseURL:     wsBaseURL,
+		RenewInterval: 30 * time.Minute,
+		MinBackoff:    3 * time.Second,
+		MaxBackoff:    60 * time.Second,
+	}
+}
+
+// ListenKey 当前使用的 listenKey，尚未获取时为空
+func (m *UserStreamManager) ListenKey() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listenKey
+}
+
+func (m *UserStreamManager) setListenKey(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listenKey = key
+}
+
+func (m *UserStreamManager) notifyListenKey(err error) {
+	if m.OnListenKey != nil {
+		m.OnListenKey(err)
+	}
+}
+
+// Run 阻塞运行直到 ctx 取消：获取 ListenKey (失败按退避重试)，在其有效期内保持连接，
+// 失效后重新创建；退出时关闭 ListenKey
+func (m *UserStreamManager) Run(ctx context.Context) {
+	backoff := m.MinBackoff
+	for {
+		key, err := m.client.GetListenKey()
+		m.notifyListenKey(err)
+		if err != nil {
+			userStreamLog.Warn("获取 ListenKey 失败，稍后重试", logging.Err(err), "backoff", backoff)
+			if !sleepCtx(ctx, backoff) {
+				return
+			}
+			backoff = m.nextBackoff(backoff)
+			continue
+		}
+		backoff = m.MinBackoff
+		m.setListenKey(key)
+		userStreamLog.Info("ListenKey 已创建")
+
+		err = m.runSession(ctx, key)
+		if ctx.Err() != nil {
+			// 退出时主动关闭，交易所不必等到 60 分钟后才回收
+			if err := m.client.CloseListenKey(key); err != nil {
+				userStreamLog.Warn("关闭 ListenKey 失败", logging.Err(err))
+			}
+			m.setListenKey("")
+			return
+		}
+		userStreamLog.Warn("ListenKey 已失效，重新创建", logging.Err(err))
+		m.setListenKey("")
+	}
+}
+
+// runSession 在一个 ListenKey 的有效期内连接私有流、断线重连并定时续期，
+// 直到 ctx 取消或 ListenKey 失效，返回结束原因
+func (m *UserStreamManager) runSession(ctx context.Context, key string) error {
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		failMu  sync.Mutex
+		failErr error
+	)
+	// fail 记录 ListenKey 失效并结束本次会话 (续期协程与连接都会随 sctx 退出)
+	fail := func(err error) {
+		failMu.Lock()
+		if failErr == nil {
+			failErr = err
+		}
+		failMu.Unlock()
+		cancel()
+	}
+	failed := func() error {
+		failMu.Lock()
+		defer failMu.Unlock()
+		return failErr
+	}
+
+	go m.renewLoop(sctx, key, fail)
+
+	backoff := m.MinBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// 重连前先续期一次：既延长有效期，也能及早发现 ListenKey 已失效 (-1125)
+			if err := m.renew(key); IsAPIErrorCode(err, CodeListenKeyInvalid) {
+				return err
+			}
+		}
+
+		conn, _, err := websocket.DefaultDialer.DialContext(sctx, m.wsBaseURL+key, nil)
+		if err == nil {
+			backoff = m.MinBackoff // 连接成功后重置退避时间
+			userStreamLog.Info("私有流已连接")
+			if m.OnConnect != nil {
+				m.OnConnect()
+			}
+			err = readUserStream(sctx, conn, m.handler())
+			if ctx.Err() == nil && m.OnDisconnect != nil {
+				m.OnDisconnect(err)
+			}
+			if errors.Is(err, ErrListenKeyExpired) {
+				return err
+			}
+			userStreamLog.Warn("私有流断开", logging.Err(err))
+		} else {
+			userStreamLog.Warn("私有流连接失败，稍后重试", logging.Err(err), "backoff", backoff)
+		}
+
+		if err := failed(); err != nil {
+			return err
+		}
+		if !sleepCtx(sctx, backoff) {
+			if err := failed(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+		backoff = m.nextBackoff(backoff)
+	}
+}
+
+func (m *UserStreamManager) handler() UserStreamHandler {
+	if m.Handler == nil {
+		return BaseUserStreamHandler{}
+	}
+	return m.Handler
+}
+
+// renewLoop 定时续期；交易所返回 -1125 时通过 fail 结束会话
+func (m *UserStreamManager) renewLoop(ctx context.Context, key string, fail func(error)) {
+	ticker := time.NewTicker(m.RenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := m.renew(key)
+			if IsAPIErrorCode(err, CodeListenKeyInvalid) {
+				fail(err)
+				return
+			}
+		}
+	}
+}
+
+func (m *UserStreamManager) renew(key string) error {
+	err := m.client.RenewListenKey(key)
+	m.notifyListenKey(err)
+	if err != nil {
+		userStreamLog.Warn("ListenKey 续期失败", logging.Err(err))
+	} else {
+		userStreamLog.Debug("ListenKey 续期成功")
+	}
+	return err
+}
+
+func (m *UserStreamManager) nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > m.MaxBackoff {
+		d = m.MaxBackoff
+	}
+	return d
+}
+
+// sleepCtx 等待 d，ctx 先取消时返回 false
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,206 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeUserStream 模拟 listenKey REST 接口与私有流 WebSocket
+type fakeUserStream struct {
+	t        *testing.T
+	mu       sync.Mutex
+	created  int
+	failPOST int             // 前 N 次创建返回 500
+	invalid  map[string]bool // 续期时返回 -1125 的 listenKey
+	closed   []string
+	conns    chan string // 每次 WebSocket 连接的 listenKey
+	onConn   func(key string, conn *websocket.Conn)
+}
+
+func newFakeUserStream(t *testing.T) *fakeUserStream {
+	return &fakeUserStream{t: t, invalid: make(map[string]bool), conns: make(chan string, 16)}
+}
+
+func (f *fakeUserStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/ws/") {
+		key := strings.TrimPrefix(r.URL.Path, "/ws/")
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			f.t.Errorf("upgrade: %v", err)
+			return
+		}
+		f.conns <- key
+		if f.onConn != nil {
+			f.onConn(key, conn)
+		}
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := r.URL.Query().Get("listenKey")
+	switch r.Method {
+	case http.MethodPost:
+		if f.failPOST > 0 {
+			f.failPOST--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.created++
+		json.NewEncoder(w).Encode(map[string]string{"listenKey": fmt.Sprintf("key%d", f.created)})
+	case http.MethodPut:
+		if f.invalid[key] {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1125,"msg":"This listenKey does not exist."}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	case http.MethodDelete:
+		f.closed = append(f.closed, key)
+		w.Write([]byte(`{}`))
+	}
+}
+
+func newTestManager(srv *httptest.Server) *UserStreamManager {
+	c := NewAPIClient("key", "secret")
+	c.BaseURL = srv.URL
+	c.Retry = RetryPolicy{MaxAttempts: 1}
+	m := NewUserStreamManager(c, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/")
+	m.MinBackoff, m.MaxBackoff = 10*time.Millisecond, 20*time.Millisecond
+	return m
+}
+
+// orderUpdates 只订阅 ORDER_TRADE_UPDATE 的测试处理器
+type orderUpdates struct {
+	BaseUserStreamHandler
+	ch chan UserDataEvent
+}
+
+func (h orderUpdates) OnOrderTradeUpdate(e UserDataEvent) { h.ch <- e }
+
+func waitConn(t *testing.T, f *fakeUserStream) string {
+	t.Helper()
+	select {
+	case key := <-f.conns:
+		return key
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for user stream connection")
+		return ""
+	}
+}
+
+func TestUserStreamManager_RecreatesOnExpiry(t *testing.T) {
+	f := newFakeUserStream(t)
+	f.onConn = func(key string, conn *websocket.Conn) {
+		if key == "key1" {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"listenKeyExpired","E":1}`))
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"ORDER_TRADE_UPDATE","E":2,"o":{"s":"BTCUSDT","c":"abc"}}`))
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	m := newTestManager(srv)
+	events := make(chan UserDataEvent, 4)
+	m.Handler = orderUpdates{ch: events}
+	var connects sync.WaitGroup
+	connects.Add(2)
+	m.OnConnect = connects.Done
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { m.Run(ctx); close(done) }()
+
+	if key := waitConn(t, f); key != "key1" {
+		t.Fatalf("first connection should use key1, got %s", key)
+	}
+	if key := waitConn(t, f); key != "key2" {
+		t.Fatalf("after listenKeyExpired a new key should be created, got %s", key)
+	}
+	select {
+	case e := <-events:
+		if e.EventType != "ORDER_TRADE_UPDATE" || e.Order.ClientOrderID != "abc" {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event on the new connection was not delivered")
+	}
+	connects.Wait()
+	if m.ListenKey() != "key2" {
+		t.Errorf("ListenKey() = %q, want key2", m.ListenKey())
+	}
+
+	cancel()
+	<-done
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.closed) != 1 || f.closed[0] != "key2" {
+		t.Errorf("shutdown should close the current listenKey, closed=%v", f.closed)
+	}
+}
+
+func TestUserStreamManager_RetriesStartup(t *testing.T) {
+	f := newFakeUserStream(t)
+	f.failPOST = 2
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	m := newTestManager(srv)
+	var mu sync.Mutex
+	var results []error
+	m.OnListenKey = func(err error) {
+		mu.Lock()
+		results = append(results, err)
+		mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	if key := waitConn(t, f); key != "key1" {
+		t.Fatalf("expected key1 after retries, got %s", key)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) != 3 || results[0] == nil || results[1] == nil || results[2] != nil {
+		t.Errorf("expected two failures then success, got %v", results)
+	}
+}
+
+func TestUserStreamManager_RenewInvalidRecreates(t *testing.T) {
+	f := newFakeUserStream(t)
+	f.invalid["key1"] = true
+	f.onConn = func(key string, conn *websocket.Conn) {
+		// 保持连接直到客户端断开
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	m := newTestManager(srv)
+	m.RenewInterval = 50 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	waitConn(t, f)
+	if key := waitConn(t, f); key != "key2" {
+		t.Fatalf("renewal -1125 should recreate the listenKey, got %s", key)
+	}
+}
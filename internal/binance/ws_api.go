@@ -0,0 +1,397 @@
+package binance
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"BinanceAutoBot2/internal/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+var wsAPILog = logging.For("binance.ws_api")
+
+var (
+	// ErrWSAPIUnavailable WebSocket API 未连接 (或尚未完成登录)，请求没有发出，可直接改走 REST
+	ErrWSAPIUnavailable = errors.New("WebSocket API 未连接")
+	// ErrWSAPIDisconnected 请求已发出但连接在收到响应前断开，结果未知
+	ErrWSAPIDisconnected = errors.New("WebSocket API 连接在响应前断开")
+	// ErrWSAPITimeout 请求已发出但在超时内没有收到响应，结果未知
+	ErrWSAPITimeout = errors.New("WebSocket API 响应超时")
+)
+
+// OrderClient 下单、撤单、改单与查单；*APIClient 走 REST，*WSAPIClient 走 WebSocket API 并在断线时回退 REST
+type OrderClient interface {
+	PlaceOrder(req OrderRequest) (*OrderResponse, error)
+	CancelOrder(req CancelOrderRequest) (*OrderResponse, error)
+	ModifyOrder(req ModifyOrderRequest) (*OrderResponse, error)
+	QueryOrder(symbol, origClientOrderID string) (*OrderResponse, error)
+}
+
+// WSAPIClient 币安 WebSocket API 的常驻连接 (order.place / order.cancel / order.modify / order.status)
+// 请求按 id 与响应对应，可并发调用；省去每笔订单的 HTTPS 握手与请求头开销
+//
+// 未配置 LogonKey 时每条请求用 REST 客户端的 API Key / Secret 做 HMAC 签名；
+// 配置 Ed25519 密钥后连接建立即 session.logon，之后的请求只带时间戳
+//
+// 连接不可用时请求直接走 REST；已发出但结果未知 (断线、超时) 的下单沿用 PlaceOrder 的先查单再重发逻辑
+type WSAPIClient struct {
+	URL  string     // 如 wss://ws-fapi.binance.com/ws-fapi/v1
+	rest *APIClient // REST 回退通道，同时提供 HMAC 密钥、重试策略与查单
+
+	LogonAPIKey string             // Ed25519 API Key，为空则逐条 HMAC 签名
+	LogonKey    ed25519.PrivateKey // 与 LogonAPIKey 对应的私钥
+	Timeout     time.Duration      // 单个请求等待响应的超时，默认 5 秒
+
+	OnConnect    func()          // 连接 (及登录) 完成后回调，可为 nil
+	OnDisconnect func(err error) // 连接断开或拨号失败后回调，可为 nil
+	// OnRoundTrip 每个请求完成后回调，Method 为 "WS"，Endpoint 为 API 方法名，可为 nil
+	OnRoundTrip func(rt RoundTrip)
+
+	seq     atomic.Uint64
+	writeMu sync.Mutex // gorilla/websocket 不允许并发写
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	ready   bool // 已连接且 (如需) 已登录，可以接收业务请求
+	pending map[string]chan wsAPIResponse
+}
+
+// NewWSAPIClient 创建客户端，rest 用于签名与回退；调用 Run 后开始连接
+func NewWSAPIClient(wsURL string, rest *APIClient) *WSAPIClient {
+	return &WSAPIClient{
+		URL:     wsURL,
+		rest:    rest,
+		Timeout: 5 * time.Second,
+		pending: make(map[string]chan wsAPIResponse),
+	}
+}
+
+// LoadEd25519Key 读取 PKCS#8 PEM 格式的 Ed25519 私钥
+func LoadEd25519Key(pemBytes []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("不是 PEM 格式")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("私钥类型为 %T，需要 Ed25519", key)
+	}
+	return priv, nil
+}
+
+type wsAPIRequest struct {
+	ID     string                 `json:"id"`
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+type wsAPIResponse struct {
+	ID         string          `json:"id"`
+	Status     int             `json:"status"`
+	Result     json.RawMessage `json:"result"`
+	Error      *APIError       `json:"error"`
+	RateLimits []struct {
+		Type        string `json:"rateLimitType"` // REQUEST_WEIGHT / ORDERS
+		Interval    string `json:"interval"`
+		IntervalNum int    `json:"intervalNum"`
+		Count       int    `json:"count"`
+	} `json:"rateLimits"`
+
+	err error // 非交易所响应：连接断开
+}
+
+// Connected 当前能否通过 WebSocket API 发送请求
+func (c *WSAPIClient) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ready
+}
+
+// Run 保持连接直到 ctx 取消，断线后按指数退避重连
+func (c *WSAPIClient) Run(ctx context.Context) {
+	backoff := time.Second
+	const maxBackoff = 30 * time.Second
+	for {
+		ready, err := c.connectAndServe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if ready {
+			backoff = time.Second // 连接成功后重置退避时间
+		}
+		wsAPILog.Warn("WebSocket API 断开，期间下单走 REST", logging.Err(err), "backoff", backoff)
+		if c.OnDisconnect != nil {
+			c.OnDisconnect(err)
+		}
+		if !sleepCtx(ctx, backoff) {
+			return
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connectAndServe 建立连接并服务到断开；返回的 bool 表示本次连接是否曾进入可下单状态
+func (c *WSAPIClient) connectAndServe(ctx context.Context) (bool, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, c.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+
+	readErr := make(chan error, 1)
+	go func() { readErr <- c.readLoop(conn) }()
+
+	if c.LogonKey != nil {
+		if _, err := c.roundTrip(conn, "session.logon", c.logonParams()); err != nil {
+			conn.Close()
+			<-readErr
+			return false, fmt.Errorf("session.logon 失败: %w", err)
+		}
+	}
+	c.mu.Lock()
+	if c.conn != conn {
+		// readLoop 已经退出并清空了连接 (如登录后立即被服务端断开)，不能把 ready 置回 true
+		c.mu.Unlock()
+		return false, <-readErr
+	}
+	c.ready = true
+	c.mu.Unlock()
+	wsAPILog.Info("WebSocket API 已连接", "url", c.URL, "logon", c.LogonKey != nil)
+	if c.OnConnect != nil {
+		c.OnConnect()
+	}
+
+	select {
+	case <-ctx.Done():
+		c.writeMu.Lock()
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "System shutting down"))
+		c.writeMu.Unlock()
+		conn.Close()
+		return true, <-readErr
+	case err := <-readErr:
+		return true, err
+	}
+}
+
+// readLoop 按 id 把响应交给等待中的请求；连接断开时让全部未完成请求以 ErrWSAPIDisconnected 返回
+func (c *WSAPIClient) readLoop(conn *websocket.Conn) error {
+	defer func() {
+		conn.Close()
+		c.mu.Lock()
+		c.conn, c.ready = nil, false
+		for id, ch := range c.pending {
+			ch <- wsAPIResponse{err: ErrWSAPIDisconnected}
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var resp wsAPIResponse
+		if err := json.Unmarshal(message, &resp); err != nil {
+			wsAPILog.Error("WebSocket API 响应解析失败", logging.Err(err), "raw", string(message))
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		} else {
+			wsAPILog.Debug("收到无人等待的响应 (可能已超时)", "id", resp.ID)
+		}
+	}
+}
+
+// call 发送一个业务请求；连接不可用时返回 ErrWSAPIUnavailable
+func (c *WSAPIClient) call(method string, params url.Values) ([]byte, error) {
+	c.mu.Lock()
+	conn, ready := c.conn, c.ready
+	c.mu.Unlock()
+	if !ready || conn == nil {
+		return nil, ErrWSAPIUnavailable
+	}
+	return c.roundTrip(conn, method, c.requestParams(params))
+}
+
+// roundTrip 在 conn 上发送请求并等待对应 id 的响应；conn 已不是当前连接 (readLoop 已退出) 时
+// 请求不发出，返回 ErrWSAPIUnavailable 让调用方改走 REST
+func (c *WSAPIClient) roundTrip(conn *websocket.Conn, method string, params map[string]interface{}) (body []byte, err error) {
+	id := strconv.FormatUint(c.seq.Add(1), 10)
+	ch := make(chan wsAPIResponse, 1)
+	c.mu.Lock()
+	if conn == nil || c.conn != conn {
+		c.mu.Unlock()
+		return nil, ErrWSAPIUnavailable
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	start := time.Now()
+	status, usedWeight, orderCount := 0, -1, -1
+	if c.OnRoundTrip != nil {
+		defer func() {
+			c.OnRoundTrip(RoundTrip{Method: "WS", Endpoint: method, Duration: time.Since(start), Status: status, Err: err,
+				UsedWeight1m: usedWeight, OrderCount1m: orderCount})
+		}()
+	}
+
+	c.writeMu.Lock()
+	err = conn.WriteJSON(wsAPIRequest{ID: id, Method: method, Params: params})
+	c.writeMu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.Timeout)
+	defer timer.Stop()
+	var resp wsAPIResponse
+	select {
+	case resp = <-ch:
+	case <-timer.C:
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		err = ErrWSAPITimeout
+		return nil, err
+	}
+	if resp.err != nil {
+		err = resp.err
+		return nil, err
+	}
+
+	status = resp.Status
+	for _, rl := range resp.RateLimits {
+		if rl.Interval != "MINUTE" || rl.IntervalNum != 1 {
+			continue
+		}
+		switch rl.Type {
+		case "REQUEST_WEIGHT":
+			usedWeight = rl.Count
+		case "ORDERS":
+			orderCount = rl.Count
+		}
+	}
+	if resp.Status != http.StatusOK {
+		apiErr := &APIError{HTTPStatus: resp.Status}
+		if resp.Error != nil {
+			apiErr.Code, apiErr.Msg = resp.Error.Code, resp.Error.Msg
+		}
+		err = apiErr
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// requestParams 业务请求参数：已登录时只需时间戳，否则附带 apiKey 并做 HMAC 签名
+func (c *WSAPIClient) requestParams(params url.Values) map[string]interface{} {
+	q := url.Values{}
+	for k, v := range params {
+		q[k] = v
+	}
+	q.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	q.Set("recvWindow", "5000")
+	if c.LogonKey == nil {
+		q.Set("apiKey", c.rest.APIKey)
+		// 签名载荷与 REST 相同：按 key 排序的 query string
+		q.Set("signature", c.rest.createSignature(q.Encode()))
+	}
+	return flattenParams(q)
+}
+
+// logonParams session.logon 参数，Ed25519 签名后 base64 编码
+func (c *WSAPIClient) logonParams() map[string]interface{} {
+	q := url.Values{}
+	q.Set("apiKey", c.LogonAPIKey)
+	q.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	q.Set("signature", base64.StdEncoding.EncodeToString(ed25519.Sign(c.LogonKey, []byte(q.Encode()))))
+	return flattenParams(q)
+}
+
+func flattenParams(q url.Values) map[string]interface{} {
+	out := make(map[string]interface{}, len(q))
+	for k, v := range q {
+		if len(v) > 0 {
+			out[k] = v[0]
+		}
+	}
+	return out
+}
+
+// send 优先走 WebSocket API，未连接时改走 REST；已发出的请求不会再经 REST 重复发送
+func (c *WSAPIClient) send(method, restMethod string, params url.Values) ([]byte, error) {
+	body, err := c.call(method, params)
+	if errors.Is(err, ErrWSAPIUnavailable) {
+		return c.rest.signedRequest(restMethod, "/fapi/v1/order", params)
+	}
+	return body, err
+}
+
+// PlaceOrder order.place；重试与结果未知时的查单逻辑与 APIClient.PlaceOrder 相同
+func (c *WSAPIClient) PlaceOrder(req OrderRequest) (*OrderResponse, error) {
+	return c.rest.placeOrder(req, func(params url.Values) ([]byte, error) {
+		return c.send("order.place", http.MethodPost, params)
+	})
+}
+
+// CancelOrder order.cancel
+func (c *WSAPIClient) CancelOrder(req CancelOrderRequest) (*OrderResponse, error) {
+	return c.orderCall(false, "order.cancel", http.MethodDelete, req.params())
+}
+
+// ModifyOrder order.modify
+func (c *WSAPIClient) ModifyOrder(req ModifyOrderRequest) (*OrderResponse, error) {
+	return c.orderCall(false, "order.modify", http.MethodPut, req.params())
+}
+
+// QueryOrder order.status
+func (c *WSAPIClient) QueryOrder(symbol, origClientOrderID string) (*OrderResponse, error) {
+	params := url.Values{}
+	params.Add("symbol", symbol)
+	params.Add("origClientOrderId", origClientOrderID)
+	return c.orderCall(true, "order.status", http.MethodGet, params)
+}
+
+func (c *WSAPIClient) orderCall(idempotent bool, method, restMethod string, params url.Values) (*OrderResponse, error) {
+	body, err := c.rest.withRetry(idempotent, func() ([]byte, error) {
+		return c.send(method, restMethod, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var order OrderResponse
+	if err := decodeJSON(body, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
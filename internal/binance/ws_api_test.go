@@ -0,0 +1,271 @@
+package binance
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeWSAPI 模拟 WebSocket API：handle 返回 nil 时直接断开连接
+type fakeWSAPI struct {
+	mu       sync.Mutex
+	requests []wsAPIRequest
+	handle   func(req wsAPIRequest) map[string]interface{}
+}
+
+func (f *fakeWSAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		var req wsAPIRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.mu.Unlock()
+		resp := f.handle(req)
+		if resp == nil {
+			return
+		}
+		resp["id"] = req.ID
+		conn.WriteJSON(resp)
+	}
+}
+
+func startWSAPI(t *testing.T, f *fakeWSAPI, rest *APIClient, setup func(*WSAPIClient)) (*WSAPIClient, context.CancelFunc) {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	c := NewWSAPIClient("ws"+strings.TrimPrefix(srv.URL, "http"), rest)
+	if setup != nil {
+		setup(c)
+	}
+	connected := make(chan struct{}, 1)
+	c.OnConnect = func() { connected <- struct{}{} }
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.Run(ctx)
+	select {
+	case <-connected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("WebSocket API did not connect")
+	}
+	return c, cancel
+}
+
+// hmacPayload 按 key 排序重建签名载荷 (不含 signature)
+func hmacPayload(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "signature" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	q := url.Values{}
+	for _, k := range keys {
+		q.Set(k, params[k].(string))
+	}
+	return q.Encode()
+}
+
+func TestWSAPI_PlaceOrderSignedRequest(t *testing.T) {
+	f := &fakeWSAPI{handle: func(req wsAPIRequest) map[string]interface{} {
+		return map[string]interface{}{
+			"status": 200,
+			"result": map[string]interface{}{"orderId": 42, "clientOrderId": req.Params["newClientOrderId"], "status": "NEW", "price": "100.00"},
+			"rateLimits": []map[string]interface{}{
+				{"rateLimitType": "REQUEST_WEIGHT", "interval": "MINUTE", "intervalNum": 1, "count": 7},
+				{"rateLimitType": "ORDERS", "interval": "MINUTE", "intervalNum": 1, "count": 3},
+			},
+		}
+	}}
+	rest := NewAPIClient("key", "secret")
+	var rts []RoundTrip
+	c, cancel := startWSAPI(t, f, rest, func(c *WSAPIClient) {
+		c.OnRoundTrip = func(rt RoundTrip) { rts = append(rts, rt) }
+	})
+	defer cancel()
+
+	order, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.01, Price: 100, NewClientOrderID: "cid1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.OrderID != 42 || order.ClientOrderID != "cid1" || order.Price != 100 {
+		t.Errorf("unexpected order: %+v", order)
+	}
+
+	req := f.requests[0]
+	if req.Method != "order.place" || req.Params["apiKey"] != "key" || req.Params["type"] != "LIMIT" || req.Params["timeInForce"] != "GTC" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if want := rest.createSignature(hmacPayload(req.Params)); req.Params["signature"] != want {
+		t.Errorf("signature mismatch: got %v want %s", req.Params["signature"], want)
+	}
+	if len(rts) != 1 || rts[0].Method != "WS" || rts[0].Endpoint != "order.place" || rts[0].Status != 200 ||
+		rts[0].UsedWeight1m != 7 || rts[0].OrderCount1m != 3 {
+		t.Errorf("unexpected round trip: %+v", rts)
+	}
+}
+
+func TestWSAPI_ErrorResponseIsAPIError(t *testing.T) {
+	f := &fakeWSAPI{handle: func(req wsAPIRequest) map[string]interface{} {
+		return map[string]interface{}{"status": 400, "error": map[string]interface{}{"code": -2019, "msg": "Margin is insufficient."}}
+	}}
+	c, cancel := startWSAPI(t, f, NewAPIClient("key", "secret"), nil)
+	defer cancel()
+
+	_, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, Price: 1})
+	if !IsAPIErrorCode(err, CodeMarginNotEnough) || ClassifyError(err) != CategoryNonRetryable {
+		t.Fatalf("expected non-retryable -2019, got %v", err)
+	}
+	if len(f.requests) != 1 {
+		t.Errorf("rejected order must not be resent, got %d requests", len(f.requests))
+	}
+}
+
+func TestWSAPI_FallsBackToRESTWhenDisconnected(t *testing.T) {
+	var restCalls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restCalls = append(restCalls, r.Method+" "+r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{"orderId": 7, "clientOrderId": r.URL.Query().Get("origClientOrderId"), "status": "CANCELED"})
+	}))
+	defer srv.Close()
+	rest := NewAPIClient("key", "secret")
+	rest.BaseURL = srv.URL
+
+	c := NewWSAPIClient("ws://127.0.0.1:1/unused", rest) // 未调用 Run，始终未连接
+	order, err := c.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: "cid1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.Status != "CANCELED" || len(restCalls) != 1 || restCalls[0] != "DELETE /fapi/v1/order" {
+		t.Errorf("expected a REST cancel, got order=%+v calls=%v", order, restCalls)
+	}
+
+	// ready 残留而连接已被 readLoop 清空时同样走 REST，不能在空连接上写入
+	c.mu.Lock()
+	c.ready = true
+	c.mu.Unlock()
+	if _, err := c.CancelOrder(CancelOrderRequest{Symbol: "BTCUSDT", OrigClientOrderID: "cid2"}); err != nil || len(restCalls) != 2 {
+		t.Errorf("stale ready flag should fall back to REST, got err=%v calls=%v", err, restCalls)
+	}
+}
+
+func TestWSAPI_ImmediateDisconnectNeverReportsReady(t *testing.T) {
+	// 服务端完成握手后立即断开：readLoop 可能先于 connectAndServe 置位 ready 退出
+	accepted := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+		select {
+		case accepted <- struct{}{}:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	c := NewWSAPIClient("ws"+strings.TrimPrefix(srv.URL, "http"), NewAPIClient("key", "secret"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	disconnected := make(chan struct{}, 1)
+	c.OnDisconnect = func(error) {
+		select {
+		case disconnected <- struct{}{}:
+		default:
+		}
+	}
+	go c.Run(ctx)
+	<-accepted
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("client should notice the disconnect")
+	}
+	// 退避期间既不能报告已连接，也不能在空连接上发送
+	if c.Connected() {
+		t.Error("client should not report ready after the connection dropped")
+	}
+	if _, err := c.call("order.status", url.Values{}); err != ErrWSAPIUnavailable {
+		t.Errorf("expected ErrWSAPIUnavailable during backoff, got %v", err)
+	}
+}
+
+func TestWSAPI_DisconnectDuringPlaceQueriesBeforeResend(t *testing.T) {
+	f := &fakeWSAPI{handle: func(req wsAPIRequest) map[string]interface{} { return nil }} // 收到请求即断开
+	var restCalls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restCalls = append(restCalls, r.Method+" "+r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{"orderId": 9, "clientOrderId": "cid1", "status": "NEW"})
+	}))
+	defer srv.Close()
+	rest := NewAPIClient("key", "secret")
+	rest.BaseURL = srv.URL
+
+	c, cancel := startWSAPI(t, f, rest, nil)
+	defer cancel()
+
+	order, err := c.PlaceOrder(OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, Price: 1, NewClientOrderID: "cid1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 断线后结果未知：先经 REST 按 clientOrderId 查到订单，不再重复下单
+	if order.OrderID != 9 || len(restCalls) != 1 || restCalls[0] != "GET /fapi/v1/order" {
+		t.Errorf("expected a single REST query, got order=%+v calls=%v", order, restCalls)
+	}
+}
+
+func TestWSAPI_SessionLogon(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	key, err := LoadEd25519Key(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("LoadEd25519Key: %v", err)
+	}
+
+	f := &fakeWSAPI{handle: func(req wsAPIRequest) map[string]interface{} {
+		if req.Method == "session.logon" {
+			sig, _ := base64.StdEncoding.DecodeString(req.Params["signature"].(string))
+			if req.Params["apiKey"] != "ed-key" || !ed25519.Verify(pub, []byte(hmacPayload(req.Params)), sig) {
+				return map[string]interface{}{"status": 401, "error": map[string]interface{}{"code": -1022, "msg": "bad signature"}}
+			}
+			return map[string]interface{}{"status": 200, "result": map[string]interface{}{"apiKey": "ed-key"}}
+		}
+		return map[string]interface{}{"status": 200, "result": map[string]interface{}{"orderId": 1, "status": "FILLED"}}
+	}}
+	c, cancel := startWSAPI(t, f, NewAPIClient("key", "secret"), func(c *WSAPIClient) {
+		c.LogonAPIKey, c.LogonKey = "ed-key", key
+	})
+	defer cancel()
+
+	if _, err := c.QueryOrder("BTCUSDT", "cid1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.requests) != 2 || f.requests[0].Method != "session.logon" || f.requests[1].Method != "order.status" {
+		t.Fatalf("expected logon then order.status, got %+v", f.requests)
+	}
+	if _, signed := f.requests[1].Params["signature"]; signed || f.requests[1].Params["timestamp"] == nil {
+		t.Errorf("requests after logon should carry only a timestamp: %+v", f.requests[1].Params)
+	}
+}
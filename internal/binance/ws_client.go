@@ -3,16 +3,27 @@ package binance
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"time"
 
+	"BinanceAutoBot2/internal/logging"
+
 	"github.com/gorilla/websocket"
 )
 
+var (
+	wsLog = logging.For("binance.ws")
+	// wsParseLog 解析失败可能逐条发生 (如交易所推送格式变更)，按消息采样
+	wsParseLog = logging.Sampled("binance.ws")
+)
+
 // WSClient 带有自动重连机制的 WebSocket 客户端
 type WSClient struct {
 	URL         string
 	OnDepthFunc func(event WSDepthEvent) // 回调函数：将网络层与业务层解耦
+	OnConnect   func()                   // 每次 (重) 连接成功后回调，可为 nil
+	// OnDisconnect 连接断开 (或拨号失败) 后回调，可为 nil
+	OnDisconnect func(err error)
 }
 
 // Start 启动客户端并阻塞运行，直到 ctx 被取消
@@ -23,12 +34,15 @@ func (c *WSClient) Start(ctx context.Context) {
 	for {
 		err := c.connectAndRead(ctx)
 		if err != nil {
-			log.Printf("[WS Client] Connection error: %v. Reconnecting in %s...", err, backoff)
+			wsLog.Warn("连接断开，稍后重连", "url", c.URL, logging.Err(err), "backoff", backoff)
+		}
+		if c.OnDisconnect != nil {
+			c.OnDisconnect(err)
 		}
 
 		select {
 		case <-ctx.Done():
-			log.Println("[WS Client] Context canceled, exiting reconnect loop.")
+			wsLog.Info("ctx 已取消，退出重连循环", "url", c.URL)
 			return
 		case <-time.After(backoff):
 			backoff *= 2
@@ -41,12 +55,15 @@ func (c *WSClient) Start(ctx context.Context) {
 }
 
 func (c *WSClient) connectAndRead(ctx context.Context) error {
-	log.Printf("[WS Client] Dialing %s", c.URL)
+	wsLog.Info("正在连接", "url", c.URL)
 	conn, _, err := websocket.DefaultDialer.Dial(c.URL, nil)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
+	if c.OnConnect != nil {
+		c.OnConnect()
+	}
 
 	// 开启一个 Goroutine 监听 ctx 的取消信号，以便优雅关闭连接
 	go func() {
@@ -59,16 +76,17 @@ func (c *WSClient) connectAndRead(ctx context.Context) error {
 		if err != nil {
 			return err // 读取失败，返回 err 触发外部的重连机制
 		}
+		receivedAt := time.Now()
 
 		var event WSDepthEvent
 		// 性能优化点：实盘中可替换为 github.com/goccy/go-json 提升解析速度
 		if err := json.Unmarshal(message, &event); err != nil {
-			log.Printf("[WS Client] JSON parse error: %v", err)
-			// 👇 新增下面这一行，把原始的 byte 数组转成 string 打印出来
-			log.Printf("[WS Client] Raw payload: %s", string(message))
+			wsParseLog.Error("深度推送解析失败", logging.Err(err), slog.String("raw", string(message)))
 			continue
 		}
 
+		event.ReceivedAt = receivedAt
+
 		// 通过回调函数将数据推给 OrderBook，网络层不关心业务逻辑
 		if c.OnDepthFunc != nil {
 			c.OnDepthFunc(event)
@@ -0,0 +1,110 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// 定长二进制布局 (小端序)：
+//
+//	偏移  长度  字段
+//	0     2     magic "OB"
+//	2     1     结构版本 (BookVersion)
+//	3     1     状态 (0 未知 / 1 SYNCED / 2 RESYNCING / 3 STALE)
+//	4     2     买盘档数 nb (uint16)
+//	6     2     卖盘档数 na (uint16)
+//	8     8     LastUpdateID (int64)
+//	16    8     Timestamp 网关发布时间，毫秒 (int64)
+//	24    8     EventTime 交易所事件时间 (int64)
+//	32    8     TransactionTime 撮合时间 (int64)
+//	40    8     ReceiveTime 网关接收时间 (int64)
+//	48    16    交易对 (ASCII，右侧补 0)
+//	64    16*nb 买盘档位：价格 float64 + 数量 float64
+//	...   16*na 卖盘档位，同上
+//
+// Python 端可直接用 struct.unpack_from("<2sBBHHqqqqq16s", buf) 解析头部
+const (
+	binaryHeaderSize = 64
+	binaryLevelSize  = 16
+	binarySymbolSize = 16
+)
+
+var binaryMagic = [2]byte{'O', 'B'}
+
+type binaryEncoder struct{}
+
+func (binaryEncoder) Format() string { return FormatBinary }
+
+func (binaryEncoder) AppendBook(dst []byte, snap *binance.OrderBookSnapshot) ([]byte, error) {
+	if len(snap.Symbol) > binarySymbolSize {
+		return dst, fmt.Errorf("codec: symbol %q exceeds %d bytes", snap.Symbol, binarySymbolSize)
+	}
+	if len(snap.Bids) > math.MaxUint16 || len(snap.Asks) > math.MaxUint16 {
+		return dst, fmt.Errorf("codec: too many levels (%d/%d)", len(snap.Bids), len(snap.Asks))
+	}
+
+	var hdr [binaryHeaderSize]byte
+	hdr[0], hdr[1] = binaryMagic[0], binaryMagic[1]
+	hdr[2] = BookVersion
+	hdr[3] = binance.BookStateCode(snap.State)
+	binary.LittleEndian.PutUint16(hdr[4:], uint16(len(snap.Bids)))
+	binary.LittleEndian.PutUint16(hdr[6:], uint16(len(snap.Asks)))
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(snap.LastUpdateID))
+	binary.LittleEndian.PutUint64(hdr[16:], uint64(snap.Timestamp))
+	binary.LittleEndian.PutUint64(hdr[24:], uint64(snap.EventTime))
+	binary.LittleEndian.PutUint64(hdr[32:], uint64(snap.TransactionTime))
+	binary.LittleEndian.PutUint64(hdr[40:], uint64(snap.ReceiveTime))
+	copy(hdr[48:48+binarySymbolSize], snap.Symbol)
+	dst = append(dst, hdr[:]...)
+
+	dst = appendLevels(dst, snap.Bids)
+	return appendLevels(dst, snap.Asks), nil
+}
+
+func appendLevels(dst []byte, levels []binance.PriceLevel) []byte {
+	for _, l := range levels {
+		dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(l.Price))
+		dst = binary.LittleEndian.AppendUint64(dst, math.Float64bits(l.Qty))
+	}
+	return dst
+}
+
+func decodeBinary(data []byte) (binance.OrderBookSnapshot, int, error) {
+	if len(data) < binaryHeaderSize {
+		return binance.OrderBookSnapshot{}, 0, fmt.Errorf("codec: binary payload too short (%d bytes)", len(data))
+	}
+	version := int(data[2])
+	nb := int(binary.LittleEndian.Uint16(data[4:]))
+	na := int(binary.LittleEndian.Uint16(data[6:]))
+	if want := binaryHeaderSize + (nb+na)*binaryLevelSize; len(data) != want {
+		return binance.OrderBookSnapshot{}, version, fmt.Errorf("codec: binary payload length %d, expected %d", len(data), want)
+	}
+
+	snap := binance.OrderBookSnapshot{
+		Symbol:          strings.TrimRight(string(data[48:48+binarySymbolSize]), "\x00"),
+		LastUpdateID:    int64(binary.LittleEndian.Uint64(data[8:])),
+		Timestamp:       int64(binary.LittleEndian.Uint64(data[16:])),
+		EventTime:       int64(binary.LittleEndian.Uint64(data[24:])),
+		TransactionTime: int64(binary.LittleEndian.Uint64(data[32:])),
+		ReceiveTime:     int64(binary.LittleEndian.Uint64(data[40:])),
+		State:           binance.BookStateFromCode(data[3]),
+	}
+	off := binaryHeaderSize
+	snap.Bids, off = readLevels(data, off, nb)
+	snap.Asks, _ = readLevels(data, off, na)
+	return snap, version, nil
+}
+
+func readLevels(data []byte, off, n int) ([]binance.PriceLevel, int) {
+	levels := make([]binance.PriceLevel, n)
+	for i := range levels {
+		levels[i].Price = math.Float64frombits(binary.LittleEndian.Uint64(data[off:]))
+		levels[i].Qty = math.Float64frombits(binary.LittleEndian.Uint64(data[off+8:]))
+		off += binaryLevelSize
+	}
+	return levels, off
+}
@@ -0,0 +1,65 @@
+// Package codec 盘口快照的线上编码：JSON、MessagePack 与定长二进制三种格式，
+// 每种格式的负载里都带结构版本，消费端可据此识别格式变化
+package codec
+
+import (
+	"errors"
+	"fmt"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// BookVersion 盘口负载结构版本，字段或布局有不兼容变化时递增
+// v2：增加交易所事件时间 E、撮合时间 T、网关接收时间 r 与数据状态 st
+const BookVersion = 2
+
+// 支持的编码格式，对应配置 redis.book_format
+const (
+	FormatJSON    = "json"
+	FormatMsgpack = "msgpack"
+	FormatBinary  = "binary"
+)
+
+// ErrUnknownFormat 负载无法识别为任何已知格式
+var ErrUnknownFormat = errors.New("codec: unknown book payload format")
+
+// BookEncoder 盘口快照编码器
+// AppendBook 把编码结果追加到 dst 后返回，调用方可复用缓冲区减少分配
+type BookEncoder interface {
+	Format() string
+	AppendBook(dst []byte, snap *binance.OrderBookSnapshot) ([]byte, error)
+}
+
+// New 按名称创建编码器，空字符串为 JSON
+func New(format string) (BookEncoder, error) {
+	switch format {
+	case "", FormatJSON:
+		return jsonEncoder{}, nil
+	case FormatMsgpack:
+		return msgpackEncoder{}, nil
+	case FormatBinary:
+		return binaryEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("codec: unsupported book format %q (json / msgpack / binary)", format)
+	}
+}
+
+// DecodeBook 根据首字节自动识别格式并解码，返回快照、格式与结构版本
+func DecodeBook(data []byte) (binance.OrderBookSnapshot, string, int, error) {
+	if len(data) == 0 {
+		return binance.OrderBookSnapshot{}, "", 0, ErrUnknownFormat
+	}
+	switch {
+	case data[0] == '{':
+		snap, v, err := decodeJSON(data)
+		return snap, FormatJSON, v, err
+	case len(data) >= 2 && data[0] == binaryMagic[0] && data[1] == binaryMagic[1]:
+		snap, v, err := decodeBinary(data)
+		return snap, FormatBinary, v, err
+	case data[0]&0xf0 == 0x80 || data[0] == 0xde:
+		snap, v, err := decodeMsgpack(data)
+		return snap, FormatMsgpack, v, err
+	default:
+		return binance.OrderBookSnapshot{}, "", 0, ErrUnknownFormat
+	}
+}
@@ -0,0 +1,147 @@
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+var allFormats = []string{FormatJSON, FormatMsgpack, FormatBinary}
+
+func sampleBook(levels int) binance.OrderBookSnapshot {
+	snap := binance.OrderBookSnapshot{Symbol: "BTCUSDT", LastUpdateID: 123456789012, Timestamp: 1700000000123,
+		EventTime: 1700000000100, TransactionTime: 1700000000098, ReceiveTime: 1700000000110, State: binance.BookStateSynced}
+	for i := 0; i < levels; i++ {
+		snap.Bids = append(snap.Bids, binance.PriceLevel{Price: 50000 - float64(i)*0.1, Qty: 0.001 * float64(i+1)})
+		snap.Asks = append(snap.Asks, binance.PriceLevel{Price: 50000.1 + float64(i)*0.1, Qty: 1.5 + float64(i)})
+	}
+	return snap
+}
+
+func TestRoundTrip_AllFormats(t *testing.T) {
+	for _, levels := range []int{0, 5, 20} {
+		want := sampleBook(levels)
+		for _, format := range allFormats {
+			enc, err := New(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := enc.AppendBook(nil, &want)
+			if err != nil {
+				t.Fatalf("%s: encode failed: %v", format, err)
+			}
+
+			got, gotFormat, version, err := DecodeBook(data)
+			if err != nil {
+				t.Fatalf("%s/%d: decode failed: %v", format, levels, err)
+			}
+			if gotFormat != format || version != BookVersion {
+				t.Errorf("%s: detected format %q version %d", format, gotFormat, version)
+			}
+			// JSON 对空切片解码为 nil，统一后再比较
+			if len(got.Bids) == 0 && len(want.Bids) == 0 {
+				got.Bids, got.Asks = want.Bids, want.Asks
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s/%d: round trip mismatch\n got %+v\nwant %+v", format, levels, got, want)
+			}
+		}
+	}
+}
+
+func TestJSON_BackwardCompatible(t *testing.T) {
+	snap := sampleBook(1)
+	data, _ := jsonEncoder{}.AppendBook(nil, &snap)
+
+	// 老消费端按原结构解析不受新增的 v 字段影响
+	var old binance.OrderBookSnapshot
+	if err := json.Unmarshal(data, &old); err != nil || old.Bids[0].Price != 50000 {
+		t.Fatalf("existing consumers should still parse the payload: %v %+v", err, old)
+	}
+	var raw map[string]interface{}
+	json.Unmarshal(data, &raw)
+	if raw["v"] != float64(BookVersion) {
+		t.Errorf("missing version tag: %s", data)
+	}
+}
+
+func TestBinary_Layout(t *testing.T) {
+	snap := sampleBook(2)
+	data, err := binaryEncoder{}.AppendBook([]byte("prefix"), &snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[len("prefix"):]
+	if len(data) != binaryHeaderSize+4*binaryLevelSize {
+		t.Fatalf("unexpected payload size %d", len(data))
+	}
+	if string(data[:2]) != "OB" || data[2] != BookVersion || data[3] != 1 || string(data[48:55]) != "BTCUSDT" {
+		t.Errorf("unexpected header: % x", data[:binaryHeaderSize])
+	}
+
+	long := binance.OrderBookSnapshot{Symbol: "THIS_SYMBOL_IS_TOO_LONG"}
+	if _, err := (binaryEncoder{}).AppendBook(nil, &long); err == nil {
+		t.Error("symbols longer than 16 bytes should be rejected")
+	}
+	if _, _, _, err := DecodeBook(data[:len(data)-1]); err == nil {
+		t.Error("truncated binary payload should fail")
+	}
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	if _, err := New("protobuf"); err == nil {
+		t.Error("unsupported format should be rejected")
+	}
+	if enc, err := New(""); err != nil || enc.Format() != FormatJSON {
+		t.Errorf("empty format should default to json, got %v %v", enc, err)
+	}
+	if _, _, _, err := DecodeBook([]byte("garbage")); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+// 基准：go test -bench=. -benchmem ./internal/codec
+// 以网关实际写入的 Top20 盘口为样本；JSONBaseline 为引入编码器之前的 json.Marshal(snap)
+func BenchmarkEncodeBook(b *testing.B) {
+	snap := sampleBook(20)
+	b.Run("JSONBaseline", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(snap); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	for _, format := range allFormats {
+		enc, _ := New(format)
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			var buf []byte
+			for i := 0; i < b.N; i++ {
+				var err error
+				if buf, err = enc.AppendBook(buf[:0], &snap); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(buf)), "payload_bytes")
+		})
+	}
+}
+
+func BenchmarkDecodeBook(b *testing.B) {
+	snap := sampleBook(20)
+	for _, format := range allFormats {
+		enc, _ := New(format)
+		data, _ := enc.AppendBook(nil, &snap)
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, _, err := DecodeBook(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package codec
+
+import (
+	"encoding/json"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// jsonBook 在原有 OrderBookSnapshot 字段基础上增加版本字段 v，旧消费端忽略即可
+type jsonBook struct {
+	Version int `json:"v"`
+	binance.OrderBookSnapshot
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Format() string { return FormatJSON }
+
+func (jsonEncoder) AppendBook(dst []byte, snap *binance.OrderBookSnapshot) ([]byte, error) {
+	data, err := json.Marshal(jsonBook{Version: BookVersion, OrderBookSnapshot: *snap})
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, data...), nil
+}
+
+func decodeJSON(data []byte) (binance.OrderBookSnapshot, int, error) {
+	var b jsonBook
+	if err := json.Unmarshal(data, &b); err != nil {
+		return binance.OrderBookSnapshot{}, 0, err
+	}
+	return b.OrderBookSnapshot, b.Version, nil
+}
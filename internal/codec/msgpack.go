@@ -0,0 +1,295 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// MessagePack 负载与 JSON 结构一致：{"v", "s", "u", "t", "E", "T", "r", "st", "b": [{"p", "q"}], "a": [...]}
+// Python 端 msgpack.unpackb(raw, raw=False) 得到的 dict 与 json.loads 相同，策略代码无需改动
+// 只用到固定的几种类型，这里手写编码避免引入反射开销
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Format() string { return FormatMsgpack }
+
+func (msgpackEncoder) AppendBook(dst []byte, snap *binance.OrderBookSnapshot) ([]byte, error) {
+	dst = append(dst, 0x8a) // fixmap，10 个字段
+	dst = mpAppendStr(dst, "v")
+	dst = mpAppendInt(dst, BookVersion)
+	dst = mpAppendStr(dst, "s")
+	dst = mpAppendStr(dst, snap.Symbol)
+	dst = mpAppendStr(dst, "u")
+	dst = mpAppendInt(dst, snap.LastUpdateID)
+	dst = mpAppendStr(dst, "t")
+	dst = mpAppendInt(dst, snap.Timestamp)
+	dst = mpAppendStr(dst, "E")
+	dst = mpAppendInt(dst, snap.EventTime)
+	dst = mpAppendStr(dst, "T")
+	dst = mpAppendInt(dst, snap.TransactionTime)
+	dst = mpAppendStr(dst, "r")
+	dst = mpAppendInt(dst, snap.ReceiveTime)
+	dst = mpAppendStr(dst, "st")
+	dst = mpAppendStr(dst, snap.State)
+	dst = mpAppendStr(dst, "b")
+	dst = mpAppendLevels(dst, snap.Bids)
+	dst = mpAppendStr(dst, "a")
+	return mpAppendLevels(dst, snap.Asks), nil
+}
+
+func mpAppendStr(dst []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		dst = append(dst, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		dst = append(dst, 0xd9, byte(n))
+	default:
+		dst = append(dst, 0xda)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(n))
+	}
+	return append(dst, s...)
+}
+
+func mpAppendInt(dst []byte, v int64) []byte {
+	if v >= 0 && v < 128 {
+		return append(dst, byte(v))
+	}
+	dst = append(dst, 0xd3)
+	return binary.BigEndian.AppendUint64(dst, uint64(v))
+}
+
+func mpAppendFloat(dst []byte, f float64) []byte {
+	dst = append(dst, 0xcb)
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(f))
+}
+
+func mpAppendLevels(dst []byte, levels []binance.PriceLevel) []byte {
+	if n := len(levels); n < 16 {
+		dst = append(dst, 0x90|byte(n))
+	} else {
+		dst = append(dst, 0xdc)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(n))
+	}
+	for _, l := range levels {
+		dst = append(dst, 0x82)
+		dst = mpAppendStr(dst, "p")
+		dst = mpAppendFloat(dst, l.Price)
+		dst = mpAppendStr(dst, "q")
+		dst = mpAppendFloat(dst, l.Qty)
+	}
+	return dst
+}
+
+var errShortMsgpack = errors.New("codec: truncated msgpack payload")
+
+// mpReader 仅支持盘口负载用到的类型 (map / array / str / int / float / nil)
+type mpReader struct {
+	data []byte
+	off  int
+}
+
+func (r *mpReader) next(n int) ([]byte, error) {
+	if r.off+n > len(r.data) {
+		return nil, errShortMsgpack
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *mpReader) length(b byte, fixMask, fixBase, code16, code32 byte) (int, error) {
+	switch {
+	case b&fixMask == fixBase:
+		return int(b &^ fixMask), nil
+	case b == code16:
+		p, err := r.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint16(p)), nil
+	case b == code32:
+		p, err := r.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.BigEndian.Uint32(p)), nil
+	}
+	return 0, fmt.Errorf("codec: unexpected msgpack type 0x%02x", b)
+}
+
+func (r *mpReader) mapLen() (int, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return r.length(b[0], 0xf0, 0x80, 0xde, 0xdf)
+}
+
+func (r *mpReader) arrayLen() (int, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return r.length(b[0], 0xf0, 0x90, 0xdc, 0xdd)
+}
+
+func (r *mpReader) str() (string, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return "", err
+	}
+	var n int
+	if b[0] == 0xd9 {
+		p, err := r.next(1)
+		if err != nil {
+			return "", err
+		}
+		n = int(p[0])
+	} else if n, err = r.length(b[0], 0xe0, 0xa0, 0xda, 0xdb); err != nil {
+		return "", err
+	}
+	p, err := r.next(n)
+	return string(p), err
+}
+
+// number 读取整数或浮点数，统一返回 float64 与 int64 两种表示
+func (r *mpReader) number() (float64, int64, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return float64(c), int64(c), nil
+	case c >= 0xe0:
+		v := int64(int8(c))
+		return float64(v), v, nil
+	case c == 0xc0:
+		return 0, 0, nil
+	}
+
+	var size int
+	switch c {
+	case 0xcc, 0xd0:
+		size = 1
+	case 0xcd, 0xd1:
+		size = 2
+	case 0xca, 0xce, 0xd2:
+		size = 4
+	case 0xcb, 0xcf, 0xd3:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("codec: unexpected msgpack number type 0x%02x", c)
+	}
+	p, err := r.next(size)
+	if err != nil {
+		return 0, 0, err
+	}
+	var u uint64
+	for _, x := range p {
+		u = u<<8 | uint64(x)
+	}
+	var v int64
+	switch c {
+	case 0xca:
+		f := float64(math.Float32frombits(uint32(u)))
+		return f, int64(f), nil
+	case 0xcb:
+		f := math.Float64frombits(u)
+		return f, int64(f), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v = int64(u)
+	case 0xd0:
+		v = int64(int8(u))
+	case 0xd1:
+		v = int64(int16(u))
+	case 0xd2:
+		v = int64(int32(u))
+	case 0xd3:
+		v = int64(u)
+	}
+	return float64(v), v, nil
+}
+
+func (r *mpReader) levels() ([]binance.PriceLevel, error) {
+	n, err := r.arrayLen()
+	if err != nil {
+		return nil, err
+	}
+	levels := make([]binance.PriceLevel, n)
+	for i := range levels {
+		fields, err := r.mapLen()
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < fields; j++ {
+			key, err := r.str()
+			if err != nil {
+				return nil, err
+			}
+			f, _, err := r.number()
+			if err != nil {
+				return nil, err
+			}
+			switch key {
+			case "p":
+				levels[i].Price = f
+			case "q":
+				levels[i].Qty = f
+			}
+		}
+	}
+	return levels, nil
+}
+
+func decodeMsgpack(data []byte) (binance.OrderBookSnapshot, int, error) {
+	var snap binance.OrderBookSnapshot
+	var version int
+	r := &mpReader{data: data}
+	fields, err := r.mapLen()
+	if err != nil {
+		return snap, 0, err
+	}
+	for i := 0; i < fields; i++ {
+		key, err := r.str()
+		if err != nil {
+			return snap, version, err
+		}
+		switch key {
+		case "s":
+			snap.Symbol, err = r.str()
+		case "st":
+			snap.State, err = r.str()
+		case "b":
+			snap.Bids, err = r.levels()
+		case "a":
+			snap.Asks, err = r.levels()
+		default:
+			var v int64
+			_, v, err = r.number()
+			switch key {
+			case "v":
+				version = int(v)
+			case "u":
+				snap.LastUpdateID = v
+			case "t":
+				snap.Timestamp = v
+			case "E":
+				snap.EventTime = v
+			case "T":
+				snap.TransactionTime = v
+			case "r":
+				snap.ReceiveTime = v
+			}
+		}
+		if err != nil {
+			return snap, version, err
+		}
+	}
+	return snap, version, nil
+}
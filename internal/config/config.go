@@ -2,33 +2,255 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 )
 
 type Config struct {
 	Binance BinanceRouter `json:"binance"`
 	Redis   RedisConfig   `json:"redis"`
+	Ledger  LedgerConfig  `json:"ledger"`
+	// SharedMemory 同机低延迟盘口：TopN 写入 /dev/shm 内存映射文件 (与 Redis 并行)
+	SharedMemory SharedMemoryConfig `json:"shared_memory"`
+	// MarketData 行情数据质量：停滞检测与 Key 过期
+	MarketData MarketDataConfig `json:"market_data"`
+	// Metrics Prometheus 指标的独立监听地址
+	Metrics MetricsConfig `json:"metrics"`
+	// Health /healthz 与 /readyz 的额外监听地址 (UDS 通道上始终提供)
+	Health HealthConfig `json:"health"`
+	// Log 日志级别、格式与采样 (网关与 internal/binance)
+	Log LogConfig `json:"log"`
+	// Accounts 多账户模式：每个命名账户 (通常是子账户) 有独立的 Key、环境与交易对，
+	// UDS 请求必须声明 account；留空为单账户模式，使用 active_env 的 Key 与 binance.symbols
+	Accounts []AccountConfig `json:"accounts"`
+	// Strategies 策略注册表：非空时 UDS 下单 / 改单 / 撤单必须声明 strategy，并按各策略的限额在网关内拦截
+	Strategies []StrategyConfig `json:"strategies"`
+	// UDS 交易通道的对端身份校验与审计日志
+	UDS UDSConfig `json:"uds"`
+}
+
+// UDSConfig UDS 交易通道访问控制
+type UDSConfig struct {
+	AllowedUIDs []uint32 `json:"allowed_uids"` // 允许连接的进程 UID (SO_PEERCRED)，留空只允许与网关相同的 UID
+	// Group socket 文件的属组 (组名或 GID)：allowed_uids 包含网关以外的 UID 时必填，socket 权限为 0660，
+	// 其他 UID 需加入该组才能连接
+	Group string `json:"group"`
+	// RequireAuth 为 true 时下单类请求必须声明配置了 token / hmac_secret 的策略并通过鉴权
+	RequireAuth  bool   `json:"require_auth"`
+	AuditLogPath string `json:"audit_log_path"` // 下单类请求的审计日志 (JSON Lines)，留空则写入 audit 组件日志
+}
+
+// StrategyConfig 一个策略的身份与限额，限额为 0 或留空表示不限制
+type StrategyConfig struct {
+	Name        string   `json:"name"`          // 同时作为 clientOrderId 前缀 "<name>_"，只允许字母、数字、- 和 _，最长 16 字符
+	MaxPosition float64  `json:"max_position"`  // 单个账户、单个交易对的净持仓上限 (数量，含未成交挂单)
+	MaxNotional float64  `json:"max_notional"`  // 单笔订单名义价值上限 (USDT)
+	Symbols     []string `json:"symbols"`       // 允许交易的交易对
+	Accounts    []string `json:"accounts"`      // 允许操作的账户 (单账户模式下为 default)
+	MaxOrders1m int      `json:"max_orders_1m"` // 一分钟内下单与改单次数上限
+	// Token / HMACSecret UDS 请求鉴权凭证 (二选一，留空不鉴权)，可由环境变量
+	// BINANCE_STRATEGY_<NAME>_TOKEN / _HMAC_SECRET 覆盖
+	Token      string `json:"token"`
+	HMACSecret string `json:"hmac_secret"`
+}
+
+// maxStrategyNameLen clientOrderId 上限 36 字符，网关生成的 ID 为 "<name>_<19 位纳秒时间戳>"
+const maxStrategyNameLen = 16
+
+// DefaultAccountName 单账户模式下隐含账户的名称
+const DefaultAccountName = "default"
+
+// AccountConfig 一个命名交易账户；api_key / api_secret 可由环境变量
+// BINANCE_ACCOUNT_<NAME>_API_KEY / _API_SECRET 覆盖，附加 Key 为 BINANCE_ACCOUNT_<NAME>_KEY_<KEY>_API_KEY / _API_SECRET
+type AccountConfig struct {
+	Name      string                  `json:"name"` // 只允许字母、数字、- 和 _，用作 Redis Key 前缀
+	Env       string                  `json:"env"`  // mainnet / testnet，留空跟随 active_env；地址取 binance 下对应环境
+	APIKey    string                  `json:"api_key"`
+	APISecret string                  `json:"api_secret"`
+	Keys      []APIKeyConfig          `json:"keys"`
+	Symbols   map[string]SymbolConfig `json:"symbols"` // 该账户允许交易的交易对及其杠杆与保证金模式，不能为空
+	// WSAPILogonKey / WSAPIPrivateKeyPath 主 Key 的 WebSocket API 登录 Key，含义同 EnvConfig
+	WSAPILogonKey       string `json:"ws_api_logon_key"`
+	WSAPIPrivateKeyPath string `json:"ws_api_private_key_path"`
+}
+
+// TradingAccount 解析后的账户：Env 为所在环境的地址配上该账户自己的 Key
+type TradingAccount struct {
+	Name    string
+	EnvName string
+	Env     EnvConfig
+	Symbols map[string]SymbolConfig
+	// ApplySettings 取自 binance.apply_symbol_settings
+	ApplySettings bool
+}
+
+// MultiAccount 是否配置了命名账户
+func (c *Config) MultiAccount() bool { return len(c.Accounts) > 0 }
+
+// TradingAccounts 返回全部交易账户；单账户模式下只有一个名为 default 的账户
+func (c *Config) TradingAccounts() []TradingAccount {
+	if !c.MultiAccount() {
+		return []TradingAccount{{
+			Name:          DefaultAccountName,
+			EnvName:       c.Binance.activeEnvName(),
+			Env:           c.Binance.GetActiveEnv(),
+			Symbols:       c.Binance.Symbols,
+			ApplySettings: c.Binance.ApplySymbolSettings,
+		}}
+	}
+	accounts := make([]TradingAccount, 0, len(c.Accounts))
+	for _, a := range c.Accounts {
+		envName := a.Env
+		if envName == "" {
+			envName = c.Binance.activeEnvName()
+		}
+		env := c.Binance.Testnet
+		if envName == "mainnet" {
+			env = c.Binance.Mainnet
+		}
+		env.APIKey, env.APISecret, env.Keys = a.APIKey, a.APISecret, a.Keys
+		env.WSAPILogonKey, env.WSAPIPrivateKeyPath = a.WSAPILogonKey, a.WSAPIPrivateKeyPath
+		accounts = append(accounts, TradingAccount{Name: a.Name, EnvName: envName, Env: env, Symbols: a.Symbols,
+			ApplySettings: c.Binance.ApplySymbolSettings})
+	}
+	return accounts
 }
 
 // BinanceRouter 负责路由当前激活的环境
 type BinanceRouter struct {
-	ActiveEnv string    `json:"active_env"`
-	Symbol    string    `json:"symbol"`
-	Mainnet   EnvConfig `json:"mainnet"`
-	Testnet   EnvConfig `json:"testnet"`
+	ActiveEnv string                  `json:"active_env"`
+	Symbol    string                  `json:"symbol"`
+	Symbols   map[string]SymbolConfig `json:"symbols"` // 按交易对声明的杠杆与保证金模式，网关启动时校验，不一致则拒绝启动
+	// ApplySymbolSettings 为 true 时启动时把不一致的杠杆 / 保证金模式改成配置值 (会改变账户的风险设置，需显式开启)
+	ApplySymbolSettings bool      `json:"apply_symbol_settings"`
+	Mainnet             EnvConfig `json:"mainnet"`
+	Testnet             EnvConfig `json:"testnet"`
+}
+
+// SymbolConfig 单个交易对的账户级设置
+type SymbolConfig struct {
+	Leverage   int    `json:"leverage"`    // 开仓杠杆，0 表示不做约束
+	MarginType string `json:"margin_type"` // "ISOLATED" 或 "CROSSED"，留空表示不做约束
 }
 
 // EnvConfig 具体的环境配置参数
 type EnvConfig struct {
-	APIKey      string `json:"api_key"`
-	APISecret   string `json:"api_secret"`
-	RestBaseURL string `json:"rest_base_url"`
-	WSDepthURL  string `json:"ws_depth_url"`
+	APIKey         string `json:"api_key"`
+	APISecret      string `json:"api_secret"`
+	RestBaseURL    string `json:"rest_base_url"`
+	WSDepthURL     string `json:"ws_depth_url"`
+	WSMarkPriceURL string `json:"ws_mark_price_url"` // 标记价格推送，留空则不计算未实现盈亏
+	// WSAPIURL 币安 WebSocket API 地址，非空时下单 / 撤单 / 改单 / 查单走常驻连接，断线期间回退 REST；留空则只用 REST
+	WSAPIURL string `json:"ws_api_url"`
+	// WSAPILogonKey / WSAPIPrivateKeyPath Ed25519 API Key 与私钥 (PKCS#8 PEM) 文件，配置后连接时 session.logon，
+	// 之后请求不再逐条签名；留空则每条请求用 api_key / api_secret 做 HMAC 签名
+	WSAPILogonKey       string `json:"ws_api_logon_key"`
+	WSAPIPrivateKeyPath string `json:"ws_api_private_key_path"`
+	// OrderLimit1m 账户一分钟内的下单额度 (下单与改单)，所有下单 Key 共享，0 表示默认 1200
+	OrderLimit1m int `json:"order_limit_1m"`
+	// Keys 同一账户的附加 API Key (子账户请配置为 Accounts 中的独立账户)，与 api_key / api_secret 一起按角色分担请求；留空则只用主 Key
+	Keys []APIKeyConfig `json:"keys"`
+}
+
+// API Key 角色
+const (
+	KeyRoleAll   = "all"   // 下单与只读请求都可使用 (默认)
+	KeyRoleOrder = "order" // 只承担下单 / 撤单 / 改单 / 查单
+	KeyRoleRead  = "read"  // 只承担账户查询、ListenKey 与对账等只读请求
+)
+
+// PrimaryKeyName 主 Key (EnvConfig.APIKey) 在 Key 列表中的名称
+const PrimaryKeyName = "primary"
+
+// APIKeyConfig 单个 API Key；api_key / api_secret 可由环境变量 BINANCE_<ENV>_KEY_<NAME>_API_KEY / _API_SECRET 覆盖
+type APIKeyConfig struct {
+	Name      string `json:"name"`
+	APIKey    string `json:"api_key"`
+	APISecret string `json:"api_secret"`
+	Role      string `json:"role"` // all (默认) / order / read
+	// WSAPILogonKey / WSAPIPrivateKeyPath 该 Key 的 WebSocket API 连接使用的 Ed25519 登录 Key，含义同 EnvConfig
+	WSAPILogonKey       string `json:"ws_api_logon_key"`
+	WSAPIPrivateKeyPath string `json:"ws_api_private_key_path"`
+}
+
+// CanOrder / CanRead 判断 Key 是否承担对应类型的请求
+func (k APIKeyConfig) CanOrder() bool { return k.Role != KeyRoleRead }
+
+func (k APIKeyConfig) CanRead() bool { return k.Role != KeyRoleOrder }
+
+// APIKeys 返回该环境的全部 API Key：主 Key (api_key 非空时，角色为 all) 在前，随后是 keys
+func (e EnvConfig) APIKeys() []APIKeyConfig {
+	var keys []APIKeyConfig
+	if e.APIKey != "" {
+		keys = append(keys, APIKeyConfig{
+			Name:                PrimaryKeyName,
+			APIKey:              e.APIKey,
+			APISecret:           e.APISecret,
+			Role:                KeyRoleAll,
+			WSAPILogonKey:       e.WSAPILogonKey,
+			WSAPIPrivateKeyPath: e.WSAPIPrivateKeyPath,
+		})
+	}
+	for _, k := range e.Keys {
+		if k.Role == "" {
+			k.Role = KeyRoleAll
+		}
+		keys = append(keys, k)
+	}
+	return keys
 }
 
 type RedisConfig struct {
-	Addr string `json:"addr"`
-	DB   int    `json:"db"`
+	Addr     string        `json:"addr"`
+	DB       int           `json:"db"`
+	Optional bool          `json:"optional"` // true 时 Redis 不可用也继续启动 (单机部署只用 UDS 订阅)，写入失败直接丢弃
+	Streams  StreamsConfig `json:"streams"`
+	// BookFormat 盘口快照 (OrderBook:<SYM> 与 Stream:Book:<SYM>) 的编码：json (默认) / msgpack / binary
+	BookFormat string `json:"book_format"`
+}
+
+// StreamsConfig 事件推送到 Redis Streams (行情、订单、成交、账户)
+type StreamsConfig struct {
+	Enabled bool  `json:"enabled"`
+	MaxLen  int64 `json:"max_len"` // 每个 Stream 保留的近似最大条数，0 表示默认 10000
+}
+
+// LedgerConfig 持久化交易账本
+type LedgerConfig struct {
+	Path string `json:"path"` // bbolt 文件路径，留空则不记录
+}
+
+// SharedMemoryConfig 共享内存盘口，布局见 internal/shmbook
+type SharedMemoryConfig struct {
+	Enabled bool   `json:"enabled"`
+	Dir     string `json:"dir"`   // 留空为 /dev/shm
+	Depth   int    `json:"depth"` // 每侧档数，0 表示默认 20；网关每次只取 Top20，超出部分为空
+}
+
+// MarketDataConfig 行情停滞检测，盘口快照的 st 字段据此标记为 STALE
+type MarketDataConfig struct {
+	StaleAfterMs  int  `json:"stale_after_ms"`  // 超过该时长未收到深度推送即标记 STALE，0 表示默认 5000
+	DeleteOnStale bool `json:"delete_on_stale"` // 标记 STALE 时删除 OrderBook:<SYM> Key，而不是写入 STALE 快照
+	KeyTTLMs      int  `json:"key_ttl_ms"`      // >0 时 OrderBook:<SYM> 带过期时间写入，网关整体退出后 Key 自动消失
+}
+
+// MetricsConfig Prometheus /metrics 端点
+type MetricsConfig struct {
+	Addr string `json:"addr"` // TCP 地址 (如 127.0.0.1:9100) 或以 / 开头的 Unix Socket 路径，留空则不开启
+}
+
+// HealthConfig 健康检查端点
+type HealthConfig struct {
+	Addr string `json:"addr"` // TCP 地址或以 / 开头的 Unix Socket 路径，留空则只在 UDS 通道上提供
+}
+
+// LogConfig 结构化日志配置，组件名见 README「结构化日志」
+type LogConfig struct {
+	Level            string            `json:"level"`              // debug / info / warn / error，留空为 info
+	Format           string            `json:"format"`             // text (默认) / json
+	Components       map[string]string `json:"components"`         // 按组件覆盖级别，如 {"binance": "debug", "depth": "warn"}
+	SampleIntervalMs int               `json:"sample_interval_ms"` // 高频路径同一条日志的最短输出间隔，0 表示默认 1000
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -57,9 +279,200 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.Binance.Testnet.APISecret = v
 	}
 
+	applyKeyEnv("BINANCE_MAINNET", cfg.Binance.Mainnet.Keys)
+	applyKeyEnv("BINANCE_TESTNET", cfg.Binance.Testnet.Keys)
+	for i := range cfg.Accounts {
+		a := &cfg.Accounts[i]
+		prefix := "BINANCE_ACCOUNT_" + envName(a.Name)
+		if v := os.Getenv(prefix + "_API_KEY"); v != "" {
+			a.APIKey = v
+		}
+		if v := os.Getenv(prefix + "_API_SECRET"); v != "" {
+			a.APISecret = v
+		}
+		applyKeyEnv(prefix, a.Keys)
+	}
+	for i := range cfg.Strategies {
+		s := &cfg.Strategies[i]
+		prefix := "BINANCE_STRATEGY_" + envName(s.Name)
+		if v := os.Getenv(prefix + "_TOKEN"); v != "" {
+			s.Token = v
+		}
+		if v := os.Getenv(prefix + "_HMAC_SECRET"); v != "" {
+			s.HMACSecret = v
+		}
+	}
+
+	if err := cfg.Binance.validateSymbols(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Binance.Mainnet.validateKeys("mainnet"); err != nil {
+		return nil, err
+	}
+	if err := cfg.Binance.Testnet.validateKeys("testnet"); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateAccounts(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateStrategies(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// validateSymbols 在启动前拦截明显错误的杠杆 / 保证金配置，避免带着错误设置下单
+func (b *BinanceRouter) validateSymbols() error {
+	return validateSymbolSettings("symbols", b.Symbols)
+}
+
+func validateSymbolSettings(path string, symbols map[string]SymbolConfig) error {
+	for symbol, sc := range symbols {
+		if sc.Leverage < 0 || sc.Leverage > 125 {
+			return fmt.Errorf("%s.%s.leverage 超出范围 [1, 125]: %d", path, symbol, sc.Leverage)
+		}
+		switch sc.MarginType {
+		case "", "ISOLATED", "CROSSED":
+		default:
+			return fmt.Errorf("%s.%s.margin_type 必须为 ISOLATED 或 CROSSED: %q", path, symbol, sc.MarginType)
+		}
+	}
+	return nil
+}
+
+// applyKeyEnv 附加 Key 的密钥同样支持环境变量注入，如 BINANCE_MAINNET_KEY_ORDER_2_API_SECRET
+func applyKeyEnv(prefix string, keys []APIKeyConfig) {
+	for i := range keys {
+		keyPrefix := prefix + "_KEY_" + envName(keys[i].Name)
+		if v := os.Getenv(keyPrefix + "_API_KEY"); v != "" {
+			keys[i].APIKey = v
+		}
+		if v := os.Getenv(keyPrefix + "_API_SECRET"); v != "" {
+			keys[i].APISecret = v
+		}
+	}
+}
+
+// envName 把名称转换为环境变量中的形式：转大写，- 换成 _
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// validateAccounts 检查命名账户：名称唯一且可作 Redis Key 前缀，环境合法，交易对非空，Key 配置完整
+func (c *Config) validateAccounts() error {
+	if !c.MultiAccount() {
+		return nil
+	}
+	seen := make(map[string]bool, len(c.Accounts))
+	for i, a := range c.Accounts {
+		if !validName(a.Name) {
+			return fmt.Errorf("accounts[%d].name 只能包含字母、数字、- 和 _: %q", i, a.Name)
+		}
+		if seen[a.Name] {
+			return fmt.Errorf("accounts 名称重复: %q", a.Name)
+		}
+		seen[a.Name] = true
+		switch a.Env {
+		case "", "mainnet", "testnet":
+		default:
+			return fmt.Errorf("accounts.%s.env 必须为 mainnet 或 testnet: %q", a.Name, a.Env)
+		}
+		if len(a.Symbols) == 0 {
+			return fmt.Errorf("accounts.%s.symbols 不能为空", a.Name)
+		}
+		if err := validateSymbolSettings("accounts."+a.Name+".symbols", a.Symbols); err != nil {
+			return err
+		}
+	}
+	for _, a := range c.TradingAccounts() {
+		if len(a.Env.APIKeys()) == 0 {
+			return fmt.Errorf("accounts.%s 未配置 API Key", a.Name)
+		}
+		if err := a.Env.validateKeys("accounts." + a.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateStrategies 检查策略注册表：名称唯一且可作 clientOrderId 前缀，限额非负，凭证最多一种，账户白名单只引用已配置的账户
+func (c *Config) validateStrategies() error {
+	accounts := make(map[string]bool)
+	for _, a := range c.TradingAccounts() {
+		accounts[a.Name] = true
+	}
+	seen := make(map[string]bool, len(c.Strategies))
+	for i, s := range c.Strategies {
+		if !validName(s.Name) || len(s.Name) > maxStrategyNameLen {
+			return fmt.Errorf("strategies[%d].name 只能包含字母、数字、- 和 _，且不超过 %d 个字符: %q", i, maxStrategyNameLen, s.Name)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("strategies 名称重复: %q", s.Name)
+		}
+		seen[s.Name] = true
+		if s.MaxPosition < 0 || s.MaxNotional < 0 || s.MaxOrders1m < 0 {
+			return fmt.Errorf("strategies.%s 的限额不能为负数", s.Name)
+		}
+		if s.Token != "" && s.HMACSecret != "" {
+			return fmt.Errorf("strategies.%s 的 token 与 hmac_secret 只能二选一", s.Name)
+		}
+		for _, a := range s.Accounts {
+			if !accounts[a] {
+				return fmt.Errorf("strategies.%s.accounts 引用了未配置的账户: %q", s.Name, a)
+			}
+		}
+	}
+	return nil
+}
+
+// validName 账户与策略名称只允许字母、数字、- 和 _ (分别用作 Redis Key 前缀与 clientOrderId 前缀)
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateKeys 检查下单额度与附加 Key 的名称、角色，并保证下单与只读请求都有 Key 可用
+func (e EnvConfig) validateKeys(env string) error {
+	if e.OrderLimit1m < 0 {
+		return fmt.Errorf("%s.order_limit_1m 不能为负数: %d", env, e.OrderLimit1m)
+	}
+	if len(e.Keys) == 0 {
+		return nil
+	}
+	seen := map[string]bool{PrimaryKeyName: e.APIKey != ""}
+	for i, k := range e.Keys {
+		if k.Name == "" {
+			return fmt.Errorf("%s.keys[%d].name 不能为空", env, i)
+		}
+		if seen[k.Name] {
+			return fmt.Errorf("%s.keys 名称重复: %q", env, k.Name)
+		}
+		seen[k.Name] = true
+		switch k.Role {
+		case "", KeyRoleAll, KeyRoleOrder, KeyRoleRead:
+		default:
+			return fmt.Errorf("%s.keys.%s.role 必须为 all、order 或 read: %q", env, k.Name, k.Role)
+		}
+	}
+	var canOrder, canRead bool
+	for _, k := range e.APIKeys() {
+		canOrder = canOrder || k.CanOrder()
+		canRead = canRead || k.CanRead()
+	}
+	if !canOrder || !canRead {
+		return fmt.Errorf("%s 至少需要一个可下单的 Key 和一个可只读查询的 Key", env)
+	}
+	return nil
+}
+
 // GetActiveEnv 核心的智能路由方法：根据 active_env 开关自动返回对应的配置实体
 func (b *BinanceRouter) GetActiveEnv() EnvConfig {
 	if b.ActiveEnv == "mainnet" {
@@ -68,3 +481,11 @@ func (b *BinanceRouter) GetActiveEnv() EnvConfig {
 	// 默认退化为 testnet，这是最安全的防爆仓兜底策略
 	return b.Testnet
 }
+
+// activeEnvName 与 GetActiveEnv 一致，非 mainnet 一律视为 testnet
+func (b *BinanceRouter) activeEnvName() string {
+	if b.ActiveEnv == "mainnet" {
+		return "mainnet"
+	}
+	return "testnet"
+}
@@ -108,3 +108,253 @@ func TestLoadConfig_InvalidJSON(t *testing.T) {
 		t.Error("expected error for invalid JSON")
 	}
 }
+
+func TestLoadConfig_SymbolSettings(t *testing.T) {
+	f, _ := os.CreateTemp("", "symbols_config_*.json")
+	defer os.Remove(f.Name())
+	f.WriteString(`{"binance": {"symbol": "BTCUSDT", "symbols": {"BTCUSDT": {"leverage": 10, "margin_type": "ISOLATED"}}, "apply_symbol_settings": true}}`)
+	f.Close()
+
+	cfg, err := LoadConfig(f.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	sc, ok := cfg.Binance.Symbols["BTCUSDT"]
+	if !ok || sc.Leverage != 10 || sc.MarginType != "ISOLATED" {
+		t.Errorf("unexpected symbol settings: %+v", sc)
+	}
+	if ta := cfg.TradingAccounts(); !ta[0].ApplySettings || ta[0].Symbols["BTCUSDT"].Leverage != 10 {
+		t.Errorf("apply_symbol_settings should be passed to the trading account: %+v", ta[0])
+	}
+
+	// 默认只校验不修改
+	plain, _ := os.CreateTemp("", "symbols_plain_*.json")
+	defer os.Remove(plain.Name())
+	plain.WriteString(`{"binance": {"symbols": {"BTCUSDT": {"leverage": 10}}}}`)
+	plain.Close()
+	if cfg, err := LoadConfig(plain.Name()); err != nil || cfg.TradingAccounts()[0].ApplySettings {
+		t.Errorf("apply_symbol_settings should default to false: %v", err)
+	}
+}
+
+func TestLoadConfig_InvalidSymbolSettings(t *testing.T) {
+	cases := []string{
+		`{"binance": {"symbols": {"BTCUSDT": {"leverage": 200}}}}`,
+		`{"binance": {"symbols": {"BTCUSDT": {"margin_type": "cross"}}}}`,
+	}
+	for _, content := range cases {
+		f, _ := os.CreateTemp("", "bad_symbols_*.json")
+		f.WriteString(content)
+		f.Close()
+
+		if _, err := LoadConfig(f.Name()); err == nil {
+			t.Errorf("expected validation error for %s", content)
+		}
+		os.Remove(f.Name())
+	}
+}
+
+func TestLoadConfig_APIKeys(t *testing.T) {
+	f, _ := os.CreateTemp("", "keys_config_*.json")
+	defer os.Remove(f.Name())
+	f.WriteString(`{"binance": {"testnet": {"api_key": "main", "api_secret": "s", "order_limit_1m": 600, "keys": [
+		{"name": "order-2", "api_key": "file_key", "role": "order"},
+		{"name": "reader", "api_key": "r", "api_secret": "rs", "role": "read"}
+	]}}}`)
+	f.Close()
+	os.Setenv("BINANCE_TESTNET_KEY_ORDER_2_API_SECRET", "env_secret")
+	defer os.Unsetenv("BINANCE_TESTNET_KEY_ORDER_2_API_SECRET")
+
+	cfg, err := LoadConfig(f.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	keys := cfg.Binance.Testnet.APIKeys()
+	if len(keys) != 3 || keys[0].Name != PrimaryKeyName || keys[0].Role != KeyRoleAll {
+		t.Fatalf("primary key should come first with role all: %+v", keys)
+	}
+	if keys[1].APISecret != "env_secret" || keys[1].APIKey != "file_key" {
+		t.Errorf("env var should override the extra key's secret: %+v", keys[1])
+	}
+	if cfg.Binance.Testnet.OrderLimit1m != 600 {
+		t.Errorf("order budget is per account, got %d", cfg.Binance.Testnet.OrderLimit1m)
+	}
+	if !keys[1].CanOrder() || keys[1].CanRead() || keys[2].CanOrder() || !keys[2].CanRead() {
+		t.Errorf("roles not applied: %+v", keys)
+	}
+}
+
+func TestLoadConfig_InvalidAPIKeys(t *testing.T) {
+	cases := []string{
+		`{"binance": {"testnet": {"keys": [{"name": "", "role": "order"}]}}}`,
+		`{"binance": {"testnet": {"api_key": "k", "keys": [{"name": "primary"}]}}}`,
+		`{"binance": {"testnet": {"keys": [{"name": "a", "role": "trade"}]}}}`,
+		`{"binance": {"testnet": {"order_limit_1m": -1}}}`,
+		// 只有下单 Key，没有可只读查询的 Key
+		`{"binance": {"mainnet": {"keys": [{"name": "a", "role": "order"}, {"name": "b", "role": "order"}]}}}`,
+	}
+	for _, content := range cases {
+		f, _ := os.CreateTemp("", "bad_keys_*.json")
+		f.WriteString(content)
+		f.Close()
+
+		if _, err := LoadConfig(f.Name()); err == nil {
+			t.Errorf("expected validation error for %s", content)
+		}
+		os.Remove(f.Name())
+	}
+}
+
+func TestLoadConfig_Accounts(t *testing.T) {
+	f, _ := os.CreateTemp("", "accounts_config_*.json")
+	defer os.Remove(f.Name())
+	f.WriteString(`{"binance": {"active_env": "testnet",
+		"mainnet": {"rest_base_url": "https://fapi.binance.com"},
+		"testnet": {"rest_base_url": "https://testnet.binancefuture.com", "api_key": "shared"}},
+	"accounts": [
+		{"name": "alpha", "api_key": "a", "api_secret": "as", "symbols": {"BTCUSDT": {"leverage": 5}}},
+		{"name": "beta-2", "env": "mainnet", "api_key": "b", "symbols": {"ETHUSDT": {}},
+			"keys": [{"name": "order-2", "api_key": "bo", "role": "order"}]}
+	]}`)
+	f.Close()
+	os.Setenv("BINANCE_ACCOUNT_BETA_2_API_SECRET", "env_secret")
+	os.Setenv("BINANCE_ACCOUNT_BETA_2_KEY_ORDER_2_API_SECRET", "env_order_secret")
+	defer os.Unsetenv("BINANCE_ACCOUNT_BETA_2_API_SECRET")
+	defer os.Unsetenv("BINANCE_ACCOUNT_BETA_2_KEY_ORDER_2_API_SECRET")
+
+	cfg, err := LoadConfig(f.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !cfg.MultiAccount() {
+		t.Fatal("expected multi-account mode")
+	}
+	accounts := cfg.TradingAccounts()
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+	alpha, beta := accounts[0], accounts[1]
+	if alpha.Name != "alpha" || alpha.EnvName != "testnet" || alpha.Env.RestBaseURL != "https://testnet.binancefuture.com" {
+		t.Errorf("alpha should follow active_env: %+v", alpha)
+	}
+	if alpha.Env.APIKey != "a" || alpha.Symbols["BTCUSDT"].Leverage != 5 {
+		t.Errorf("alpha should use its own key and symbols: %+v", alpha)
+	}
+	if beta.EnvName != "mainnet" || beta.Env.RestBaseURL != "https://fapi.binance.com" {
+		t.Errorf("beta should use the mainnet endpoints: %+v", beta)
+	}
+	keys := beta.Env.APIKeys()
+	if len(keys) != 2 || keys[0].APISecret != "env_secret" || keys[1].APISecret != "env_order_secret" {
+		t.Errorf("account env vars should override secrets: %+v", keys)
+	}
+
+	// 未配置 accounts 时为单账户模式，隐含账户使用 active_env 的 Key 与 binance.symbols
+	single := &Config{Binance: cfg.Binance}
+	if single.MultiAccount() {
+		t.Fatal("expected single-account mode")
+	}
+	if got := single.TradingAccounts(); len(got) != 1 || got[0].Name != DefaultAccountName || got[0].Env.APIKey != "shared" {
+		t.Errorf("unexpected default account: %+v", got)
+	}
+}
+
+func TestLoadConfig_InvalidAccounts(t *testing.T) {
+	cases := []string{
+		`{"accounts": [{"name": "a:b", "api_key": "k", "symbols": {"BTCUSDT": {}}}]}`,
+		`{"accounts": [{"name": "a", "api_key": "k", "symbols": {"BTCUSDT": {}}}, {"name": "a", "api_key": "k", "symbols": {"ETHUSDT": {}}}]}`,
+		`{"accounts": [{"name": "a", "env": "prod", "api_key": "k", "symbols": {"BTCUSDT": {}}}]}`,
+		`{"accounts": [{"name": "a", "api_key": "k"}]}`,
+		`{"accounts": [{"name": "a", "api_key": "k", "symbols": {"BTCUSDT": {"leverage": 200}}}]}`,
+		// 账户没有任何 Key
+		`{"accounts": [{"name": "a", "symbols": {"BTCUSDT": {}}}]}`,
+	}
+	for _, content := range cases {
+		f, _ := os.CreateTemp("", "bad_accounts_*.json")
+		f.WriteString(content)
+		f.Close()
+
+		if _, err := LoadConfig(f.Name()); err == nil {
+			t.Errorf("expected validation error for %s", content)
+		}
+		os.Remove(f.Name())
+	}
+}
+
+func TestLoadConfig_Strategies(t *testing.T) {
+	f, _ := os.CreateTemp("", "strategies_config_*.json")
+	defer os.Remove(f.Name())
+	f.WriteString(`{"strategies": [
+		{"name": "grid", "max_position": 0.5, "max_notional": 20000, "symbols": ["BTCUSDT"], "accounts": ["default"], "max_orders_1m": 60},
+		{"name": "mm-2"}
+	]}`)
+	f.Close()
+
+	cfg, err := LoadConfig(f.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Strategies) != 2 {
+		t.Fatalf("expected 2 strategies, got %d", len(cfg.Strategies))
+	}
+	grid := cfg.Strategies[0]
+	if grid.Name != "grid" || grid.MaxPosition != 0.5 || grid.MaxNotional != 20000 || grid.MaxOrders1m != 60 ||
+		len(grid.Symbols) != 1 || grid.Symbols[0] != "BTCUSDT" || len(grid.Accounts) != 1 || grid.Accounts[0] != "default" {
+		t.Errorf("unexpected strategy limits: %+v", grid)
+	}
+
+	cases := []string{
+		`{"strategies": [{"name": ""}]}`,
+		`{"strategies": [{"name": "grid.v2"}]}`,
+		`{"strategies": [{"name": "a-very-long-strategy-name"}]}`,
+		`{"strategies": [{"name": "grid"}, {"name": "grid"}]}`,
+		`{"strategies": [{"name": "grid", "max_position": -1}]}`,
+		`{"strategies": [{"name": "grid", "accounts": ["alpha"]}]}`, // 单账户模式下只有 default
+	}
+	for _, content := range cases {
+		bad, _ := os.CreateTemp("", "bad_strategies_*.json")
+		bad.WriteString(content)
+		bad.Close()
+
+		if _, err := LoadConfig(bad.Name()); err == nil {
+			t.Errorf("expected validation error for %s", content)
+		}
+		os.Remove(bad.Name())
+	}
+}
+
+func TestLoadConfig_UDSAuth(t *testing.T) {
+	f, _ := os.CreateTemp("", "uds_auth_config_*.json")
+	defer os.Remove(f.Name())
+	f.WriteString(`{
+		"uds": {"allowed_uids": [1000, 1001], "require_auth": true, "audit_log_path": "data/audit.jsonl"},
+		"strategies": [
+			{"name": "grid", "token": "file-token"},
+			{"name": "mm-2", "hmac_secret": ""}
+		]
+	}`)
+	f.Close()
+
+	os.Setenv("BINANCE_STRATEGY_GRID_TOKEN", "env-token")
+	os.Setenv("BINANCE_STRATEGY_MM_2_HMAC_SECRET", "env-secret")
+	defer os.Unsetenv("BINANCE_STRATEGY_GRID_TOKEN")
+	defer os.Unsetenv("BINANCE_STRATEGY_MM_2_HMAC_SECRET")
+
+	cfg, err := LoadConfig(f.Name())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.UDS.AllowedUIDs) != 2 || cfg.UDS.AllowedUIDs[1] != 1001 || !cfg.UDS.RequireAuth || cfg.UDS.AuditLogPath != "data/audit.jsonl" {
+		t.Errorf("unexpected uds config: %+v", cfg.UDS)
+	}
+	if cfg.Strategies[0].Token != "env-token" || cfg.Strategies[1].HMACSecret != "env-secret" {
+		t.Errorf("strategy credentials should be overridden by env: %+v", cfg.Strategies)
+	}
+
+	bad, _ := os.CreateTemp("", "bad_uds_auth_*.json")
+	defer os.Remove(bad.Name())
+	bad.WriteString(`{"strategies": [{"name": "arb", "token": "t", "hmac_secret": "s"}]}`)
+	bad.Close()
+	if _, err := LoadConfig(bad.Name()); err == nil {
+		t.Error("expected error when both token and hmac_secret are set")
+	}
+}
@@ -0,0 +1,262 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/pnl"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SchemaVersion 事件结构版本，字段有不兼容变化时递增，消费端据此判断能否解析
+const SchemaVersion = "1"
+
+// Redis Stream 名称
+const (
+	StreamBookPrefix = "Stream:Book:" // 按交易对分流，如 Stream:Book:BTCUSDT
+	StreamOrders     = "Stream:Orders"
+	StreamFills      = "Stream:Fills"
+	StreamAccount    = "Stream:Account"
+)
+
+// 事件类型，对应每条消息的 type 字段
+const (
+	TypeBook    = "book"
+	TypeOrder   = "order"
+	TypeFill    = "fill"
+	TypeAccount = "account"
+)
+
+// FormatJSON data 字段的默认编码；盘口可按配置改为 msgpack / binary (见 internal/codec)
+const FormatJSON = "json"
+
+// DefaultMaxLen 每个 Stream 保留的近似最大条数
+const DefaultMaxLen = 10000
+
+// publishTimeout 单次 XADD 的超时，防止 Redis 阻塞 WS 接收协程
+const publishTimeout = 50 * time.Millisecond
+
+// OrderEvent 订单与成交事件的统一结构 (Stream:Orders 与 Stream:Fills 共用)
+type OrderEvent struct {
+	Symbol          string  `json:"symbol"`
+	ClientOrderID   string  `json:"client_order_id"`
+	Strategy        string  `json:"strategy"` // 由 clientOrderId 前缀解析出的策略，与盈亏、账本的归属一致
+	OrderID         int64   `json:"order_id"`
+	Side            string  `json:"side"`
+	PositionSide    string  `json:"position_side"`
+	OrderType       string  `json:"order_type,omitempty"`
+	ExecutionType   string  `json:"execution_type"` // NEW / TRADE / CANCELED / EXPIRED / AMENDMENT / CALCULATED
+	Status          string  `json:"status"`
+	OrigQty         float64 `json:"orig_qty"`
+	Price           float64 `json:"price"`
+	AvgPrice        float64 `json:"avg_price"`
+	FilledQty       float64 `json:"filled_qty"` // 累计成交数量
+	LastFilledQty   float64 `json:"last_filled_qty"`
+	LastFilledPrice float64 `json:"last_filled_price"`
+	Commission      float64 `json:"commission"`
+	CommissionAsset string  `json:"commission_asset,omitempty"`
+	RealizedPnL     float64 `json:"realized_pnl"`
+	TradeID         int64   `json:"trade_id,omitempty"`
+	EventTime       int64   `json:"event_time"`       // 交易所事件 / 成交时间 (毫秒)
+	Source          string  `json:"source,omitempty"` // 空为实时推送；reconcile 表示由对账补发
+}
+
+// IsFill 是否为成交事件
+func (e OrderEvent) IsFill() bool {
+	return e.ExecutionType == "TRADE" || e.ExecutionType == "CALCULATED"
+}
+
+// OrderEventFromUpdate 由 ORDER_TRADE_UPDATE 构造订单事件
+func OrderEventFromUpdate(o *binance.OrderTradeUpdate) OrderEvent {
+	return OrderEvent{
+		Symbol:          o.Symbol,
+		ClientOrderID:   o.ClientOrderID,
+		Strategy:        pnl.StrategyFromClientOrderID(o.ClientOrderID),
+		OrderID:         o.OrderID,
+		Side:            o.Side,
+		PositionSide:    o.PositionSide,
+		OrderType:       o.OrderType,
+		ExecutionType:   o.ExecutionType,
+		Status:          o.Status,
+		OrigQty:         parseFloat(o.OrigQty),
+		Price:           parseFloat(o.Price),
+		AvgPrice:        parseFloat(o.AvgPrice),
+		FilledQty:       parseFloat(o.CumFilledQty),
+		LastFilledQty:   parseFloat(o.LastFilledQty),
+		LastFilledPrice: parseFloat(o.LastFilledPrice),
+		Commission:      parseFloat(o.Commission),
+		CommissionAsset: o.CommissionAsset,
+		RealizedPnL:     parseFloat(o.RealizedProfit),
+		TradeID:         o.TradeID,
+		EventTime:       o.TradeTime,
+	}
+}
+
+// FillEventFromTrade 由对账补记的成交 (/fapi/v1/userTrades) 构造成交事件
+func FillEventFromTrade(t binance.UserTrade, clientOrderID string) OrderEvent {
+	return OrderEvent{
+		Symbol:          t.Symbol,
+		ClientOrderID:   clientOrderID,
+		Strategy:        pnl.StrategyFromClientOrderID(clientOrderID),
+		OrderID:         t.OrderID,
+		Side:            t.Side,
+		PositionSide:    t.PositionSide,
+		ExecutionType:   "TRADE",
+		LastFilledQty:   t.Qty,
+		LastFilledPrice: t.Price,
+		Commission:      t.Commission,
+		CommissionAsset: t.CommissionAsset,
+		RealizedPnL:     t.RealizedPnl,
+		TradeID:         t.ID,
+		EventTime:       t.Time,
+		Source:          "reconcile",
+	}
+}
+
+// Publisher 把行情、订单、成交与账户事件 XADD 到 Redis Streams (消费端可用 XREAD BLOCK 阻塞等待)，
+// 同时投递给进程内 Hub 供 UDS 订阅端使用
+// 每条消息的字段：v (结构版本)、type、symbol、ts (网关发布时间，毫秒)、fmt (data 的编码格式)、data
+// rdb 为 nil 时不写 Redis Streams；hub 为 nil 时不做进程内投递；Publisher 本身为 nil 时所有发布操作为空操作
+type Publisher struct {
+	rdb     *redis.Client
+	maxLen  int64
+	hub     *Hub
+	account string // 非空时订单 / 成交 / 账户 Stream 名加上 "<account>:" 前缀，事件带 account 字段
+}
+
+// NewPublisher 创建发布器，maxLen <= 0 时使用 DefaultMaxLen
+func NewPublisher(rdb *redis.Client, maxLen int64, hub *Hub) *Publisher {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLen
+	}
+	return &Publisher{rdb: rdb, maxLen: maxLen, hub: hub}
+}
+
+// WithAccount 返回发布到指定账户的 Publisher：订单、成交与账户事件写入 <account>:Stream:Orders 等，
+// 行情仍写入共用的 Stream:Book:<SYM>
+func (p *Publisher) WithAccount(account string) *Publisher {
+	if p == nil {
+		return nil
+	}
+	cp := *p
+	cp.account = account
+	return &cp
+}
+
+// stream 返回加上账户前缀后的 Stream 名
+func (p *Publisher) stream(name string) string {
+	if p.account == "" {
+		return name
+	}
+	return p.account + ":" + name
+}
+
+// PublishBook 发布盘口快照到 Stream:Book:<SYM>
+// payload 为按 format (json / msgpack / binary) 编码好的负载，与 OrderBook:<SYM> Key 的内容相同，避免重复编码；
+// 进程内 Hub 始终推送 JSON，且只在有订阅者时才编码
+func (p *Publisher) PublishBook(ctx context.Context, snap binance.OrderBookSnapshot, payload []byte, format string) error {
+	if p == nil {
+		return nil
+	}
+	ts := time.Now().UnixMilli()
+	if p.hub != nil && p.hub.Subscribers() > 0 {
+		if data, err := json.Marshal(snap); err == nil {
+			p.hub.Broadcast(Event{Version: SchemaVersion, Type: TypeBook, Symbol: snap.Symbol, TS: ts, Data: data})
+		}
+	}
+	if p.rdb == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return p.rdb.XAdd(ctx, p.args(StreamBookPrefix+snap.Symbol, TypeBook, snap.Symbol, format, ts, payload)).Err()
+}
+
+// PublishOrder 发布订单事件到 Stream:Orders，成交同时写入 Stream:Fills
+func (p *Publisher) PublishOrder(ctx context.Context, e OrderEvent) error {
+	if p == nil {
+		return nil
+	}
+	if !e.IsFill() {
+		return p.publish(ctx, p.stream(StreamOrders), TypeOrder, e.Symbol, e)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ts := time.Now().UnixMilli()
+	p.hub.Broadcast(Event{Version: SchemaVersion, Type: TypeOrder, Symbol: e.Symbol, Account: p.account, TS: ts, Data: data})
+	p.hub.Broadcast(Event{Version: SchemaVersion, Type: TypeFill, Symbol: e.Symbol, Account: p.account, TS: ts, Data: data})
+	if p.rdb == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	pipe := p.rdb.Pipeline()
+	pipe.XAdd(ctx, p.args(p.stream(StreamOrders), TypeOrder, e.Symbol, FormatJSON, ts, data))
+	pipe.XAdd(ctx, p.args(p.stream(StreamFills), TypeFill, e.Symbol, FormatJSON, ts, data))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// PublishOrderOnly 只发布到 Stream:Orders (成交已由对账补记到 Stream:Fills，推送仍需更新订单状态)
+func (p *Publisher) PublishOrderOnly(ctx context.Context, e OrderEvent) error {
+	return p.publish(ctx, p.stream(StreamOrders), TypeOrder, e.Symbol, e)
+}
+
+// PublishFill 只发布到 Stream:Fills (对账补记的成交没有对应的订单推送)
+func (p *Publisher) PublishFill(ctx context.Context, e OrderEvent) error {
+	return p.publish(ctx, p.stream(StreamFills), TypeFill, e.Symbol, e)
+}
+
+// PublishAccount 发布账户快照到 Stream:Account
+func (p *Publisher) PublishAccount(ctx context.Context, snapshot interface{}) error {
+	return p.publish(ctx, p.stream(StreamAccount), TypeAccount, "", snapshot)
+}
+
+func (p *Publisher) publish(ctx context.Context, stream, typ, symbol string, payload interface{}) error {
+	if p == nil {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ts := time.Now().UnixMilli()
+	p.hub.Broadcast(Event{Version: SchemaVersion, Type: typ, Symbol: symbol, Account: p.account, TS: ts, Data: data})
+	if p.rdb == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return p.rdb.XAdd(ctx, p.args(stream, typ, symbol, FormatJSON, ts, data)).Err()
+}
+
+// args 构造 XADD 参数，MAXLEN 使用近似裁剪 (~)，避免每次写入都精确截断
+func (p *Publisher) args(stream, typ, symbol, format string, ts int64, data []byte) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: []interface{}{
+			"v", SchemaVersion,
+			"type", typ,
+			"symbol", symbol,
+			"ts", strconv.FormatInt(ts, 10),
+			"fmt", format,
+			"data", data,
+		},
+	}
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+func TestNewPublisher_DefaultMaxLen(t *testing.T) {
+	if p := NewPublisher(nil, 0, nil); p.maxLen != DefaultMaxLen {
+		t.Errorf("expected default max len %d, got %d", DefaultMaxLen, p.maxLen)
+	}
+	if p := NewPublisher(nil, 500, nil); p.maxLen != 500 {
+		t.Errorf("expected max len 500, got %d", p.maxLen)
+	}
+}
+
+func TestArgs_Schema(t *testing.T) {
+	p := NewPublisher(nil, 100, nil)
+	args := p.args(StreamOrders, TypeOrder, "BTCUSDT", FormatJSON, 1700000000000, []byte(`{"a":1}`))
+
+	if args.Stream != StreamOrders || args.MaxLen != 100 || !args.Approx {
+		t.Errorf("unexpected XADD args: %+v", args)
+	}
+	values := args.Values.([]interface{})
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(values); i += 2 {
+		fields[values[i].(string)] = values[i+1]
+	}
+	if fields["v"] != SchemaVersion || fields["type"] != TypeOrder || fields["symbol"] != "BTCUSDT" || fields["fmt"] != FormatJSON {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+	if fields["ts"] != "1700000000000" || string(fields["data"].([]byte)) != `{"a":1}` {
+		t.Errorf("missing ts or data: %v", fields)
+	}
+}
+
+func TestNilPublisherIsNoop(t *testing.T) {
+	var p *Publisher
+	if err := p.PublishBook(context.Background(), binance.OrderBookSnapshot{Symbol: "BTCUSDT"}, nil, FormatJSON); err != nil {
+		t.Errorf("nil publisher should be a no-op, got %v", err)
+	}
+	if err := p.PublishOrder(context.Background(), OrderEvent{ExecutionType: "TRADE"}); err != nil {
+		t.Errorf("nil publisher should be a no-op, got %v", err)
+	}
+}
+
+func TestOrderEventFromUpdate(t *testing.T) {
+	var event binance.UserDataEvent
+	raw := `{"e":"ORDER_TRADE_UPDATE","E":1,"T":1,"o":{"s":"BTCUSDT","c":"grid_17","S":"BUY","o":"LIMIT",
+		"q":"0.2","p":"50000","ap":"49995","x":"TRADE","X":"PARTIALLY_FILLED","i":8,"l":"0.1","z":"0.1","L":"49995",
+		"N":"USDT","n":"2","T":123,"t":9,"ps":"BOTH","rp":"0"}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	e := OrderEventFromUpdate(event.Order)
+	if !e.IsFill() {
+		t.Error("TRADE execution should be a fill")
+	}
+	if e.ClientOrderID != "grid_17" || e.Strategy != "grid" || e.OrigQty != 0.2 || e.AvgPrice != 49995 || e.LastFilledQty != 0.1 ||
+		e.Commission != 2 || e.TradeID != 9 || e.EventTime != 123 {
+		t.Errorf("unexpected order event: %+v", e)
+	}
+
+	fill := FillEventFromTrade(binance.UserTrade{ID: 5, OrderID: 8, Symbol: "BTCUSDT", Qty: 0.1, Price: 50000, Time: 9}, "grid_17")
+	if !fill.IsFill() || fill.Source != "reconcile" || fill.ClientOrderID != "grid_17" || fill.Strategy != "grid" || fill.LastFilledPrice != 50000 {
+		t.Errorf("unexpected reconciled fill event: %+v", fill)
+	}
+}
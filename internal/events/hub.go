@@ -0,0 +1,133 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+)
+
+// Event 进程内推送的事件，字段与 Redis Streams 消息一一对应
+type Event struct {
+	Version string          `json:"v"`
+	Seq     uint64          `json:"seq"` // Hub 内单调递增，订阅端可据此发现丢失
+	Type    string          `json:"type"`
+	Symbol  string          `json:"symbol,omitempty"`
+	Account string          `json:"account,omitempty"` // 多账户模式下订单、成交与账户事件所属账户，行情事件为空
+	TS      int64           `json:"ts"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Subscription 一个本地订阅者；C 关闭表示订阅已结束
+type Subscription struct {
+	C <-chan Event
+
+	ch       chan Event
+	types    map[string]bool
+	symbols  map[string]bool
+	accounts map[string]bool
+	dropped  atomic.Uint64
+	hub      *Hub
+	once     sync.Once
+}
+
+// TakeDropped 返回并清零因消费过慢被丢弃的事件数
+func (s *Subscription) TakeDropped() uint64 {
+	return s.dropped.Swap(0)
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+func (s *Subscription) match(e *Event) bool {
+	if len(s.types) > 0 && !s.types[e.Type] {
+		return false
+	}
+	// 账户事件不带交易对，不受交易对过滤影响
+	if len(s.symbols) > 0 && e.Symbol != "" && !s.symbols[e.Symbol] {
+		return false
+	}
+	// 行情事件不属于任何账户，同理不受账户过滤影响
+	if len(s.accounts) > 0 && e.Account != "" && !s.accounts[e.Account] {
+		return false
+	}
+	return true
+}
+
+// Hub 进程内事件分发：发布端从不阻塞，订阅端缓冲区满时丢弃并计数
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	seq  atomic.Uint64
+}
+
+// NewHub 创建事件分发器
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe 订阅指定类型与交易对的事件，空列表表示不过滤
+func (h *Hub) Subscribe(types, symbols []string, buffer int) *Subscription {
+	return h.SubscribeAccounts(types, symbols, nil, buffer)
+}
+
+// SubscribeAccounts 在 Subscribe 的基础上只接收指定账户的事件
+func (h *Hub) SubscribeAccounts(types, symbols, accounts []string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = 1
+	}
+	ch := make(chan Event, buffer)
+	s := &Subscription{C: ch, ch: ch, types: toSet(types), symbols: toSet(symbols), accounts: toSet(accounts), hub: h}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// Broadcast 分配序号后投递给所有匹配的订阅者
+func (h *Hub) Broadcast(e Event) {
+	if h == nil {
+		return
+	}
+	e.Seq = h.seq.Add(1)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs {
+		if !s.match(&e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribers 当前订阅者数量
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+func toSet(items []string) map[string]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(items))
+	for _, it := range items {
+		if it != "" {
+			set[it] = true
+		}
+	}
+	return set
+}
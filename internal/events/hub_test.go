@@ -0,0 +1,176 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+func TestHub_FilterAndSeq(t *testing.T) {
+	h := NewHub()
+	books := h.Subscribe([]string{TypeBook}, []string{"BTCUSDT"}, 10)
+	all := h.Subscribe(nil, []string{"ETHUSDT"}, 10)
+	defer books.Close()
+	defer all.Close()
+
+	h.Broadcast(Event{Type: TypeBook, Symbol: "BTCUSDT"})
+	h.Broadcast(Event{Type: TypeBook, Symbol: "ETHUSDT"})
+	h.Broadcast(Event{Type: TypeAccount})
+
+	if len(books.C) != 1 {
+		t.Fatalf("book subscriber should only get BTCUSDT books, got %d events", len(books.C))
+	}
+	if e := <-books.C; e.Seq != 1 || e.Symbol != "BTCUSDT" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	// 账户事件不带交易对，不受交易对过滤影响
+	if len(all.C) != 2 {
+		t.Fatalf("expected ETHUSDT book + account event, got %d", len(all.C))
+	}
+	<-all.C
+	if e := <-all.C; e.Type != TypeAccount || e.Seq != 3 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestHub_SlowSubscriberDrops(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(nil, nil, 2)
+	for i := 0; i < 5; i++ {
+		h.Broadcast(Event{Type: TypeBook})
+	}
+	if n := sub.TakeDropped(); n != 3 {
+		t.Errorf("expected 3 dropped events, got %d", n)
+	}
+	if n := sub.TakeDropped(); n != 0 {
+		t.Errorf("dropped counter should reset, got %d", n)
+	}
+
+	sub.Close()
+	sub.Close() // 重复关闭安全
+	if h.Subscribers() != 0 {
+		t.Errorf("closed subscription should be removed")
+	}
+	h.Broadcast(Event{Type: TypeBook}) // 关闭后广播不应 panic
+}
+
+func TestPublisher_FeedsHub(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(nil, nil, 10)
+	defer sub.Close()
+
+	p := NewPublisher(nil, 0, h)
+	if err := p.PublishOrder(context.Background(), OrderEvent{Symbol: "BTCUSDT", ExecutionType: "TRADE"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.C) != 2 {
+		t.Fatalf("fill should be delivered as both order and fill events, got %d", len(sub.C))
+	}
+	order, fill := <-sub.C, <-sub.C
+	if order.Type != TypeOrder || fill.Type != TypeFill || order.Version != SchemaVersion || order.TS == 0 {
+		t.Errorf("unexpected events: %+v / %+v", order, fill)
+	}
+}
+
+func TestPublisher_OrderOnlySkipsFill(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(nil, nil, 10)
+	defer sub.Close()
+
+	p := NewPublisher(nil, 0, h)
+	if err := p.PublishOrderOnly(context.Background(), OrderEvent{Symbol: "BTCUSDT", ExecutionType: "TRADE"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.C) != 1 {
+		t.Fatalf("duplicate fill should only be delivered as an order event, got %d", len(sub.C))
+	}
+	if e := <-sub.C; e.Type != TypeOrder {
+		t.Errorf("unexpected event type %q", e.Type)
+	}
+}
+
+func TestPublisher_WithAccount(t *testing.T) {
+	h := NewHub()
+	alpha := h.SubscribeAccounts(nil, nil, []string{"alpha"}, 10)
+	defer alpha.Close()
+
+	base := NewPublisher(nil, 0, h)
+	p := base.WithAccount("alpha")
+	if p.stream(StreamOrders) != "alpha:Stream:Orders" || base.stream(StreamOrders) != StreamOrders {
+		t.Errorf("account streams should be prefixed without touching the base publisher")
+	}
+	ctx := context.Background()
+	p.PublishAccount(ctx, map[string]int{"x": 1})
+	base.WithAccount("beta").PublishAccount(ctx, map[string]int{"x": 2})
+	base.PublishBook(ctx, binance.OrderBookSnapshot{Symbol: "BTCUSDT"}, nil, FormatJSON)
+
+	// alpha 的订阅者收到自己的账户事件与不属于任何账户的行情，收不到 beta 的事件
+	if len(alpha.C) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(alpha.C))
+	}
+	if e := <-alpha.C; e.Type != TypeAccount || e.Account != "alpha" {
+		t.Errorf("unexpected account event: %+v", e)
+	}
+	if e := <-alpha.C; e.Type != TypeBook || e.Account != "" {
+		t.Errorf("unexpected book event: %+v", e)
+	}
+}
+
+func TestServeHTTP_SSE(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?types=fill&symbols=BTCUSDT", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, ": connected") {
+		t.Fatalf("expected connected comment, got %q", line)
+	}
+	reader.ReadString('\n')
+
+	p := NewPublisher(nil, 0, h)
+	p.PublishBook(ctx, bookSnapshot("BTCUSDT"), nil, FormatJSON) // 类型不匹配，不应推送
+	p.PublishFill(ctx, OrderEvent{Symbol: "BTCUSDT", ClientOrderID: "grid_1", ExecutionType: "TRADE"})
+
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if !strings.HasPrefix(lines[0], "id: ") || lines[1] != "event: fill" || !strings.HasPrefix(lines[2], "data: ") {
+		t.Fatalf("unexpected SSE frame: %q", lines)
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &e); err != nil {
+		t.Fatal(err)
+	}
+	var fill OrderEvent
+	if err := json.Unmarshal(e.Data, &fill); err != nil || fill.ClientOrderID != "grid_1" {
+		t.Errorf("unexpected fill payload: %s (%v)", e.Data, err)
+	}
+}
+
+func bookSnapshot(symbol string) binance.OrderBookSnapshot {
+	return binance.OrderBookSnapshot{Symbol: symbol, LastUpdateID: 1}
+}
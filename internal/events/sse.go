@@ -0,0 +1,86 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sseBuffer 每个 SSE 订阅者的事件缓冲条数
+const sseBuffer = 1024
+
+// sseHeartbeat 空闲时发送注释行的间隔，便于客户端识别断线
+var sseHeartbeat = 15 * time.Second
+
+// ServeHTTP 以 Server-Sent Events 推送事件，供同机策略进程直接订阅 (GET /api/stream)
+// 参数：types=book,order,fill,account、symbols=BTCUSDT,ETHUSDT 与 accounts=alpha,beta (多账户模式)，均可省略
+// 每条消息：id 为 Hub 序号，event 为事件类型，data 为 Event 的 JSON；
+// 消费过慢导致丢弃时先推送一条 event: dropped，data 为 {"count": N}，客户端应据此重新拉取全量状态
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	sub := h.SubscribeAccounts(splitList(q.Get("types")), splitList(q.Get("symbols")), splitList(q.Get("accounts")), sseBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if n := sub.TakeDropped(); n > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", n)
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data); err != nil {
+				return
+			}
+			// 把缓冲区中已到达的事件一并写出后再 Flush，减少高频行情下的系统调用
+			if len(sub.C) == 0 {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	parts := strings.Split(v, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
@@ -0,0 +1,130 @@
+// Package latency 固定桶的延迟直方图：写入无分配、可并发，支持按区间求差后估算分位数
+package latency
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultBounds 默认桶上界，覆盖网关内部的微秒级处理到跨洋 REST 的秒级往返
+var DefaultBounds = []time.Duration{
+	50 * time.Microsecond, 100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond,
+	25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// Histogram 延迟直方图，负值 (如交易所与本机时钟偏差) 计入第一个桶
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []uint64 // 最后一个元素为超过最大上界的溢出桶
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram 创建直方图，bounds 为空时使用 DefaultBounds；bounds 必须升序
+func NewHistogram(bounds ...time.Duration) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultBounds
+	}
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe 记录一次耗时；Histogram 为 nil 时为空操作
+func (h *Histogram) Observe(d time.Duration) {
+	if h == nil {
+		return
+	}
+	if d < 0 {
+		d = 0
+	}
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+	h.mu.Unlock()
+}
+
+// Since 记录从 start 到现在的耗时
+func (h *Histogram) Since(start time.Time) {
+	h.Observe(time.Since(start))
+}
+
+// Snapshot 直方图的一致性快照
+type Snapshot struct {
+	Bounds []time.Duration // 桶上界
+	Counts []uint64        // 各桶计数 (非累计)，比 Bounds 多一个溢出桶
+	Count  uint64
+	Sum    time.Duration
+	Max    time.Duration // 累计最大值；Sub 得到的区间快照中为 0
+}
+
+// Snapshot 返回当前累计数据
+func (h *Histogram) Snapshot() Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Snapshot{
+		Bounds: h.bounds,
+		Counts: append([]uint64(nil), h.counts...),
+		Count:  h.count,
+		Sum:    h.sum,
+		Max:    h.max,
+	}
+}
+
+// Sub 计算与更早快照之间的区间增量，用于周期性日志只统计本周期的数据
+func (s Snapshot) Sub(prev Snapshot) Snapshot {
+	if len(prev.Counts) != len(s.Counts) {
+		return s
+	}
+	out := Snapshot{Bounds: s.Bounds, Counts: make([]uint64, len(s.Counts)), Count: s.Count - prev.Count, Sum: s.Sum - prev.Sum}
+	for i := range s.Counts {
+		out.Counts[i] = s.Counts[i] - prev.Counts[i]
+	}
+	return out
+}
+
+// Mean 平均耗时
+func (s Snapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile 估算分位数 (q 取 0~1)：定位所在桶后在桶内线性插值，溢出桶返回最大上界
+func (s Snapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := q * float64(s.Count)
+	var cum float64
+	for i, c := range s.Counts {
+		if c == 0 {
+			continue
+		}
+		if cum+float64(c) >= rank {
+			if i == len(s.Bounds) {
+				return s.Bounds[len(s.Bounds)-1]
+			}
+			var lower time.Duration
+			if i > 0 {
+				lower = s.Bounds[i-1]
+			}
+			frac := (rank - cum) / float64(c)
+			return lower + time.Duration(frac*float64(s.Bounds[i]-lower))
+		}
+		cum += float64(c)
+	}
+	return s.Bounds[len(s.Bounds)-1]
+}
@@ -0,0 +1,92 @@
+package latency
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistogram_Buckets(t *testing.T) {
+	h := NewHistogram(time.Millisecond, 10*time.Millisecond)
+	h.Observe(-time.Millisecond) // 时钟偏差导致的负值计入第一个桶
+	h.Observe(time.Millisecond)  // 等于上界归入该桶
+	h.Observe(5 * time.Millisecond)
+	h.Observe(time.Second) // 溢出桶
+
+	s := h.Snapshot()
+	if s.Count != 4 || s.Max != time.Second {
+		t.Fatalf("unexpected snapshot: %+v", s)
+	}
+	want := []uint64{2, 1, 1}
+	for i, c := range want {
+		if s.Counts[i] != c {
+			t.Errorf("bucket %d: expected %d, got %d", i, c, s.Counts[i])
+		}
+	}
+	if s.Mean() != (time.Millisecond+5*time.Millisecond+time.Second)/4 {
+		t.Errorf("unexpected mean %v", s.Mean())
+	}
+
+	var nilHist *Histogram
+	nilHist.Observe(time.Second) // nil 直方图为空操作
+}
+
+func TestSnapshot_Quantile(t *testing.T) {
+	h := NewHistogram(time.Millisecond, 2*time.Millisecond, 4*time.Millisecond)
+	for i := 0; i < 90; i++ {
+		h.Observe(500 * time.Microsecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(3 * time.Millisecond)
+	}
+	s := h.Snapshot()
+
+	if p50 := s.Quantile(0.5); p50 <= 0 || p50 > time.Millisecond {
+		t.Errorf("p50 should fall in the first bucket, got %v", p50)
+	}
+	if p99 := s.Quantile(0.99); p99 <= 2*time.Millisecond || p99 > 4*time.Millisecond {
+		t.Errorf("p99 should fall in the (2ms, 4ms] bucket, got %v", p99)
+	}
+	if (Snapshot{}).Quantile(0.5) != 0 {
+		t.Error("empty snapshot quantile should be 0")
+	}
+
+	h.Observe(time.Minute)
+	if q := h.Snapshot().Quantile(1); q != 4*time.Millisecond {
+		t.Errorf("overflow bucket should report the largest bound, got %v", q)
+	}
+}
+
+func TestSnapshot_Sub(t *testing.T) {
+	h := NewHistogram()
+	h.Observe(time.Millisecond)
+	prev := h.Snapshot()
+	h.Observe(time.Second)
+	h.Observe(time.Second)
+
+	delta := h.Snapshot().Sub(prev)
+	if delta.Count != 2 || delta.Sum != 2*time.Second || delta.Max != 0 {
+		t.Errorf("unexpected interval snapshot: %+v", delta)
+	}
+	if q := delta.Quantile(0.5); q <= 500*time.Millisecond {
+		t.Errorf("interval quantile should ignore earlier observations, got %v", q)
+	}
+}
+
+func TestHistogram_Concurrent(t *testing.T) {
+	h := NewHistogram()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				h.Observe(time.Duration(j) * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+	if c := h.Snapshot().Count; c != 8000 {
+		t.Errorf("expected 8000 observations, got %d", c)
+	}
+}
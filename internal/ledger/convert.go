@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"strconv"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// FromOrderResponse 把 REST 回执 (下单或撤单) 转为账本记录
+func FromOrderResponse(kind string, o *binance.OrderResponse) Entry {
+	return Entry{
+		Kind:          kind,
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		PositionSide:  o.PositionSide,
+		OrderType:     o.Type,
+		ClientOrderID: o.ClientOrderID,
+		OrderID:       o.OrderID,
+		Status:        o.Status,
+		Quantity:      o.OrigQty,
+		Price:         o.Price,
+		FilledQty:     o.ExecutedQty,
+		FillPrice:     o.AvgPrice,
+		ExchangeTime:  o.UpdateTime,
+	}
+}
+
+// FromOrderUpdate 把 ORDER_TRADE_UPDATE 中的成交与撤销转为账本记录；
+// NEW 等状态已由 REST 回执记录，返回 false
+func FromOrderUpdate(o *binance.OrderTradeUpdate) (Entry, bool) {
+	if o == nil {
+		return Entry{}, false
+	}
+	e := Entry{
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		PositionSide:  o.PositionSide,
+		OrderType:     o.OrderType,
+		ClientOrderID: o.ClientOrderID,
+		OrderID:       o.OrderID,
+		Status:        o.Status,
+		Quantity:      parseFloat(o.OrigQty),
+		Price:         parseFloat(o.Price),
+		ExchangeTime:  o.TradeTime,
+	}
+	switch o.ExecutionType {
+	case "TRADE", "CALCULATED": // CALCULATED 为强平成交
+		e.Kind = KindFill
+		e.FilledQty = parseFloat(o.LastFilledQty)
+		e.FillPrice = parseFloat(o.LastFilledPrice)
+		e.TradeID = o.TradeID
+		e.Commission = parseFloat(o.Commission)
+		e.CommissionAsset = o.CommissionAsset
+		e.RealizedPnL = parseFloat(o.RealizedProfit)
+	case "CANCELED", "EXPIRED":
+		e.Kind = KindCanceled
+		e.FilledQty = parseFloat(o.CumFilledQty)
+	default:
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// FromUserTrade 把对账发现的漏接成交 (/fapi/v1/userTrades) 转为账本记录
+// 成交记录不带 clientOrderId，由调用方从本地订单映射中补齐
+func FromUserTrade(t binance.UserTrade, clientOrderID string) Entry {
+	return Entry{
+		Kind:            KindFill,
+		Symbol:          t.Symbol,
+		Side:            t.Side,
+		PositionSide:    t.PositionSide,
+		ClientOrderID:   clientOrderID,
+		OrderID:         t.OrderID,
+		FilledQty:       t.Qty,
+		FillPrice:       t.Price,
+		TradeID:         t.ID,
+		Commission:      t.Commission,
+		CommissionAsset: t.CommissionAsset,
+		RealizedPnL:     t.RealizedPnl,
+		ExchangeTime:    t.Time,
+		Source:          SourceReconcile,
+	}
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -0,0 +1,76 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVHeader 导出 CSV 的列顺序
+var CSVHeader = []string{
+	"seq", "time", "kind", "strategy", "symbol", "side", "position_side", "order_type",
+	"client_order_id", "order_id", "status", "quantity", "price", "filled_qty", "fill_price",
+	"trade_id", "commission", "commission_asset", "realized_pnl", "exchange_time", "error", "source", "account",
+}
+
+// WriteCSV 把记录按 CSVHeader 的列顺序写出，时间列为 UTC RFC3339 (毫秒精度)
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		row := []string{
+			strconv.FormatUint(e.Seq, 10),
+			formatMillis(e.Time),
+			e.Kind,
+			e.Strategy,
+			e.Symbol,
+			e.Side,
+			e.PositionSide,
+			e.OrderType,
+			e.ClientOrderID,
+			formatInt(e.OrderID),
+			e.Status,
+			formatFloat(e.Quantity),
+			formatFloat(e.Price),
+			formatFloat(e.FilledQty),
+			formatFloat(e.FillPrice),
+			formatInt(e.TradeID),
+			formatFloat(e.Commission),
+			e.CommissionAsset,
+			formatFloat(e.RealizedPnL),
+			formatMillis(e.ExchangeTime),
+			e.Error,
+			e.Source,
+			e.Account,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatMillis(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+func formatInt(v int64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func formatFloat(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
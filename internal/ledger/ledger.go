@@ -0,0 +1,202 @@
+package ledger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 记录类型
+const (
+	KindOrderRequest  = "order_request"  // 网关收到的下单指令
+	KindOrderAck      = "order_ack"      // 交易所回执 (REST 或 WebSocket API)
+	KindOrderReject   = "order_reject"   // 下单失败 (交易所拒单或网络错误)
+	KindCancelRequest = "cancel_request" // 网关收到的撤单指令
+	KindCancelAck     = "cancel_ack"     // 撤单 REST 回执
+	KindCancelReject  = "cancel_reject"  // 撤单失败
+	KindModifyRequest = "modify_request" // 网关收到的改单指令
+	KindModifyAck     = "modify_ack"     // 改单回执
+	KindModifyReject  = "modify_reject"  // 改单失败
+	KindFill          = "fill"           // ORDER_TRADE_UPDATE 成交 (x == TRADE)
+	KindCanceled      = "canceled"       // ORDER_TRADE_UPDATE 撤销 / 过期
+)
+
+var entriesBucket = []byte("entries")
+
+// Entry 账本中的一条记录；不同类型只填写相关字段
+type Entry struct {
+	Seq             uint64  `json:"seq"`
+	Time            int64   `json:"time"` // 记录时间 (毫秒)
+	Kind            string  `json:"kind"`
+	Strategy        string  `json:"strategy,omitempty"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side,omitempty"`
+	PositionSide    string  `json:"position_side,omitempty"`
+	OrderType       string  `json:"order_type,omitempty"`
+	ClientOrderID   string  `json:"client_order_id,omitempty"`
+	OrderID         int64   `json:"order_id,omitempty"`
+	Status          string  `json:"status,omitempty"`
+	Quantity        float64 `json:"quantity,omitempty"`
+	Price           float64 `json:"price,omitempty"`
+	FilledQty       float64 `json:"filled_qty,omitempty"`
+	FillPrice       float64 `json:"fill_price,omitempty"`
+	TradeID         int64   `json:"trade_id,omitempty"`
+	Commission      float64 `json:"commission,omitempty"`
+	CommissionAsset string  `json:"commission_asset,omitempty"`
+	RealizedPnL     float64 `json:"realized_pnl,omitempty"`
+	ExchangeTime    int64   `json:"exchange_time,omitempty"` // 交易所撮合 / 回执时间 (毫秒)
+	Error           string  `json:"error,omitempty"`
+	Source          string  `json:"source,omitempty"`  // 空为实时链路；reconcile 表示由对账补录
+	Account         string  `json:"account,omitempty"` // 多账户模式下记录所属账户
+}
+
+// SourceReconcile 对账补录记录的来源标记
+const SourceReconcile = "reconcile"
+
+// Filter 查询条件，零值字段表示不过滤；From / To 为毫秒时间戳，区间为 [From, To]
+type Filter struct {
+	Account  string
+	Strategy string
+	Symbol   string
+	Kind     string
+	From     int64
+	To       int64
+	Limit    int
+}
+
+func (f Filter) match(e *Entry) bool {
+	return (f.Account == "" || f.Account == e.Account) &&
+		(f.Strategy == "" || f.Strategy == e.Strategy) &&
+		(f.Symbol == "" || f.Symbol == e.Symbol) &&
+		(f.Kind == "" || f.Kind == e.Kind)
+}
+
+// Store 基于 bbolt 的嵌入式账本，Key 为 8 字节时间戳 + 8 字节序号 (大端)，按时间顺序存储，
+// 时间范围查询直接做游标区间扫描
+type Store struct {
+	db *bolt.DB
+}
+
+// Open 打开 (必要时创建) 账本文件；readOnly 用于导出工具，网关运行期间文件被独占锁定
+func Open(path string, readOnly bool) (*Store, error) {
+	if !readOnly {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, err
+	}
+	if !readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(entriesBucket)
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭账本文件
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Append 写入一条记录，Time 为空时取当前时间；返回分配的序号
+// 每次调用是一个独立事务 (一次 fsync)，交易链路上应改用 Writer 异步批量写入
+func (s *Store) Append(e Entry) (uint64, error) {
+	entries := []Entry{e}
+	err := s.AppendBatch(entries)
+	return entries[0].Seq, err
+}
+
+// AppendBatch 在同一个事务中按顺序写入多条记录 (只 fsync 一次)，并把分配的序号与时间回填到 entries
+func (s *Store) AppendBatch(entries []Entry) error {
+	now := time.Now().UnixMilli()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(entriesBucket)
+		for i := range entries {
+			e := &entries[i]
+			if e.Time == 0 {
+				e.Time = now
+			}
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			e.Seq = seq
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(entryKey(e.Time, seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Query 按时间顺序返回满足条件的记录
+func (s *Store) Query(f Filter) ([]Entry, error) {
+	var out []Entry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(entriesBucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(entryKey(f.From, 0)); k != nil; k, v = c.Next() {
+			if f.To > 0 && int64(binary.BigEndian.Uint64(k[:8])) > f.To {
+				break
+			}
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if !f.match(&e) {
+				continue
+			}
+			out = append(out, e)
+			if f.Limit > 0 && len(out) >= f.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func entryKey(ms int64, seq uint64) []byte {
+	if ms < 0 {
+		ms = 0
+	}
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k[:8], uint64(ms))
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
+
+// ParseTime 解析毫秒时间戳或 RFC3339 时间，空字符串返回 0 (不限制)
+func ParseTime(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixMilli(), nil
+}
@@ -0,0 +1,208 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sub", "ledger.db")
+	s, err := Open(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func TestAppendAndQuery(t *testing.T) {
+	s, _ := openTestStore(t)
+	defer s.Close()
+
+	records := []Entry{
+		{Time: 3000, Kind: KindFill, Strategy: "grid", Symbol: "BTCUSDT"},
+		{Time: 1000, Kind: KindOrderRequest, Strategy: "grid", Symbol: "BTCUSDT"},
+		{Time: 2000, Kind: KindOrderRequest, Strategy: "mm", Symbol: "ETHUSDT", Account: "beta"},
+		{Time: 4000, Kind: KindCanceled, Strategy: "grid", Symbol: "ETHUSDT", Account: "beta"},
+	}
+	for _, e := range records {
+		if _, err := s.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := s.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || all[0].Time != 1000 || all[3].Time != 4000 {
+		t.Fatalf("entries should be returned in time order: %+v", all)
+	}
+	if all[0].Seq != 2 {
+		t.Errorf("expected seq 2 for second append, got %d", all[0].Seq)
+	}
+
+	grid, _ := s.Query(Filter{Strategy: "grid", Symbol: "BTCUSDT"})
+	if len(grid) != 2 {
+		t.Errorf("expected 2 grid BTC entries, got %d", len(grid))
+	}
+
+	beta, _ := s.Query(Filter{Account: "beta"})
+	if len(beta) != 2 || beta[0].Strategy != "mm" {
+		t.Errorf("expected 2 entries for account beta, got %+v", beta)
+	}
+
+	ranged, _ := s.Query(Filter{From: 2000, To: 3000})
+	if len(ranged) != 2 || ranged[0].Time != 2000 || ranged[1].Time != 3000 {
+		t.Errorf("time range should be inclusive: %+v", ranged)
+	}
+
+	limited, _ := s.Query(Filter{Kind: KindOrderRequest, Limit: 1})
+	if len(limited) != 1 || limited[0].Strategy != "grid" {
+		t.Errorf("unexpected limited result: %+v", limited)
+	}
+}
+
+func TestReopenPersists(t *testing.T) {
+	s, path := openTestStore(t)
+	if _, err := s.Append(Entry{Kind: KindOrderAck, Symbol: "BTCUSDT", OrderID: 42}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	ro, err := Open(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	entries, err := ro.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].OrderID != 42 || entries[0].Time == 0 {
+		t.Errorf("entry should survive reopen with a timestamp: %+v", entries)
+	}
+}
+
+func TestFromOrderUpdate(t *testing.T) {
+	var event binance.UserDataEvent
+	raw := `{"e":"ORDER_TRADE_UPDATE","E":1,"T":1,"o":{"s":"BTCUSDT","c":"grid_17","S":"BUY","o":"LIMIT",
+		"q":"0.2","p":"50000","x":"TRADE","X":"FILLED","i":8,"l":"0.2","z":"0.2","L":"49990",
+		"N":"USDT","n":"4","T":123,"t":9,"ps":"BOTH","rp":"1.5"}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := FromOrderUpdate(event.Order)
+	if !ok || e.Kind != KindFill {
+		t.Fatalf("TRADE should produce a fill entry, got %+v", e)
+	}
+	if e.FilledQty != 0.2 || e.FillPrice != 49990 || e.TradeID != 9 || e.Commission != 4 || e.RealizedPnL != 1.5 {
+		t.Errorf("unexpected fill entry: %+v", e)
+	}
+
+	event.Order.ExecutionType = "EXPIRED"
+	if e, ok := FromOrderUpdate(event.Order); !ok || e.Kind != KindCanceled {
+		t.Errorf("EXPIRED should produce a canceled entry, got %+v", e)
+	}
+	event.Order.ExecutionType = "NEW"
+	if _, ok := FromOrderUpdate(event.Order); ok {
+		t.Error("NEW should be skipped")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []Entry{
+		{Seq: 1, Time: 1700000000123, Kind: KindFill, Strategy: "grid", Symbol: "BTCUSDT", FilledQty: 0.01, FillPrice: 50000.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rows[1]) != len(CSVHeader) {
+		t.Fatalf("unexpected csv shape: %v", rows)
+	}
+	if rows[1][1] != "2023-11-14T22:13:20.123Z" || rows[1][13] != "0.01" || rows[1][14] != "50000.5" {
+		t.Errorf("unexpected csv row: %v", rows[1])
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	if v, _ := ParseTime("1700000000000"); v != 1700000000000 {
+		t.Errorf("millis not parsed: %d", v)
+	}
+	if v, _ := ParseTime("2023-11-14T22:13:20Z"); v != 1700000000000 {
+		t.Errorf("RFC3339 not parsed: %d", v)
+	}
+	if _, err := ParseTime("yesterday"); err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
+
+func TestWriter_BatchesInOrder(t *testing.T) {
+	s, _ := openTestStore(t)
+	defer s.Close()
+
+	var failed int
+	w := NewWriter(s, func(err error, entries []Entry) { failed += len(entries) })
+	const n = 1000
+	for i := 0; i < n; i++ {
+		w.Append(Entry{Kind: KindOrderRequest, Symbol: "BTCUSDT", OrderID: int64(i)})
+	}
+	w.Close()
+	if w.Append(Entry{Kind: KindFill}) {
+		t.Error("append after close should be rejected")
+	}
+	w.Close()
+
+	got, err := s.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 0 || len(got) != n {
+		t.Fatalf("expected %d committed entries, got %d (failed %d)", n, len(got), failed)
+	}
+	// 序号按入队顺序分配；同一毫秒内的记录按序号排列，整体顺序与入队一致
+	for i, e := range got {
+		if e.OrderID != int64(i) || e.Seq != uint64(i+1) || e.Time == 0 {
+			t.Fatalf("entry %d out of order: %+v", i, e)
+		}
+	}
+}
+
+// BenchmarkAppend 每条记录一个事务 (一次 fsync)，即交易链路上同步写账本的开销
+func BenchmarkAppend(b *testing.B) {
+	s, err := Open(filepath.Join(b.TempDir(), "ledger.db"), false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Append(Entry{Kind: KindOrderRequest, Symbol: "BTCUSDT"})
+	}
+}
+
+// BenchmarkWriterAppend 调用方只付出入队的开销，提交在写协程中批量完成 (计时包含最终 Close 的全部提交)
+func BenchmarkWriterAppend(b *testing.B) {
+	s, err := Open(filepath.Join(b.TempDir(), "ledger.db"), false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	w := NewWriter(s, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Append(Entry{Kind: KindOrderRequest, Symbol: "BTCUSDT"})
+	}
+	w.Close()
+}
@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"sync"
+	"time"
+)
+
+// 异步写入参数
+const (
+	writerBuffer   = 4096 // 待写入记录的缓冲上限，写满时 Append 阻塞 (只在磁盘长时间卡住时出现)
+	writerMaxBatch = 256  // 单个事务最多合并的记录数
+)
+
+// Writer 把账本写入移出交易链路：Append 只把记录放入缓冲通道，单个写协程把已积攒的记录合并为一个事务提交，
+// 负载高时多条记录共用一次 fsync，空闲时第一条记录立即提交，不引入额外等待
+//
+// 代价：记录在提交前只存在于内存，进程崩溃会丢失最后一批尚未提交的记录 (通常不超过几毫秒)。
+// 交易所仍是订单与成交的权威来源，挂单与成交对账会在重启后补录缺失的成交
+type Writer struct {
+	store   *Store
+	ch      chan Entry
+	done    chan struct{}
+	onError func(err error, entries []Entry)
+
+	mu     sync.RWMutex // 保护 closed，防止关闭后仍有协程向通道写入
+	closed bool
+}
+
+// NewWriter 启动写协程；onError 在批量写入失败时调用 (可为 nil)
+func NewWriter(store *Store, onError func(err error, entries []Entry)) *Writer {
+	w := &Writer{
+		store:   store,
+		ch:      make(chan Entry, writerBuffer),
+		done:    make(chan struct{}),
+		onError: onError,
+	}
+	go w.run()
+	return w
+}
+
+// Append 记录入队；Time 为空时取入队时间，保证记录时间反映事件发生的时刻而不是提交时刻。
+// Close 之后的记录被丢弃并返回 false
+func (w *Writer) Append(e Entry) bool {
+	if e.Time == 0 {
+		e.Time = time.Now().UnixMilli()
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+	w.ch <- e
+	return true
+}
+
+// Close 停止接收新记录，提交缓冲中的全部记录后返回；可重复调用
+func (w *Writer) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.ch)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	batch := make([]Entry, 0, writerMaxBatch)
+	for e := range w.ch {
+		batch = append(batch[:0], e)
+	drain:
+		for len(batch) < writerMaxBatch {
+			select {
+			case next, ok := <-w.ch:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		if err := w.store.AppendBatch(batch); err != nil && w.onError != nil {
+			w.onError(err, batch)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package logging 基于 log/slog 的分组件日志：每个组件一个 Logger (自动带 component 属性)，
+// 级别可按组件单独配置，输出格式可选 text / json，高频路径可按消息采样
+//
+// 组件 Logger 可在包初始化时创建，Setup 之后的级别与格式变更对已创建的 Logger 立即生效
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSampleInterval 未配置采样间隔时，同一条高频日志的最短输出间隔
+const DefaultSampleInterval = time.Second
+
+// Options 日志配置
+type Options struct {
+	Level  string // debug / info / warn / error，留空为 info
+	Format string // text (默认) / json
+	// Components 按组件覆盖级别；组件名以 "." 分级，如 "binance" 同时作用于 "binance.ws" 与 "binance.user_stream"
+	Components     map[string]string
+	SampleInterval time.Duration // Sampled Logger 中同一条消息的最短输出间隔，0 表示 DefaultSampleInterval
+	Output         io.Writer     // 留空为 os.Stderr
+}
+
+// rootHandler 当前生效的底层输出 Handler，Setup 时整体替换
+type rootHandler struct{ h slog.Handler }
+
+var (
+	root           atomic.Pointer[rootHandler]
+	sampleInterval atomic.Int64
+
+	mu        sync.Mutex
+	levels    = make(map[string]*slog.LevelVar) // 已创建的组件 → 级别
+	defLevel  = slog.LevelInfo
+	overrides map[string]slog.Level
+)
+
+func init() {
+	root.Store(&rootHandler{h: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})})
+	sampleInterval.Store(int64(DefaultSampleInterval))
+}
+
+// ParseLevel 解析级别名称 (不区分大小写)
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("未知的日志级别 %q (可选 debug / info / warn / error)", s)
+}
+
+// Setup 应用配置并把 slog 默认 Logger (以及标准库 log 包的输出) 指向同一个 Handler
+func Setup(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	comp := make(map[string]slog.Level, len(opts.Components))
+	for name, s := range opts.Components {
+		l, err := ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("log.components.%s: %w", name, err)
+		}
+		comp[name] = l
+	}
+
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	// 级别过滤由组件 Handler 完成，底层 Handler 全部放行
+	hOpts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(out, hOpts)
+	case "json":
+		h = slog.NewJSONHandler(out, hOpts)
+	default:
+		return fmt.Errorf("未知的日志格式 %q (可选 text / json)", opts.Format)
+	}
+
+	interval := opts.SampleInterval
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+	sampleInterval.Store(int64(interval))
+
+	mu.Lock()
+	defLevel, overrides = level, comp
+	for name, lv := range levels {
+		lv.Set(resolveLevel(name))
+	}
+	mu.Unlock()
+
+	root.Store(&rootHandler{h: h})
+	slog.SetDefault(For(""))
+	return nil
+}
+
+// resolveLevel 按组件名逐级向上查找覆盖级别，调用方需持有 mu
+func resolveLevel(name string) slog.Level {
+	for n := name; n != ""; {
+		if l, ok := overrides[n]; ok {
+			return l
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			break
+		}
+		n = n[:i]
+	}
+	return defLevel
+}
+
+func levelVar(name string) *slog.LevelVar {
+	mu.Lock()
+	defer mu.Unlock()
+	lv, ok := levels[name]
+	if !ok {
+		lv = new(slog.LevelVar)
+		lv.Set(resolveLevel(name))
+		levels[name] = lv
+	}
+	return lv
+}
+
+// For 返回组件 Logger，每条记录带 component 属性；name 为空时不带 (用于默认 Logger)
+func For(name string) *slog.Logger {
+	return slog.New(newHandler(name, nil))
+}
+
+// Sampled 返回带采样的组件 Logger：同一条消息在采样间隔内只输出第一次，
+// 下一次输出时附带 suppressed 属性说明期间丢弃的条数。用于逐条深度推送等高频路径
+func Sampled(name string) *slog.Logger {
+	return slog.New(newHandler(name, &sampler{entries: make(map[string]*sampleEntry)}))
+}
+
+func newHandler(name string, s *sampler) *handler {
+	h := &handler{level: levelVar(name), sampler: s}
+	if name != "" {
+		h.ops = []handlerOp{{attrs: []slog.Attr{slog.String("component", name)}}}
+	}
+	return h
+}
+
+// handlerOp WithAttrs / WithGroup 的调用记录，底层 Handler 替换后按原顺序重放
+type handlerOp struct {
+	group string
+	attrs []slog.Attr
+}
+
+// handler 组件 Handler：按组件级别过滤、可选采样，再交给当前的底层 Handler 输出
+type handler struct {
+	level   *slog.LevelVar
+	ops     []handlerOp
+	sampler *sampler
+
+	cache atomic.Pointer[cachedHandler]
+}
+
+// cachedHandler 已重放 ops 的底层 Handler，底层 Handler 未变时直接复用
+type cachedHandler struct {
+	root *rootHandler
+	h    slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if h.sampler != nil {
+		ok, dropped := h.sampler.allow(r.Message, r.Time)
+		if !ok {
+			return nil
+		}
+		if dropped > 0 {
+			r.AddAttrs(slog.Int("suppressed", dropped))
+		}
+	}
+	return h.resolve().Handle(ctx, r)
+}
+
+func (h *handler) resolve() slog.Handler {
+	cur := root.Load()
+	if c := h.cache.Load(); c != nil && c.root == cur {
+		return c.h
+	}
+	out := cur.h
+	for _, op := range h.ops {
+		if op.group != "" {
+			out = out.WithGroup(op.group)
+		} else {
+			out = out.WithAttrs(op.attrs)
+		}
+	}
+	h.cache.Store(&cachedHandler{root: cur, h: out})
+	return out
+}
+
+func (h *handler) with(op handlerOp) *handler {
+	ops := make([]handlerOp, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{level: h.level, ops: append(ops, op), sampler: h.sampler}
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(handlerOp{attrs: attrs})
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(handlerOp{group: name})
+}
+
+// sampler 按消息文本采样，With 派生的 Logger 共用同一个 sampler
+type sampler struct {
+	mu      sync.Mutex
+	entries map[string]*sampleEntry
+}
+
+type sampleEntry struct {
+	last    time.Time
+	dropped int
+}
+
+func (s *sampler) allow(msg string, now time.Time) (bool, int) {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	interval := time.Duration(sampleInterval.Load())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[msg]
+	if !ok {
+		s.entries[msg] = &sampleEntry{last: now}
+		return true, 0
+	}
+	if now.Sub(e.last) < interval {
+		e.dropped++
+		return false, 0
+	}
+	dropped := e.dropped
+	e.last, e.dropped = now, 0
+	return true, dropped
+}
+
+// Err 错误属性，统一使用 err 作为键
+func Err(err error) slog.Attr {
+	return slog.Any("err", err)
+}
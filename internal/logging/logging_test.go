@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+	ws := For("binance.ws") // 在 Setup 之前创建，配置变更后同样生效
+	if err := Setup(Options{Level: "warn", Components: map[string]string{"binance": "debug"}, Output: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	main := For("main")
+
+	ws.Debug("dial")
+	main.Info("hidden")
+	main.Warn("shown")
+
+	out := buf.String()
+	if !strings.Contains(out, "component=binance.ws") || !strings.Contains(out, "msg=dial") {
+		t.Errorf("sub-component should inherit the binance override: %q", out)
+	}
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown") {
+		t.Errorf("main should use the default warn level: %q", out)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(Options{Format: "json", Output: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	For("uds").With("symbol", "BTCUSDT").Info("order placed", "latency", 3*time.Millisecond, Err(errors.New("boom")))
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("output should be one JSON object: %v (%q)", err, buf.String())
+	}
+	if rec["component"] != "uds" || rec["symbol"] != "BTCUSDT" || rec["msg"] != "order placed" ||
+		rec["level"] != "INFO" || rec["err"] != "boom" {
+		t.Errorf("unexpected record: %v", rec)
+	}
+}
+
+func TestSampled(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(Options{SampleInterval: time.Hour, Output: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	l := Sampled("depth")
+	for i := 0; i < 5; i++ {
+		l.Info("depth event")
+	}
+	l.With("symbol", "ETHUSDT").Info("resync") // 不同消息分别计数，派生 Logger 共用采样状态
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Fatalf("expected 2 lines within one interval, got %d: %q", n, buf.String())
+	}
+
+	sampleInterval.Store(0) // 间隔到期后输出，并带上期间丢弃的条数
+	l.Info("depth event")
+	if !strings.Contains(buf.String(), "suppressed=4") {
+		t.Errorf("expected suppressed=4 on the next emitted line: %q", buf.String())
+	}
+}
+
+func TestSetup_RejectsUnknownValues(t *testing.T) {
+	if err := Setup(Options{Level: "verbose"}); err == nil {
+		t.Error("unknown level should be rejected")
+	}
+	if err := Setup(Options{Components: map[string]string{"book": "loud"}}); err == nil || !strings.Contains(err.Error(), "book") {
+		t.Errorf("unknown component level should name the component, got %v", err)
+	}
+	if err := Setup(Options{Format: "xml"}); err == nil {
+		t.Error("unknown format should be rejected")
+	}
+}
@@ -18,6 +18,11 @@ type LocalOrderBook struct {
 	IsReady      bool
 	Synced       bool
 	NeedsResync  bool // 序列号断层时标记需要重新同步
+
+	// 最后一条已应用深度事件的时间 (毫秒)，随快照发布供策略端判断数据新鲜度
+	lastEventTime   int64
+	lastTxTime      int64
+	lastReceiveTime int64
 }
 
 func NewLocalOrderBook(symbol string) *LocalOrderBook {
@@ -92,6 +97,11 @@ func (ob *LocalOrderBook) ProcessDepthEvent(event binance.WSDepthEvent) error {
 	ob.updateLevels(ob.Bids, event.Bids)
 	ob.updateLevels(ob.Asks, event.Asks)
 	ob.LastUpdateID = event.FinalUpdateID
+	ob.lastEventTime = event.EventTime
+	ob.lastTxTime = event.TransactionTime
+	if !event.ReceivedAt.IsZero() {
+		ob.lastReceiveTime = event.ReceivedAt.UnixMilli()
+	}
 
 	return nil
 }
@@ -129,17 +139,37 @@ func (ob *LocalOrderBook) CheckAndClearResync() bool {
 	return false
 }
 
+// state 状态机视角的数据质量：未就绪、未缝合或等待重同步时为 RESYNCING
+// STALE 由网关的停滞检测根据事件到达间隔判断，不在这里给出
+func (ob *LocalOrderBook) state() string {
+	if ob.IsReady && ob.Synced && !ob.NeedsResync {
+		return binance.BookStateSynced
+	}
+	return binance.BookStateResyncing
+}
+
+// Status 线程安全地读取快照是否已灌入 (ready) 与增量是否已缝合 (synced)
+func (ob *LocalOrderBook) Status() (ready, synced bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.IsReady, ob.Synced && !ob.NeedsResync
+}
+
 // GetTopN 提取排序后的前 N 档盘口快照
 func (ob *LocalOrderBook) GetTopN(n int) binance.OrderBookSnapshot {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
 
 	snap := binance.OrderBookSnapshot{
-		Symbol:       ob.Symbol,
-		LastUpdateID: ob.LastUpdateID,
-		Timestamp:    time.Now().UnixMilli(),
-		Bids:         make([]binance.PriceLevel, 0, len(ob.Bids)),
-		Asks:         make([]binance.PriceLevel, 0, len(ob.Asks)),
+		Symbol:          ob.Symbol,
+		LastUpdateID:    ob.LastUpdateID,
+		Timestamp:       time.Now().UnixMilli(),
+		EventTime:       ob.lastEventTime,
+		TransactionTime: ob.lastTxTime,
+		ReceiveTime:     ob.lastReceiveTime,
+		State:           ob.state(),
+		Bids:            make([]binance.PriceLevel, 0, len(ob.Bids)),
+		Asks:            make([]binance.PriceLevel, 0, len(ob.Asks)),
 	}
 
 	// 1. 提取所有 Bids 和 Asks
@@ -4,6 +4,7 @@ import (
 	"BinanceAutoBot2/internal/binance"
 	"sync"
 	"testing"
+	"time"
 )
 
 func makeSnapshot(lastUpdateID int64, bids, asks [][]string) *binance.RestDepthSnapshot {
@@ -121,6 +122,28 @@ func TestProcessDepthEvent_SequenceGapTriggersResync(t *testing.T) {
 	}
 }
 
+func TestStatus(t *testing.T) {
+	ob := NewLocalOrderBook("BTCUSDT")
+	if ready, synced := ob.Status(); ready || synced {
+		t.Errorf("new book should be neither ready nor synced, got %v/%v", ready, synced)
+	}
+
+	ob.InitWithSnapshot(makeSnapshot(100, nil, nil))
+	if ready, synced := ob.Status(); !ready || synced {
+		t.Errorf("book should be ready but not synced after snapshot, got %v/%v", ready, synced)
+	}
+
+	ob.ProcessDepthEvent(makeEvent(99, 101, 98, nil, nil))
+	if ready, synced := ob.Status(); !ready || !synced {
+		t.Errorf("book should be synced after stitching, got %v/%v", ready, synced)
+	}
+
+	ob.ProcessDepthEvent(makeEvent(1000, 1001, 999, nil, nil))
+	if ready, synced := ob.Status(); ready || synced {
+		t.Errorf("sequence gap should clear both flags, got %v/%v", ready, synced)
+	}
+}
+
 func TestProcessDepthEvent_NotReady(t *testing.T) {
 	ob := NewLocalOrderBook("BTCUSDT")
 	// 未初始化快照，直接处理事件应静默忽略
@@ -202,3 +225,29 @@ func TestConcurrentAccess(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestGetTopN_StateAndTimes(t *testing.T) {
+	ob := NewLocalOrderBook("BTCUSDT")
+	if s := ob.GetTopN(5).State; s != binance.BookStateResyncing {
+		t.Errorf("book without snapshot should be RESYNCING, got %s", s)
+	}
+
+	ob.InitWithSnapshot(makeSnapshot(100, nil, nil))
+	ev := makeEvent(99, 101, 98, nil, nil)
+	ev.EventTime, ev.TransactionTime, ev.ReceivedAt = 1000, 990, time.UnixMilli(1005)
+	ob.ProcessDepthEvent(ev)
+
+	snap := ob.GetTopN(5)
+	if snap.State != binance.BookStateSynced {
+		t.Errorf("expected SYNCED after stitching, got %s", snap.State)
+	}
+	if snap.EventTime != 1000 || snap.TransactionTime != 990 || snap.ReceiveTime != 1005 || snap.Timestamp < snap.ReceiveTime {
+		t.Errorf("unexpected timestamps: %+v", snap)
+	}
+
+	// 断层后在重新拉取快照之前应标记为 RESYNCING
+	ob.ProcessDepthEvent(makeEvent(1000, 1001, 999, nil, nil))
+	if s := ob.GetTopN(5).State; s != binance.BookStateResyncing {
+		t.Errorf("expected RESYNCING after sequence gap, got %s", s)
+	}
+}
@@ -0,0 +1,381 @@
+package pnl
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// Fill 一笔成交，来自 ORDER_TRADE_UPDATE (x == TRADE)
+type Fill struct {
+	Symbol          string
+	ClientOrderID   string
+	Side            string // BUY / SELL
+	PositionSide    string // BOTH / LONG / SHORT
+	Qty             float64
+	Price           float64
+	Commission      float64
+	CommissionAsset string
+	RealizedPnL     float64 // 交易所回报的本笔已实现盈亏 (rp / realizedPnl)，用于核对本地计算
+	TradeTime       int64
+}
+
+// InheritedStrategy 网关启动前已有的持仓 (由 Seed 载入，归属未知) 所在的策略维度
+const InheritedStrategy = "inherited"
+
+// Stats 某个维度 (交易对或策略) 的盈亏汇总，金额单位均为 USDT
+type Stats struct {
+	Position            float64            `json:"position"`             // 净持仓 (多为正，空为负)
+	EntryPrice          float64            `json:"entry_price"`          // 加权平均开仓价，多个策略汇总时为 0
+	MarkPrice           float64            `json:"mark_price,omitempty"` // 仅交易对维度
+	RealizedPnL         float64            `json:"realized_pnl"`
+	ExchangeRealizedPnL float64            `json:"exchange_realized_pnl"` // 交易所回报 (rp) 的已实现盈亏累计，用于核对本地计算
+	UnrealizedPnL       float64            `json:"unrealized_pnl"`
+	Commission          float64            `json:"commission"`           // 以 USDT 计的手续费
+	OtherFees           map[string]float64 `json:"other_fees,omitempty"` // 非 USDT 手续费 (如 BNB 抵扣)，按币种累计
+	Funding             float64            `json:"funding"`              // 资金费，收入为正，支出为负
+	NetPnL              float64            `json:"net_pnl"`              // 已实现 + 未实现 - USDT 手续费 + 资金费
+	Volume              float64            `json:"volume"`               // 累计成交额
+	TradeCount          int                `json:"trade_count"`
+	UpdateTime          int64              `json:"update_time,omitempty"` // 最近一次成交时间
+}
+
+// Report 全部交易对与策略的盈亏快照
+type Report struct {
+	Symbols    map[string]Stats `json:"symbols"`
+	Strategies map[string]Stats `json:"strategies"`
+	Total      Stats            `json:"total"`
+}
+
+// book 单个 (策略, 交易对, 持仓方向) 的持仓与累计盈亏，采用加权平均成本法
+type book struct {
+	strategy     string
+	symbol       string
+	qty          float64
+	avgPrice     float64
+	realized     float64
+	exchangeRPnL float64 // 交易所回报的已实现盈亏累计
+	commission   float64
+	otherFees    map[string]float64
+	funding      float64
+	volume       float64
+	tradeCount   int
+	lastFillTime int64
+}
+
+// apply 按加权平均成本法更新持仓，平仓部分计入已实现盈亏
+func (b *book) apply(signedQty, price float64) {
+	switch {
+	case b.qty == 0 || sameSign(b.qty, signedQty):
+		// 开仓或加仓：重新计算均价
+		total := math.Abs(b.qty) + math.Abs(signedQty)
+		b.avgPrice = (b.avgPrice*math.Abs(b.qty) + price*math.Abs(signedQty)) / total
+		b.qty += signedQty
+	default:
+		// 减仓、平仓或反手
+		closeQty := math.Min(math.Abs(signedQty), math.Abs(b.qty))
+		b.realized += closeQty * (price - b.avgPrice) * sign(b.qty)
+		remaining := b.qty + signedQty
+		switch {
+		case math.Abs(remaining) < 1e-12:
+			b.qty, b.avgPrice = 0, 0
+		case !sameSign(remaining, b.qty):
+			// 反手：剩余部分按本次成交价开新仓
+			b.qty, b.avgPrice = remaining, price
+		default:
+			b.qty = remaining
+		}
+	}
+}
+
+// Engine 实时盈亏引擎：消费成交、标记价格与资金费，按交易对和策略两个维度汇总
+type Engine struct {
+	mu         sync.RWMutex
+	books      map[string]*book // key: strategy|symbol|positionSide
+	markPrices map[string]float64
+	quoteAsset string
+}
+
+// NewEngine 创建盈亏引擎，quoteAsset 为计价币种 (U本位合约为 USDT)
+func NewEngine(quoteAsset string) *Engine {
+	return &Engine{
+		books:      make(map[string]*book),
+		markPrices: make(map[string]float64),
+		quoteAsset: quoteAsset,
+	}
+}
+
+// StrategyFromClientOrderID 从 clientOrderId 中解析策略标识
+// 约定 clientOrderId 为 "<strategy>_<序号>"，取最后一个下划线之前的部分；没有下划线时归为 "manual"
+func StrategyFromClientOrderID(clientOrderID string) string {
+	if i := strings.LastIndex(clientOrderID, "_"); i > 0 {
+		return clientOrderID[:i]
+	}
+	return "manual"
+}
+
+func bookKey(strategy, symbol, positionSide string) string {
+	if positionSide == "" {
+		positionSide = "BOTH"
+	}
+	return strategy + "|" + symbol + "|" + positionSide
+}
+
+func (e *Engine) book(strategy, symbol, positionSide string) *book {
+	key := bookKey(strategy, symbol, positionSide)
+	b, ok := e.books[key]
+	if !ok {
+		b = &book{strategy: strategy, symbol: symbol, otherFees: make(map[string]float64)}
+		e.books[key] = b
+	}
+	return b
+}
+
+// Seed 载入网关启动前已有的持仓 (positionRisk 的数量与开仓均价)，记在 InheritedStrategy 名下；
+// 之后超出策略自身持仓的反向成交先平掉这部分持仓，已实现盈亏按载入的均价计入成交所属策略，
+// 而不是在策略名下开出方向相反的幽灵仓位
+func (e *Engine) Seed(symbol, positionSide string, qty, entryPrice float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	b := e.book(InheritedStrategy, symbol, positionSide)
+	b.qty, b.avgPrice = qty, entryPrice
+	if qty == 0 {
+		b.avgPrice = 0
+	}
+}
+
+// OnFill 处理一笔成交
+func (e *Engine) OnFill(f Fill) {
+	if f.Qty == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	strategy := StrategyFromClientOrderID(f.ClientOrderID)
+	b := e.book(strategy, f.Symbol, f.PositionSide)
+	signedQty := f.Qty
+	if f.Side == "SELL" {
+		signedQty = -f.Qty
+	}
+	if strategy != InheritedStrategy {
+		signedQty = e.closeInherited(b, f, signedQty)
+	}
+	if signedQty != 0 {
+		b.apply(signedQty, f.Price)
+	}
+	b.exchangeRPnL += f.RealizedPnL
+
+	if f.CommissionAsset == "" || f.CommissionAsset == e.quoteAsset {
+		b.commission += f.Commission
+	} else {
+		b.otherFees[f.CommissionAsset] += f.Commission
+	}
+	b.volume += f.Qty * f.Price
+	b.tradeCount++
+	b.lastFillTime = f.TradeTime
+}
+
+// closeInherited 成交中超出策略 b 自身反向持仓的部分先平掉同一交易对 / 方向上启动前的持仓，
+// 已实现盈亏计入 b；返回仍需由 b 自身处理的带符号数量
+func (e *Engine) closeInherited(b *book, f Fill, signedQty float64) float64 {
+	inh, ok := e.books[bookKey(InheritedStrategy, f.Symbol, f.PositionSide)]
+	if !ok || inh.qty == 0 || sameSign(inh.qty, signedQty) {
+		return signedQty
+	}
+	var own float64
+	if b.qty != 0 && !sameSign(b.qty, signedQty) {
+		own = math.Min(math.Abs(signedQty), math.Abs(b.qty))
+	}
+	closeQty := math.Min(math.Abs(signedQty)-own, math.Abs(inh.qty))
+	if closeQty <= 0 {
+		return signedQty
+	}
+	b.realized += closeQty * (f.Price - inh.avgPrice) * sign(inh.qty)
+	inh.qty += sign(signedQty) * closeQty
+	if math.Abs(inh.qty) < 1e-12 {
+		inh.qty, inh.avgPrice = 0, 0
+	}
+	return signedQty - sign(signedQty)*closeQty
+}
+
+// OnMarkPrice 更新标记价格，未实现盈亏在生成报告时按最新标记价计算
+func (e *Engine) OnMarkPrice(symbol string, price float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.markPrices[symbol] = price
+}
+
+// OnFunding 记录一次资金费 (amount 为钱包余额变化量，支出为负)
+// symbol 为空时 (全仓资金费推送不带仓位信息)，按各交易对持仓名义价值比例分摊；
+// 同一交易对内再按各策略持仓数量比例分摊
+func (e *Engine) OnFunding(symbol string, amount float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if symbol != "" {
+		e.allocateFunding(symbol, amount)
+		return
+	}
+
+	notional := make(map[string]float64)
+	var total float64
+	for _, b := range e.books {
+		n := math.Abs(b.qty) * e.priceFor(b)
+		notional[b.symbol] += n
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+	for sym, n := range notional {
+		e.allocateFunding(sym, amount*n/total)
+	}
+}
+
+func (e *Engine) allocateFunding(symbol string, amount float64) {
+	var totalQty float64
+	var holders []*book
+	for _, b := range e.books {
+		if b.symbol == symbol && b.qty != 0 {
+			totalQty += math.Abs(b.qty)
+			holders = append(holders, b)
+		}
+	}
+	if totalQty == 0 {
+		// 本地没有持仓记录 (如网关重启前开的仓)，记到手工仓位名下，保证总额不丢
+		e.book("manual", symbol, "BOTH").funding += amount
+		return
+	}
+	for _, b := range holders {
+		b.funding += amount * math.Abs(b.qty) / totalQty
+	}
+}
+
+// priceFor 返回计算未实现盈亏使用的价格：优先标记价格，没有时退化为均价 (即未实现盈亏为 0)
+func (e *Engine) priceFor(b *book) float64 {
+	if p, ok := e.markPrices[b.symbol]; ok && p > 0 {
+		return p
+	}
+	return b.avgPrice
+}
+
+// Report 生成当前盈亏快照
+func (e *Engine) Report() Report {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	r := Report{
+		Symbols:    make(map[string]Stats),
+		Strategies: make(map[string]Stats),
+	}
+	for _, b := range e.books {
+		unrealized := b.qty * (e.priceFor(b) - b.avgPrice)
+		s := Stats{
+			Position:            b.qty,
+			EntryPrice:          b.avgPrice,
+			RealizedPnL:         b.realized,
+			UnrealizedPnL:       unrealized,
+			ExchangeRealizedPnL: b.exchangeRPnL,
+			Commission:          b.commission,
+			OtherFees:           b.otherFees,
+			Funding:             b.funding,
+			Volume:              b.volume,
+			TradeCount:          b.tradeCount,
+			UpdateTime:          b.lastFillTime,
+		}
+
+		sym, seen := r.Symbols[b.symbol]
+		sym = merge(sym, s, !seen)
+		sym.MarkPrice = e.markPrices[b.symbol]
+		r.Symbols[b.symbol] = sym
+
+		strat, seen := r.Strategies[b.strategy]
+		r.Strategies[b.strategy] = merge(strat, s, !seen)
+		r.Total = merge(r.Total, s, false)
+	}
+	r.Total.Position, r.Total.EntryPrice = 0, 0 // 跨交易对的持仓数量没有意义
+	return r
+}
+
+// merge 把 s 累加到 acc；仅有一个来源 (first) 时保留均价，多个来源汇总后均价无意义置 0
+func merge(acc, s Stats, first bool) Stats {
+	acc.Position += s.Position
+	if first {
+		acc.EntryPrice = s.EntryPrice
+	} else {
+		acc.EntryPrice = 0
+	}
+	acc.RealizedPnL += s.RealizedPnL
+	acc.ExchangeRealizedPnL += s.ExchangeRealizedPnL
+	acc.UnrealizedPnL += s.UnrealizedPnL
+	acc.Commission += s.Commission
+	for asset, fee := range s.OtherFees {
+		if acc.OtherFees == nil {
+			acc.OtherFees = make(map[string]float64)
+		}
+		acc.OtherFees[asset] += fee
+	}
+	acc.Funding += s.Funding
+	acc.Volume += s.Volume
+	acc.TradeCount += s.TradeCount
+	if s.UpdateTime > acc.UpdateTime {
+		acc.UpdateTime = s.UpdateTime
+	}
+	acc.NetPnL = acc.RealizedPnL + acc.UnrealizedPnL - acc.Commission + acc.Funding
+	return acc
+}
+
+func sign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+func sameSign(a, b float64) bool {
+	return (a > 0 && b > 0) || (a < 0 && b < 0)
+}
+
+// FillFromUserTrade 从 /fapi/v1/userTrades 的成交记录构造成交，用于对账补记漏接的推送
+func FillFromUserTrade(t binance.UserTrade, clientOrderID string) Fill {
+	return Fill{
+		Symbol:          t.Symbol,
+		ClientOrderID:   clientOrderID,
+		Side:            t.Side,
+		PositionSide:    t.PositionSide,
+		Qty:             t.Qty,
+		Price:           t.Price,
+		Commission:      t.Commission,
+		CommissionAsset: t.CommissionAsset,
+		RealizedPnL:     t.RealizedPnl,
+		TradeTime:       t.Time,
+	}
+}
+
+// FillFromOrderUpdate 从 ORDER_TRADE_UPDATE 中提取成交 (TRADE，以及强平 / ADL 的 CALCULATED)；
+// 非成交事件 (NEW / CANCELED 等) 返回 false
+func FillFromOrderUpdate(o *binance.OrderTradeUpdate) (Fill, bool) {
+	if o == nil || (o.ExecutionType != "TRADE" && o.ExecutionType != "CALCULATED") {
+		return Fill{}, false
+	}
+	qty, _ := strconv.ParseFloat(o.LastFilledQty, 64)
+	price, _ := strconv.ParseFloat(o.LastFilledPrice, 64)
+	commission, _ := strconv.ParseFloat(o.Commission, 64)
+	realized, _ := strconv.ParseFloat(o.RealizedProfit, 64)
+	return Fill{
+		Symbol:          o.Symbol,
+		ClientOrderID:   o.ClientOrderID,
+		Side:            o.Side,
+		PositionSide:    o.PositionSide,
+		Qty:             qty,
+		Price:           price,
+		Commission:      commission,
+		CommissionAsset: o.CommissionAsset,
+		RealizedPnL:     realized,
+		TradeTime:       o.TradeTime,
+	}, qty > 0
+}
@@ -0,0 +1,192 @@
+package pnl
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestStrategyFromClientOrderID(t *testing.T) {
+	cases := map[string]string{
+		"grid_1700000000000":   "grid",
+		"mm_btc_1700000000000": "mm_btc",
+		"web_manual":           "web",
+		"x-abc":                "manual",
+		"":                     "manual",
+		"_1700000000000":       "manual",
+	}
+	for id, want := range cases {
+		if got := StrategyFromClientOrderID(id); got != want {
+			t.Errorf("StrategyFromClientOrderID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestOnFill_AverageCostAndRealized(t *testing.T) {
+	e := NewEngine("USDT")
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_1", Side: "BUY", Qty: 1, Price: 100, Commission: 0.1, CommissionAsset: "USDT"})
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_2", Side: "BUY", Qty: 1, Price: 200, Commission: 0.1, CommissionAsset: "USDT"})
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_3", Side: "SELL", Qty: 1, Price: 180, Commission: 0.1, CommissionAsset: "USDT"})
+	e.OnMarkPrice("BTCUSDT", 160)
+
+	s := e.Report().Strategies["grid"]
+	if !almostEqual(s.Position, 1) || !almostEqual(s.EntryPrice, 150) {
+		t.Errorf("expected 1 @ 150, got %v @ %v", s.Position, s.EntryPrice)
+	}
+	if !almostEqual(s.RealizedPnL, 30) {
+		t.Errorf("expected realized 30, got %v", s.RealizedPnL)
+	}
+	if !almostEqual(s.UnrealizedPnL, 10) {
+		t.Errorf("expected unrealized 10, got %v", s.UnrealizedPnL)
+	}
+	if !almostEqual(s.Commission, 0.3) || !almostEqual(s.NetPnL, 39.7) {
+		t.Errorf("expected commission 0.3 / net 39.7, got %v / %v", s.Commission, s.NetPnL)
+	}
+	if s.TradeCount != 3 || !almostEqual(s.Volume, 480) {
+		t.Errorf("unexpected trade count / volume: %d / %v", s.TradeCount, s.Volume)
+	}
+}
+
+func TestOnFill_Reversal(t *testing.T) {
+	e := NewEngine("USDT")
+	e.OnFill(Fill{Symbol: "ETHUSDT", ClientOrderID: "mm_1", Side: "BUY", Qty: 2, Price: 3000})
+	e.OnFill(Fill{Symbol: "ETHUSDT", ClientOrderID: "mm_2", Side: "SELL", Qty: 3, Price: 2900})
+
+	s := e.Report().Symbols["ETHUSDT"]
+	if !almostEqual(s.RealizedPnL, -200) {
+		t.Errorf("expected realized -200, got %v", s.RealizedPnL)
+	}
+	if !almostEqual(s.Position, -1) || !almostEqual(s.EntryPrice, 2900) {
+		t.Errorf("expected -1 @ 2900 after reversal, got %v @ %v", s.Position, s.EntryPrice)
+	}
+}
+
+func TestSeed_InheritedPositionClosedByStrategyFill(t *testing.T) {
+	e := NewEngine("USDT")
+	// 重启前 grid 开的 2 @ 100 多仓：重启后只能从 positionRisk 载入
+	e.Seed("BTCUSDT", "BOTH", 2, 100)
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_9", Side: "SELL", Qty: 1.5, Price: 120, RealizedPnL: 30})
+	e.OnMarkPrice("BTCUSDT", 110)
+
+	r := e.Report()
+	grid, inherited := r.Strategies["grid"], r.Strategies[InheritedStrategy]
+	if !almostEqual(grid.Position, 0) || !almostEqual(grid.RealizedPnL, 30) || !almostEqual(grid.ExchangeRealizedPnL, 30) {
+		t.Errorf("closing fill should realize against the inherited cost, not open a short: %+v", grid)
+	}
+	if !almostEqual(inherited.Position, 0.5) || !almostEqual(inherited.UnrealizedPnL, 5) {
+		t.Errorf("inherited position should shrink to 0.5: %+v", inherited)
+	}
+	if sym := r.Symbols["BTCUSDT"]; !almostEqual(sym.Position, 0.5) {
+		t.Errorf("symbol position should match the exchange (0.5), got %v", sym.Position)
+	}
+
+	// 超出启动前持仓的部分才在策略名下反手开仓
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_10", Side: "SELL", Qty: 1, Price: 100})
+	grid = e.Report().Strategies["grid"]
+	if !almostEqual(grid.Position, -0.5) || !almostEqual(grid.EntryPrice, 100) || !almostEqual(grid.RealizedPnL, 30) {
+		t.Errorf("only the excess should open a short for the strategy: %+v", grid)
+	}
+}
+
+func TestOnFill_NonQuoteCommission(t *testing.T) {
+	e := NewEngine("USDT")
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_1", Side: "BUY", Qty: 1, Price: 100, Commission: 0.01, CommissionAsset: "BNB"})
+
+	total := e.Report().Total
+	if total.Commission != 0 || !almostEqual(total.OtherFees["BNB"], 0.01) {
+		t.Errorf("BNB fee should be tracked separately: %+v", total)
+	}
+}
+
+func TestOnFunding_Allocation(t *testing.T) {
+	e := NewEngine("USDT")
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "a_1", Side: "BUY", Qty: 3, Price: 100})
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "b_1", Side: "SELL", Qty: 1, Price: 100})
+	e.OnFill(Fill{Symbol: "ETHUSDT", ClientOrderID: "a_2", Side: "BUY", Qty: 4, Price: 100})
+
+	// 指定交易对：按持仓数量比例分摊
+	e.OnFunding("BTCUSDT", -4)
+	r := e.Report()
+	if !almostEqual(r.Symbols["BTCUSDT"].Funding, -4) {
+		t.Errorf("expected BTC funding -4, got %v", r.Symbols["BTCUSDT"].Funding)
+	}
+	if !almostEqual(r.Strategies["b"].Funding, -1) {
+		t.Errorf("expected strategy b funding -1, got %v", r.Strategies["b"].Funding)
+	}
+
+	// 未指定交易对：按名义价值分摊 (BTC 400，ETH 400)
+	e.OnFunding("", 2)
+	r = e.Report()
+	if !almostEqual(r.Symbols["ETHUSDT"].Funding, 1) {
+		t.Errorf("expected ETH funding 1, got %v", r.Symbols["ETHUSDT"].Funding)
+	}
+	if !almostEqual(r.Total.Funding, -2) {
+		t.Errorf("expected total funding -2, got %v", r.Total.Funding)
+	}
+
+	// 没有本地持仓的交易对记到 manual 名下
+	e.OnFunding("SOLUSDT", -0.5)
+	if !almostEqual(e.Report().Strategies["manual"].Funding, -0.5) {
+		t.Errorf("unowned funding should go to manual")
+	}
+}
+
+func TestFillFromOrderUpdate(t *testing.T) {
+	var event binance.UserDataEvent
+	raw := `{"e":"ORDER_TRADE_UPDATE","E":1,"T":1,"o":{"s":"BTCUSDT","c":"grid_17","S":"SELL","o":"LIMIT",
+		"q":"0.2","p":"50000","x":"TRADE","X":"PARTIALLY_FILLED","i":8,"l":"0.1","z":"0.1","L":"50010",
+		"N":"USDT","n":"2.0005","rp":"1.5","T":123,"t":9,"ps":"BOTH"}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := FillFromOrderUpdate(event.Order)
+	if !ok {
+		t.Fatal("expected TRADE execution to produce a fill")
+	}
+	if f.Symbol != "BTCUSDT" || f.ClientOrderID != "grid_17" || f.Side != "SELL" ||
+		f.Qty != 0.1 || f.Price != 50010 || f.Commission != 2.0005 || f.RealizedPnL != 1.5 || f.TradeTime != 123 {
+		t.Errorf("unexpected fill: %+v", f)
+	}
+
+	event.Order.ExecutionType = "NEW"
+	if _, ok := FillFromOrderUpdate(event.Order); ok {
+		t.Error("NEW execution should not produce a fill")
+	}
+	if _, ok := FillFromOrderUpdate(nil); ok {
+		t.Error("nil order should not produce a fill")
+	}
+}
+
+func TestFillFromOrderUpdate_Liquidation(t *testing.T) {
+	var event binance.UserDataEvent
+	raw := `{"e":"ORDER_TRADE_UPDATE","E":1,"T":1,"o":{"s":"BTCUSDT","c":"autoclose-1700000000000","S":"SELL","o":"LIQUIDATION",
+		"q":"1","p":"0","x":"CALCULATED","X":"FILLED","i":9,"l":"1","z":"1","L":"40000",
+		"N":"USDT","n":"20","T":456,"t":10,"ps":"BOTH"}}`
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := FillFromOrderUpdate(event.Order)
+	if !ok || f.Qty != 1 || f.Price != 40000 || f.Commission != 20 {
+		t.Fatalf("CALCULATED (liquidation / ADL) execution should produce a fill: %+v %v", f, ok)
+	}
+
+	// 强平成交 (归属 manual) 使交易对净持仓归零，交易对整体盈亏锁定为强平亏损，不再随标记价格变化
+	e := NewEngine("USDT")
+	e.OnFill(Fill{Symbol: "BTCUSDT", ClientOrderID: "grid_1", Side: "BUY", Qty: 1, Price: 50000})
+	e.OnFill(f)
+	for _, mark := range []float64{40000, 30000} {
+		e.OnMarkPrice("BTCUSDT", mark)
+		s := e.Report().Symbols["BTCUSDT"]
+		if !almostEqual(s.Position, 0) || !almostEqual(s.RealizedPnL+s.UnrealizedPnL, -10000) || !almostEqual(s.Commission, 20) {
+			t.Errorf("mark %v: liquidation should flatten the symbol with pnl -10000, got %+v", mark, s)
+		}
+	}
+}
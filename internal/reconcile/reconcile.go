@@ -0,0 +1,430 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// Exchange 对账所需的 REST 查询，*binance.APIClient 实现了该接口
+type Exchange interface {
+	GetOpenOrders(symbol string) ([]binance.OrderResponse, error)
+	GetUserTrades(symbol string, startTime int64, limit int) ([]binance.UserTrade, error)
+	QueryOrder(symbol, origClientOrderID string) (*binance.OrderResponse, error)
+}
+
+// 差异类型
+const (
+	TypeUnknownOrder = "unknown_order" // 交易所有挂单，本地没有跟踪 (漏掉 NEW 推送或在网关之外下单)
+	TypeGhostOrder   = "ghost_order"   // 本地认为仍在挂，交易所已成交 / 撤销 (漏掉终态推送)
+	TypeFilledQty    = "filled_qty"    // 双方都在挂，但已成交数量不一致 (漏掉部分成交推送)
+	TypeMissedFill   = "missed_fill"   // 交易所有成交记录，本地没有收到对应的 TRADE 推送
+)
+
+const (
+	userTradesLimit  = 1000
+	defaultGrace     = 5 * time.Second
+	defaultRetention = 24 * time.Hour
+)
+
+// Discrepancy 一次对账发现的差异，已按交易所状态修复本地记录
+type Discrepancy struct {
+	Type          string  `json:"type"`
+	Symbol        string  `json:"symbol"`
+	ClientOrderID string  `json:"client_order_id,omitempty"`
+	OrderID       int64   `json:"order_id,omitempty"`
+	TradeID       int64   `json:"trade_id,omitempty"`
+	LocalStatus   string  `json:"local_status,omitempty"`
+	RemoteStatus  string  `json:"remote_status,omitempty"`
+	LocalFilled   float64 `json:"local_filled"`
+	RemoteFilled  float64 `json:"remote_filled"`
+	Time          int64   `json:"time"` // 发现时间 (毫秒)
+}
+
+// Order 本地跟踪的挂单
+type Order struct {
+	Symbol        string  `json:"symbol"`
+	ClientOrderID string  `json:"client_order_id"`
+	OrderID       int64   `json:"order_id"`
+	Side          string  `json:"side"`
+	PositionSide  string  `json:"position_side"`
+	Type          string  `json:"type"`
+	Status        string  `json:"status"`
+	OrigQty       float64 `json:"orig_qty"`
+	Price         float64 `json:"price"`
+	FilledQty     float64 `json:"filled_qty"`
+	UpdateTime    int64   `json:"update_time"` // 本地最近一次更新时间 (毫秒)
+}
+
+// symbolID 交易对内唯一的 ID：合约的 orderId 与 tradeId 都按交易对分配，不同交易对可能重复
+type symbolID struct {
+	symbol string
+	id     int64
+}
+
+type orderRef struct {
+	clientOrderID string
+	seen          int64
+}
+
+// Reconciler 由 ORDER_TRADE_UPDATE 与 REST 回执维护本地挂单与已见成交，
+// 定期 (及私有流重连后) 与 /fapi/v1/openOrders、/fapi/v1/userTrades 比对并修复
+type Reconciler struct {
+	// OnMissedFill 发现漏掉的成交时调用，clientOrderID 在本地没有记录时为空；
+	// 调用方据此补记盈亏与账本。在 Reconcile 的调用协程中执行
+	OnMissedFill func(trade binance.UserTrade, clientOrderID string)
+	// Grace 比 Grace 更新的订单与成交留到下一轮再判断，避免与正在途中的推送竞争
+	Grace time.Duration
+	// Retention 已完结订单与已见成交的保留时间
+	Retention time.Duration
+
+	exchange Exchange
+	now      func() time.Time
+
+	runMu        sync.Mutex // 串行化 Reconcile，定时器与重连可能同时触发
+	mu           sync.Mutex
+	working      map[string]*Order     // clientOrderId -> 仍在挂的订单
+	orderIDs     map[symbolID]orderRef // (symbol, orderId) -> clientOrderId，成交记录只带 orderId
+	trades       map[symbolID]int64    // (symbol, tradeId) -> 成交时间
+	cursors      map[string]int64      // symbol -> 下一次 userTrades 查询起点
+	bootstrapped map[string]bool       // 首次对账只接管现有挂单，不报差异
+}
+
+// New 创建对账器
+func New(exchange Exchange) *Reconciler {
+	return &Reconciler{
+		Grace:        defaultGrace,
+		Retention:    defaultRetention,
+		exchange:     exchange,
+		now:          time.Now,
+		working:      make(map[string]*Order),
+		orderIDs:     make(map[symbolID]orderRef),
+		trades:       make(map[symbolID]int64),
+		cursors:      make(map[string]int64),
+		bootstrapped: make(map[string]bool),
+	}
+}
+
+// TrackOrder 记录 REST 下单回执，保证 NEW 推送丢失时订单仍被跟踪
+func (r *Reconciler) TrackOrder(o *binance.OrderResponse) {
+	if o == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now().UnixMilli()
+	r.orderIDs[symbolID{o.Symbol, o.OrderID}] = orderRef{clientOrderID: o.ClientOrderID, seen: now}
+	if !isWorking(o.Status) {
+		delete(r.working, o.ClientOrderID)
+		return
+	}
+	if existing, ok := r.working[o.ClientOrderID]; ok && existing.FilledQty > o.ExecutedQty {
+		return // 推送先于回执到达，保留推送中更新的成交数量
+	}
+	r.working[o.ClientOrderID] = orderFromResponse(o, now)
+}
+
+// ApplyOrderUpdate 应用 ORDER_TRADE_UPDATE；返回 false 表示该成交已由对账补记过，调用方不应重复处理
+func (r *Reconciler) ApplyOrderUpdate(o *binance.OrderTradeUpdate) bool {
+	if o == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now().UnixMilli()
+	r.orderIDs[symbolID{o.Symbol, o.OrderID}] = orderRef{clientOrderID: o.ClientOrderID, seen: now}
+
+	fresh := true
+	if (o.ExecutionType == "TRADE" || o.ExecutionType == "CALCULATED") && o.TradeID != 0 {
+		key := symbolID{o.Symbol, o.TradeID}
+		if _, seen := r.trades[key]; seen {
+			fresh = false
+		}
+		r.trades[key] = o.TradeTime
+	}
+
+	if !isWorking(o.Status) {
+		delete(r.working, o.ClientOrderID)
+		return fresh
+	}
+	w, ok := r.working[o.ClientOrderID]
+	if !ok {
+		w = &Order{Symbol: o.Symbol, ClientOrderID: o.ClientOrderID}
+		r.working[o.ClientOrderID] = w
+	}
+	w.OrderID = o.OrderID
+	w.Side = o.Side
+	w.PositionSide = o.PositionSide
+	w.Type = o.OrderType
+	w.Status = o.Status
+	w.OrigQty = parseFloat(o.OrigQty)
+	w.Price = parseFloat(o.Price)
+	w.FilledQty = parseFloat(o.CumFilledQty)
+	w.UpdateTime = now
+	return fresh
+}
+
+// WorkingOrders 返回当前本地跟踪的挂单，按交易对与 clientOrderId 排序
+func (r *Reconciler) WorkingOrders() []Order {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Order, 0, len(r.working))
+	for _, o := range r.working {
+		out = append(out, *o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Symbol != out[j].Symbol {
+			return out[i].Symbol < out[j].Symbol
+		}
+		return out[i].ClientOrderID < out[j].ClientOrderID
+	})
+	return out
+}
+
+// Reconcile 对给定交易对 (以及所有仍有本地挂单的交易对) 执行一轮对账，返回发现并已修复的差异
+// 单个交易对查询失败不影响其它交易对，错误合并返回
+func (r *Reconciler) Reconcile(symbols []string) ([]Discrepancy, error) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	var found []Discrepancy
+	var errs []error
+	for _, symbol := range r.symbols(symbols) {
+		d, err := r.reconcileSymbol(symbol)
+		found = append(found, d...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", symbol, err))
+		}
+	}
+	r.prune()
+	return found, errors.Join(errs...)
+}
+
+func (r *Reconciler) symbols(extra []string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set := make(map[string]bool)
+	for _, s := range extra {
+		if s != "" {
+			set[s] = true
+		}
+	}
+	for _, o := range r.working {
+		set[o.Symbol] = true
+	}
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *Reconciler) reconcileSymbol(symbol string) ([]Discrepancy, error) {
+	start := r.now()
+	cutoff := start.Add(-r.Grace).UnixMilli()
+
+	remote, err := r.exchange.GetOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	first := !r.bootstrapped[symbol]
+	r.bootstrapped[symbol] = true
+	found, ghosts := r.compareOpenOrders(symbol, remote, cutoff, first, start.UnixMilli())
+	r.mu.Unlock()
+
+	// 幽灵订单逐个查询终态，确认不是推送延迟后再从本地移除
+	var errs []error
+	for _, g := range ghosts {
+		order, err := r.exchange.QueryOrder(symbol, g.ClientOrderID)
+		if err != nil {
+			if !binance.IsAPIErrorCode(err, binance.CodeNoSuchOrder) {
+				errs = append(errs, err)
+				continue
+			}
+			order = &binance.OrderResponse{Symbol: symbol, ClientOrderID: g.ClientOrderID, OrderID: g.OrderID, Status: "NOT_FOUND"}
+		}
+		if d, ok := r.resolveGhost(g, order, start.UnixMilli()); ok {
+			found = append(found, d)
+		}
+	}
+
+	fills, err := r.checkTrades(symbol, cutoff, start.UnixMilli())
+	return append(found, fills...), errors.Join(append(errs, err)...)
+}
+
+// compareOpenOrders 在持锁状态下比对挂单，返回已修复的差异与需要查询终态的幽灵订单候选
+func (r *Reconciler) compareOpenOrders(symbol string, remote []binance.OrderResponse, cutoff int64, first bool, now int64) ([]Discrepancy, []Order) {
+	var found []Discrepancy
+	open := make(map[string]bool, len(remote))
+
+	for i := range remote {
+		o := &remote[i]
+		open[o.ClientOrderID] = true
+		r.orderIDs[symbolID{symbol, o.OrderID}] = orderRef{clientOrderID: o.ClientOrderID, seen: now}
+
+		local, ok := r.working[o.ClientOrderID]
+		switch {
+		case !ok:
+			if !first && o.UpdateTime > cutoff {
+				continue // 刚下的单，回执可能还在路上
+			}
+			r.working[o.ClientOrderID] = orderFromResponse(o, now)
+			if !first {
+				found = append(found, Discrepancy{
+					Type: TypeUnknownOrder, Symbol: symbol, ClientOrderID: o.ClientOrderID, OrderID: o.OrderID,
+					RemoteStatus: o.Status, RemoteFilled: o.ExecutedQty, Time: now,
+				})
+			}
+		case local.FilledQty != o.ExecutedQty && local.UpdateTime <= cutoff:
+			found = append(found, Discrepancy{
+				Type: TypeFilledQty, Symbol: symbol, ClientOrderID: o.ClientOrderID, OrderID: o.OrderID,
+				LocalStatus: local.Status, RemoteStatus: o.Status, LocalFilled: local.FilledQty, RemoteFilled: o.ExecutedQty, Time: now,
+			})
+			r.working[o.ClientOrderID] = orderFromResponse(o, now)
+		}
+	}
+
+	var ghosts []Order
+	for id, local := range r.working {
+		if local.Symbol == symbol && !open[id] && local.UpdateTime <= cutoff {
+			ghosts = append(ghosts, *local)
+		}
+	}
+	return found, ghosts
+}
+
+func (r *Reconciler) resolveGhost(g Order, remote *binance.OrderResponse, now int64) (Discrepancy, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	local, ok := r.working[g.ClientOrderID]
+	if !ok || local.UpdateTime != g.UpdateTime {
+		return Discrepancy{}, false // 查询期间推送已经更新了该订单
+	}
+	if isWorking(remote.Status) {
+		// 交易所仍在挂 (openOrders 与查询之间的竞争)，只同步成交数量
+		r.working[g.ClientOrderID] = orderFromResponse(remote, now)
+		return Discrepancy{}, false
+	}
+	delete(r.working, g.ClientOrderID)
+	return Discrepancy{
+		Type: TypeGhostOrder, Symbol: g.Symbol, ClientOrderID: g.ClientOrderID, OrderID: g.OrderID,
+		LocalStatus: g.Status, RemoteStatus: remote.Status, LocalFilled: g.FilledQty, RemoteFilled: remote.ExecutedQty, Time: now,
+	}, true
+}
+
+// checkTrades 拉取游标之后的成交，找出本地没有见过的成交
+func (r *Reconciler) checkTrades(symbol string, cutoff, now int64) ([]Discrepancy, error) {
+	r.mu.Lock()
+	cursor := r.cursors[symbol]
+	r.mu.Unlock()
+
+	if cursor == 0 {
+		// 首次对账：之前的成交无法判断是否漏接，从现在开始跟踪
+		r.mu.Lock()
+		r.cursors[symbol] = cutoff
+		r.mu.Unlock()
+		return nil, nil
+	}
+
+	trades, err := r.exchange.GetUserTrades(symbol, cursor, userTradesLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	type missed struct {
+		trade         binance.UserTrade
+		clientOrderID string
+	}
+	var found []Discrepancy
+	var repairs []missed
+	next := cutoff
+
+	r.mu.Lock()
+	for _, t := range trades {
+		if t.Time > cutoff {
+			continue // 推送可能还在路上，下一轮再判断
+		}
+		key := symbolID{symbol, t.ID}
+		if _, seen := r.trades[key]; seen {
+			continue
+		}
+		r.trades[key] = t.Time
+		ref := r.orderIDs[symbolID{symbol, t.OrderID}]
+		found = append(found, Discrepancy{
+			Type: TypeMissedFill, Symbol: symbol, ClientOrderID: ref.clientOrderID, OrderID: t.OrderID,
+			TradeID: t.ID, RemoteFilled: t.Qty, Time: now,
+		})
+		repairs = append(repairs, missed{trade: t, clientOrderID: ref.clientOrderID})
+	}
+	if len(trades) >= userTradesLimit {
+		// 单页已满，只推进到本页最后一笔，剩余部分下一轮继续
+		next = trades[len(trades)-1].Time
+	}
+	r.cursors[symbol] = next
+	r.mu.Unlock()
+
+	if r.OnMissedFill != nil {
+		for _, m := range repairs {
+			r.OnMissedFill(m.trade, m.clientOrderID)
+		}
+	}
+	return found, nil
+}
+
+// prune 清理超过保留时间的已完结订单映射与已见成交
+func (r *Reconciler) prune() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	horizon := r.now().Add(-r.Retention).UnixMilli()
+	for id, t := range r.trades {
+		if t < horizon {
+			delete(r.trades, id)
+		}
+	}
+	for id, ref := range r.orderIDs {
+		if ref.seen < horizon {
+			if _, working := r.working[ref.clientOrderID]; !working {
+				delete(r.orderIDs, id)
+			}
+		}
+	}
+}
+
+func orderFromResponse(o *binance.OrderResponse, now int64) *Order {
+	return &Order{
+		Symbol:        o.Symbol,
+		ClientOrderID: o.ClientOrderID,
+		OrderID:       o.OrderID,
+		Side:          o.Side,
+		PositionSide:  o.PositionSide,
+		Type:          o.Type,
+		Status:        o.Status,
+		OrigQty:       o.OrigQty,
+		Price:         o.Price,
+		FilledQty:     o.ExecutedQty,
+		UpdateTime:    now,
+	}
+}
+
+func isWorking(status string) bool {
+	return status == "NEW" || status == "PARTIALLY_FILLED"
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -0,0 +1,224 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+type fakeExchange struct {
+	open    []binance.OrderResponse
+	trades  []binance.UserTrade
+	orders  map[string]*binance.OrderResponse // QueryOrder 结果，缺失时返回 -2013
+	queried []string
+	since   int64
+}
+
+func (f *fakeExchange) GetOpenOrders(symbol string) ([]binance.OrderResponse, error) {
+	return f.open, nil
+}
+
+func (f *fakeExchange) GetUserTrades(symbol string, startTime int64, limit int) ([]binance.UserTrade, error) {
+	f.since = startTime
+	return f.trades, nil
+}
+
+func (f *fakeExchange) QueryOrder(symbol, clientOrderID string) (*binance.OrderResponse, error) {
+	f.queried = append(f.queried, clientOrderID)
+	if o, ok := f.orders[clientOrderID]; ok {
+		return o, nil
+	}
+	return nil, &binance.APIError{HTTPStatus: 400, Code: binance.CodeNoSuchOrder, Msg: "Order does not exist."}
+}
+
+// newTestReconciler 返回时钟可控的对账器，初始时间 t0 = 1_000_000 ms
+func newTestReconciler(ex *fakeExchange) (*Reconciler, *time.Time) {
+	r := New(ex)
+	clock := time.UnixMilli(1_000_000)
+	r.now = func() time.Time { return clock }
+	return r, &clock
+}
+
+func TestFirstRunAdoptsOpenOrdersSilently(t *testing.T) {
+	ex := &fakeExchange{open: []binance.OrderResponse{
+		{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1, Status: "NEW", OrigQty: 1, UpdateTime: 999_999},
+	}}
+	r, _ := newTestReconciler(ex)
+
+	found, err := r.Reconcile([]string{"BTCUSDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("first run should not report discrepancies, got %+v", found)
+	}
+	if orders := r.WorkingOrders(); len(orders) != 1 || orders[0].ClientOrderID != "grid_1" {
+		t.Errorf("open order should be adopted: %+v", orders)
+	}
+}
+
+func TestGhostOrderResolvedFromQuery(t *testing.T) {
+	ex := &fakeExchange{orders: map[string]*binance.OrderResponse{
+		"grid_1": {Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1, Status: "FILLED", ExecutedQty: 1},
+	}}
+	r, clock := newTestReconciler(ex)
+	r.Reconcile([]string{"BTCUSDT"}) // 首轮接管
+
+	r.TrackOrder(&binance.OrderResponse{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1, Status: "NEW", OrigQty: 1})
+	r.TrackOrder(&binance.OrderResponse{Symbol: "BTCUSDT", ClientOrderID: "grid_2", OrderID: 2, Status: "NEW", OrigQty: 1})
+
+	// 仍在宽限期内：不判定为幽灵单
+	found, _ := r.Reconcile(nil)
+	if len(found) != 0 || len(ex.queried) != 0 {
+		t.Fatalf("orders within grace period should be skipped: %+v", found)
+	}
+
+	*clock = clock.Add(time.Minute)
+	found, err := r.Reconcile(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 ghost orders, got %+v", found)
+	}
+	byID := map[string]Discrepancy{found[0].ClientOrderID: found[0], found[1].ClientOrderID: found[1]}
+	if d := byID["grid_1"]; d.Type != TypeGhostOrder || d.RemoteStatus != "FILLED" || d.RemoteFilled != 1 {
+		t.Errorf("unexpected ghost discrepancy: %+v", d)
+	}
+	if d := byID["grid_2"]; d.RemoteStatus != "NOT_FOUND" {
+		t.Errorf("missing order should be reported as NOT_FOUND: %+v", d)
+	}
+	if len(r.WorkingOrders()) != 0 {
+		t.Errorf("ghost orders should be removed locally")
+	}
+}
+
+func TestUnknownOrderAndFilledQtyMismatch(t *testing.T) {
+	ex := &fakeExchange{}
+	r, clock := newTestReconciler(ex)
+	r.Reconcile([]string{"BTCUSDT"})
+
+	r.ApplyOrderUpdate(&binance.OrderTradeUpdate{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1,
+		ExecutionType: "NEW", Status: "NEW", OrigQty: "1", CumFilledQty: "0"})
+	*clock = clock.Add(time.Minute)
+
+	ex.open = []binance.OrderResponse{
+		{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1, Status: "PARTIALLY_FILLED", OrigQty: 1, ExecutedQty: 0.4},
+		{Symbol: "BTCUSDT", ClientOrderID: "ext_9", OrderID: 9, Status: "NEW", OrigQty: 2, UpdateTime: 1_000},
+		{Symbol: "BTCUSDT", ClientOrderID: "grid_new", OrderID: 10, Status: "NEW", UpdateTime: clock.UnixMilli()},
+	}
+	found, err := r.Reconcile(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected mismatch + unknown order, got %+v", found)
+	}
+	types := map[string]Discrepancy{found[0].Type: found[0], found[1].Type: found[1]}
+	if d := types[TypeFilledQty]; d.LocalFilled != 0 || d.RemoteFilled != 0.4 {
+		t.Errorf("unexpected filled qty discrepancy: %+v", d)
+	}
+	if d := types[TypeUnknownOrder]; d.ClientOrderID != "ext_9" {
+		t.Errorf("unexpected unknown order discrepancy: %+v", d)
+	}
+	if orders := r.WorkingOrders(); len(orders) != 2 || orders[1].FilledQty != 0.4 {
+		t.Errorf("local state should be repaired from REST: %+v", orders)
+	}
+}
+
+func TestMissedFillReportedOnce(t *testing.T) {
+	ex := &fakeExchange{}
+	r, clock := newTestReconciler(ex)
+	var repaired []string
+	r.OnMissedFill = func(trade binance.UserTrade, clientOrderID string) {
+		repaired = append(repaired, clientOrderID)
+	}
+	r.Reconcile([]string{"BTCUSDT"}) // 首轮只设置游标
+
+	r.ApplyOrderUpdate(&binance.OrderTradeUpdate{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1,
+		ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", TradeID: 100, TradeTime: 1_000_100, CumFilledQty: "0.1"})
+	*clock = clock.Add(time.Minute)
+	ex.trades = []binance.UserTrade{
+		{ID: 100, OrderID: 1, Symbol: "BTCUSDT", Qty: 0.1, Time: 1_000_100},
+		{ID: 101, OrderID: 1, Symbol: "BTCUSDT", Qty: 0.2, Time: 1_000_200},
+		{ID: 102, OrderID: 1, Symbol: "BTCUSDT", Qty: 0.3, Time: clock.UnixMilli()}, // 宽限期内
+	}
+
+	found, err := r.Reconcile(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missed := 0
+	for _, d := range found {
+		if d.Type == TypeMissedFill {
+			missed++
+			if d.TradeID != 101 || d.ClientOrderID != "grid_1" {
+				t.Errorf("unexpected missed fill: %+v", d)
+			}
+		}
+	}
+	if missed != 1 || len(repaired) != 1 || repaired[0] != "grid_1" {
+		t.Fatalf("expected exactly one repaired fill, got %d / %v", missed, repaired)
+	}
+	if ex.since != 1_000_000-defaultGrace.Milliseconds() {
+		t.Errorf("trades should be queried from the previous cutoff, got %d", ex.since)
+	}
+
+	// 已补记的成交随后到达的推送应被标记为重复
+	fresh := r.ApplyOrderUpdate(&binance.OrderTradeUpdate{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1,
+		ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", TradeID: 101, CumFilledQty: "0.3"})
+	if fresh {
+		t.Error("late push for a repaired trade should be reported as duplicate")
+	}
+
+	// 下一轮不再重复报告
+	*clock = clock.Add(time.Minute)
+	found, _ = r.Reconcile(nil)
+	for _, d := range found {
+		if d.Type == TypeMissedFill && d.TradeID != 102 {
+			t.Errorf("trade reported twice: %+v", d)
+		}
+	}
+}
+
+func TestApplyOrderUpdate_TerminalRemovesWorking(t *testing.T) {
+	r, _ := newTestReconciler(&fakeExchange{})
+	r.TrackOrder(&binance.OrderResponse{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1, Status: "NEW"})
+	r.ApplyOrderUpdate(&binance.OrderTradeUpdate{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 1,
+		ExecutionType: "CANCELED", Status: "CANCELED"})
+	if len(r.WorkingOrders()) != 0 {
+		t.Error("canceled order should no longer be working")
+	}
+}
+
+func TestTradeAndOrderIDsScopedBySymbol(t *testing.T) {
+	ex := &fakeExchange{}
+	r, clock := newTestReconciler(ex)
+	var repaired []string
+	r.OnMissedFill = func(trade binance.UserTrade, clientOrderID string) {
+		repaired = append(repaired, trade.Symbol+"/"+clientOrderID)
+	}
+	r.Reconcile([]string{"ETHUSDT"}) // 首轮只设置游标
+
+	// 合约的 tradeId / orderId 按交易对分配，不同交易对的相同 ID 是不同的成交
+	if !r.ApplyOrderUpdate(&binance.OrderTradeUpdate{Symbol: "BTCUSDT", ClientOrderID: "grid_1", OrderID: 7,
+		ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", TradeID: 100, TradeTime: 1_000_100}) {
+		t.Fatal("first fill should be fresh")
+	}
+	if !r.ApplyOrderUpdate(&binance.OrderTradeUpdate{Symbol: "ETHUSDT", ClientOrderID: "mm_1", OrderID: 8,
+		ExecutionType: "TRADE", Status: "PARTIALLY_FILLED", TradeID: 100, TradeTime: 1_000_100}) {
+		t.Error("fill on another symbol with the same trade id should be fresh")
+	}
+
+	// 漏接成交按 (交易对, orderId) 找回 clientOrderId，不会串到另一个交易对的订单
+	r.TrackOrder(&binance.OrderResponse{Symbol: "ETHUSDT", ClientOrderID: "mm_2", OrderID: 7, Status: "NEW"})
+	*clock = clock.Add(time.Minute)
+	ex.trades = []binance.UserTrade{{ID: 101, OrderID: 7, Symbol: "ETHUSDT", Qty: 1, Time: 1_000_200}}
+	if _, err := r.Reconcile(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(repaired) != 1 || repaired[0] != "ETHUSDT/mm_2" {
+		t.Errorf("missed fill should map to the ETHUSDT order, got %v", repaired)
+	}
+}
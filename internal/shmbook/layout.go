@@ -0,0 +1,95 @@
+// Package shmbook 把每个交易对的 TopN 盘口写入 /dev/shm 下的内存映射文件，
+// 同机消费端 mmap 后直接读内存，每个 tick 不需要任何系统调用
+//
+// 文件布局 (小端序，总长 96 + depth*32 字节)：
+//
+//	偏移  长度      字段
+//	0     8         magic "QBOOKSHM"
+//	8     4         布局版本 (LayoutVersion, uint32)
+//	12    4         depth：每侧最大档数 (uint32)
+//	16    8         seq：seqlock 序号 (uint64)，奇数表示写入中
+//	24    8         LastUpdateID (int64)
+//	32    8         Timestamp 网关发布时间，毫秒 (int64)
+//	40    4         买盘有效档数 nb (uint32)
+//	44    4         卖盘有效档数 na (uint32)
+//	48    8         EventTime 交易所事件时间 (int64)
+//	56    8         TransactionTime 撮合时间 (int64)
+//	64    8         ReceiveTime 网关接收时间 (int64)
+//	72    4         状态 (uint32：0 未知 / 1 SYNCED / 2 RESYNCING / 3 STALE)
+//	76    4         保留
+//	80    16        交易对 (ASCII，右侧补 0)
+//	96    16*depth  买盘：价格 float64 + 数量 float64，价格从高到低
+//	...   16*depth  卖盘：同上，价格从低到高
+//
+// 读取协议 (seqlock)：
+//  1. 读 seq 得到 s1，若为奇数说明写入中，重试
+//  2. 拷贝 24 字节之后需要的字段
+//  3. 再读 seq 得到 s2，s1 != s2 说明读取期间被覆盖，丢弃重试
+//
+// seq 只在写入完成后变化，消费端比较 seq 即可判断盘口是否更新
+package shmbook
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"path/filepath"
+)
+
+// LayoutVersion 文件布局版本，布局有不兼容变化时递增
+// v2：增加交易所事件时间、撮合时间、网关接收时间与数据状态
+const LayoutVersion = 2
+
+// DefaultDir 默认目录 (Linux 下为内存文件系统)
+const DefaultDir = "/dev/shm"
+
+// DefaultDepth 默认每侧档数
+const DefaultDepth = 20
+
+const (
+	headerSize = 96
+	levelSize  = 16
+	symbolSize = 16
+
+	offVersion = 8
+	offDepth   = 12
+	offSeq     = 16
+	offUpdate  = 24
+	offTime    = 32
+	offBids    = 40
+	offAsks    = 44
+	offEvent   = 48
+	offTx      = 56
+	offReceive = 64
+	offState   = 72
+	offSymbol  = 80
+)
+
+var magic = [8]byte{'Q', 'B', 'O', 'O', 'K', 'S', 'H', 'M'}
+
+var (
+	// ErrBadLayout 文件不是本包创建的，或布局版本不兼容
+	ErrBadLayout = errors.New("shmbook: bad magic or layout version")
+	// ErrBusy 多次重试仍读到写入中的数据
+	ErrBusy = errors.New("shmbook: writer busy, retry later")
+)
+
+// Path 交易对对应的共享内存文件路径，如 /dev/shm/quant_book_BTCUSDT
+func Path(dir, symbol string) string {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return filepath.Join(dir, "quant_book_"+symbol)
+}
+
+func fileSize(depth int) int {
+	return headerSize + 2*depth*levelSize
+}
+
+func putFloat(b []byte, f float64) {
+	binary.LittleEndian.PutUint64(b, math.Float64bits(f))
+}
+
+func getFloat(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
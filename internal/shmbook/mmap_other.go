@@ -0,0 +1,18 @@
+//go:build !unix
+
+package shmbook
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("shmbook: shared memory book is only supported on unix")
+
+func mmapFile(f *os.File, size int, writable bool) ([]byte, error) {
+	return nil, errUnsupported
+}
+
+func munmap(b []byte) error {
+	return nil
+}
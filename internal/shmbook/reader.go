@@ -0,0 +1,126 @@
+package shmbook
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// readRetries seqlock 读取的最大重试次数
+const readRetries = 1000
+
+// Reader 共享内存读取端，Open 之后 Read 只访问映射内存，不产生系统调用
+// 同一个 Reader 不应被多个协程并发调用 Read (快照切片会被复用)
+type Reader struct {
+	f     *os.File
+	mem   []byte
+	depth int
+	seq   *uint64
+}
+
+// Open 以只读方式映射网关写入的共享内存文件
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.Size() < headerSize {
+		f.Close()
+		return nil, ErrBadLayout
+	}
+	mem, err := mmapFile(f, int(st.Size()), false)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("shmbook: mmap %s: %w", path, err)
+	}
+
+	depth := int(binary.LittleEndian.Uint32(mem[offDepth:]))
+	if [8]byte(mem[:8]) != magic || binary.LittleEndian.Uint32(mem[offVersion:]) != LayoutVersion ||
+		depth <= 0 || fileSize(depth) != len(mem) {
+		munmap(mem)
+		f.Close()
+		return nil, ErrBadLayout
+	}
+	return &Reader{f: f, mem: mem, depth: depth, seq: (*uint64)(unsafe.Pointer(&mem[offSeq]))}, nil
+}
+
+// Depth 每侧最大档数
+func (r *Reader) Depth() int {
+	return r.depth
+}
+
+// Seq 当前 seqlock 序号，与上次读取结果相同说明盘口未更新，可用于廉价的变更检测
+func (r *Reader) Seq() uint64 {
+	return atomic.LoadUint64(r.seq)
+}
+
+// Read 按 seqlock 协议读取一致的快照到 snap (复用其切片)，返回对应的序号
+// 序号为 0 表示网关尚未写入任何快照
+func (r *Reader) Read(snap *binance.OrderBookSnapshot) (uint64, error) {
+	m := r.mem
+	for i := 0; i < readRetries; i++ {
+		s1 := atomic.LoadUint64(r.seq)
+		if s1%2 == 1 {
+			runtime.Gosched()
+			continue
+		}
+
+		nb := min(int(binary.LittleEndian.Uint32(m[offBids:])), r.depth)
+		na := min(int(binary.LittleEndian.Uint32(m[offAsks:])), r.depth)
+		snap.LastUpdateID = int64(binary.LittleEndian.Uint64(m[offUpdate:]))
+		snap.Timestamp = int64(binary.LittleEndian.Uint64(m[offTime:]))
+		snap.EventTime = int64(binary.LittleEndian.Uint64(m[offEvent:]))
+		snap.TransactionTime = int64(binary.LittleEndian.Uint64(m[offTx:]))
+		snap.ReceiveTime = int64(binary.LittleEndian.Uint64(m[offReceive:]))
+		state := uint8(binary.LittleEndian.Uint32(m[offState:]))
+		var sym [symbolSize]byte
+		copy(sym[:], m[offSymbol:])
+		snap.Bids = readLevels(snap.Bids[:0], m[headerSize:], nb)
+		snap.Asks = readLevels(snap.Asks[:0], m[headerSize+r.depth*levelSize:], na)
+
+		if atomic.LoadUint64(r.seq) == s1 {
+			snap.State = binance.BookStateFromCode(state)
+			// 交易对不变时不重新分配字符串
+			if name := bytesTrim(sym[:]); snap.Symbol != string(name) {
+				snap.Symbol = string(name)
+			}
+			return s1, nil
+		}
+	}
+	return 0, ErrBusy
+}
+
+func bytesTrim(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
+
+func readLevels(dst []binance.PriceLevel, src []byte, n int) []binance.PriceLevel {
+	for i := 0; i < n; i++ {
+		dst = append(dst, binance.PriceLevel{Price: getFloat(src[i*levelSize:]), Qty: getFloat(src[i*levelSize+8:])})
+	}
+	return dst
+}
+
+// Close 解除映射并关闭文件
+func (r *Reader) Close() error {
+	err := munmap(r.mem)
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+package shmbook
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+func book(id int64, levels int) binance.OrderBookSnapshot {
+	snap := binance.OrderBookSnapshot{Symbol: "BTCUSDT", LastUpdateID: id, Timestamp: 1700000000000 + id,
+		EventTime: 1700000000000, TransactionTime: 1699999999999, ReceiveTime: 1700000000001, State: binance.BookStateSynced}
+	for i := 0; i < levels; i++ {
+		// 每档价格与数量都由 id 推导，读端可据此校验是否读到撕裂的数据
+		snap.Bids = append(snap.Bids, binance.PriceLevel{Price: float64(id) - float64(i), Qty: float64(id)})
+		snap.Asks = append(snap.Asks, binance.PriceLevel{Price: float64(id) + float64(i) + 1, Qty: float64(id)})
+	}
+	return snap
+}
+
+func TestWriteRead(t *testing.T) {
+	path := Path(t.TempDir(), "BTCUSDT")
+	w, err := Create(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Depth() != 5 {
+		t.Fatalf("expected depth 5, got %d", r.Depth())
+	}
+
+	var snap binance.OrderBookSnapshot
+	if seq, err := r.Read(&snap); err != nil || seq != 0 {
+		t.Fatalf("fresh file should read seq 0, got %d (%v)", seq, err)
+	}
+
+	in := book(42, 8) // 超过 depth 的档位被截断
+	if err := w.Write(&in); err != nil {
+		t.Fatal(err)
+	}
+	seq, err := r.Read(&snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 2 || r.Seq() != 2 {
+		t.Errorf("expected seq 2 after one write, got %d / %d", seq, r.Seq())
+	}
+	if snap.Symbol != "BTCUSDT" || snap.LastUpdateID != 42 || snap.Timestamp != 1700000000042 ||
+		snap.EventTime != 1700000000000 || snap.TransactionTime != 1699999999999 || snap.ReceiveTime != 1700000000001 ||
+		snap.State != binance.BookStateSynced {
+		t.Errorf("unexpected header: %+v", snap)
+	}
+	if len(snap.Bids) != 5 || len(snap.Asks) != 5 || snap.Bids[4].Price != 38 || snap.Asks[0].Price != 43 {
+		t.Errorf("unexpected levels: %+v / %+v", snap.Bids, snap.Asks)
+	}
+
+	// 档数变少时读端只看到有效档位
+	short := book(43, 2)
+	w.Write(&short)
+	r.Read(&snap)
+	if len(snap.Bids) != 2 || snap.Bids[0].Price != 43 {
+		t.Errorf("expected 2 levels after shrinking, got %+v", snap.Bids)
+	}
+}
+
+func TestCreate_ReusesSeqAndResetsOnDepthChange(t *testing.T) {
+	path := Path(t.TempDir(), "ETHUSDT")
+	w, _ := Create(path, 5)
+	snap := book(1, 1)
+	w.Write(&snap)
+	w.Write(&snap)
+	w.Close()
+
+	w, err := Create(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := Open(path)
+	if r.Seq() != 4 {
+		t.Errorf("restart with same layout should keep seq, got %d", r.Seq())
+	}
+	r.Close()
+	w.Close()
+
+	w, _ = Create(path, 10)
+	defer w.Close()
+	r, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Depth() != 10 || r.Seq() != 0 {
+		t.Errorf("depth change should reinitialize the file, got depth %d seq %d", r.Depth(), r.Seq())
+	}
+}
+
+func TestCreate_ClearsTornWriteOnReuse(t *testing.T) {
+	path := Path(t.TempDir(), "ETHUSDT")
+	w, _ := Create(path, 5)
+	snap := book(7, 5)
+	w.Write(&snap)
+	w.Close()
+
+	// 模拟进程在写入中途退出：seq 停在奇数
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var odd [8]byte
+	odd[0] = 3
+	if _, err := f.WriteAt(odd[:], offSeq); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	w, err = Create(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	r, _ := Open(path)
+	defer r.Close()
+
+	var out binance.OrderBookSnapshot
+	seq, err := r.Read(&out)
+	if err != nil || seq != 4 {
+		t.Fatalf("expected even seq 4 after recovery, got %d (%v)", seq, err)
+	}
+	if out.LastUpdateID != 0 || len(out.Bids) != 0 || len(out.Asks) != 0 {
+		t.Errorf("torn book should be cleared before publishing, got %+v", out)
+	}
+}
+
+func TestOpen_RejectsForeignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junk")
+	os.WriteFile(path, make([]byte, 200), 0o644)
+	if _, err := Open(path); err != ErrBadLayout {
+		t.Errorf("expected ErrBadLayout, got %v", err)
+	}
+	var w *Writer
+	if err := w.Write(&binance.OrderBookSnapshot{}); err != nil {
+		t.Errorf("nil writer should be a no-op, got %v", err)
+	}
+}
+
+func TestConcurrentReadsAreConsistent(t *testing.T) {
+	path := Path(t.TempDir(), "BTCUSDT")
+	w, _ := Create(path, 20)
+	defer w.Close()
+	r, _ := Open(path)
+	defer r.Close()
+
+	const writes = 20000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(1); i <= writes; i++ {
+			snap := book(i, 20)
+			w.Write(&snap)
+		}
+	}()
+
+	var snap binance.OrderBookSnapshot
+	var lastSeq uint64
+	for lastSeq < 2*writes {
+		seq, err := r.Read(&snap)
+		if err != nil {
+			continue
+		}
+		if seq < lastSeq {
+			t.Fatalf("seq went backwards: %d -> %d", lastSeq, seq)
+		}
+		lastSeq = seq
+		if seq == 0 {
+			continue
+		}
+		id := snap.LastUpdateID
+		for i, l := range snap.Bids {
+			if l.Qty != float64(id) || l.Price != float64(id)-float64(i) {
+				t.Fatalf("torn read at seq %d: update %d, level %d = %+v", seq, id, i, l)
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// 基准：go test -bench=. -benchmem ./internal/shmbook
+func BenchmarkRead(b *testing.B) {
+	path := Path(b.TempDir(), "BTCUSDT")
+	w, _ := Create(path, 20)
+	defer w.Close()
+	snap := book(1, 20)
+	w.Write(&snap)
+	r, _ := Open(path)
+	defer r.Close()
+
+	var out binance.OrderBookSnapshot
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Read(&out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	w, _ := Create(Path(b.TempDir(), "BTCUSDT"), 20)
+	defer w.Close()
+	snap := book(1, 20)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.Write(&snap)
+	}
+}
@@ -0,0 +1,128 @@
+package shmbook
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// Writer 单个交易对的共享内存写入端，每个文件只允许一个写入者
+type Writer struct {
+	mu    sync.Mutex
+	f     *os.File
+	mem   []byte
+	depth int
+	seq   *uint64
+}
+
+// Create 创建或复用共享内存文件；布局一致时沿用原有 seq，读端在网关重启后看到的序号仍单调递增
+func Create(path string, depth int) (*Writer, error) {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	size := fileSize(depth)
+	if err := f.Truncate(int64(size)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	mem, err := mmapFile(f, size, true)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("shmbook: mmap %s: %w", path, err)
+	}
+
+	w := &Writer{f: f, mem: mem, depth: depth, seq: (*uint64)(unsafe.Pointer(&mem[offSeq]))}
+	reuse := [8]byte(mem[:8]) == magic &&
+		binary.LittleEndian.Uint32(mem[offVersion:]) == LayoutVersion &&
+		int(binary.LittleEndian.Uint32(mem[offDepth:])) == depth
+	if !reuse {
+		clear(mem)
+		copy(mem, magic[:])
+		binary.LittleEndian.PutUint32(mem[offVersion:], LayoutVersion)
+		binary.LittleEndian.PutUint32(mem[offDepth:], uint32(depth))
+	} else if s := atomic.LoadUint64(w.seq); s%2 == 1 {
+		// 上次进程在写入中途退出，盘口可能只写了一半：先清空头部字段与档位 (含档数)，再恢复为偶数
+		clear(mem[offUpdate:])
+		atomic.StoreUint64(w.seq, s+1)
+	}
+	return w, nil
+}
+
+// Depth 每侧最大档数
+func (w *Writer) Depth() int {
+	return w.depth
+}
+
+// Write 写入一次盘口快照，超过 depth 的档位被截断；交易对超过 16 字节返回错误
+// Writer 为 nil 时为空操作，未开启共享内存时调用方无需判断
+func (w *Writer) Write(snap *binance.OrderBookSnapshot) error {
+	if w == nil {
+		return nil
+	}
+	if len(snap.Symbol) > symbolSize {
+		return fmt.Errorf("shmbook: symbol %q exceeds %d bytes", snap.Symbol, symbolSize)
+	}
+	nb, na := min(len(snap.Bids), w.depth), min(len(snap.Asks), w.depth)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.mem == nil {
+		return os.ErrClosed
+	}
+
+	s := atomic.AddUint64(w.seq, 1) // 奇数：写入中
+	m := w.mem
+	binary.LittleEndian.PutUint64(m[offUpdate:], uint64(snap.LastUpdateID))
+	binary.LittleEndian.PutUint64(m[offTime:], uint64(snap.Timestamp))
+	binary.LittleEndian.PutUint32(m[offBids:], uint32(nb))
+	binary.LittleEndian.PutUint32(m[offAsks:], uint32(na))
+	binary.LittleEndian.PutUint64(m[offEvent:], uint64(snap.EventTime))
+	binary.LittleEndian.PutUint64(m[offTx:], uint64(snap.TransactionTime))
+	binary.LittleEndian.PutUint64(m[offReceive:], uint64(snap.ReceiveTime))
+	binary.LittleEndian.PutUint32(m[offState:], uint32(binance.BookStateCode(snap.State)))
+	sym := m[offSymbol : offSymbol+symbolSize]
+	clear(sym[copy(sym, snap.Symbol):])
+	writeLevels(m[headerSize:], snap.Bids[:nb])
+	writeLevels(m[headerSize+w.depth*levelSize:], snap.Asks[:na])
+	atomic.StoreUint64(w.seq, s+1) // 偶数：写入完成
+
+	return nil
+}
+
+func writeLevels(dst []byte, levels []binance.PriceLevel) {
+	for i, l := range levels {
+		putFloat(dst[i*levelSize:], l.Price)
+		putFloat(dst[i*levelSize+8:], l.Qty)
+	}
+}
+
+// Close 解除映射并关闭文件；文件保留，读端仍可读到最后一次快照 (可根据 Timestamp 判断是否过期)
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.mem == nil {
+		return nil
+	}
+	err := munmap(w.mem)
+	w.mem = nil
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,515 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+	"BinanceAutoBot2/internal/pnl"
+)
+
+// rateWindow 下单频率限制的滑动窗口
+const rateWindow = time.Minute
+
+// epsilon 数量比较的容差，避免浮点累加误差导致恰好等于上限的订单被拒
+const epsilon = 1e-9
+
+// 拒单原因，出现在 Violation.Reason 与指标标签中
+const (
+	ReasonMissingStrategy = "missing_strategy"    // 注册表启用时请求未声明策略
+	ReasonUnknownStrategy = "unknown_strategy"    // 策略不在注册表中
+	ReasonClientOrderID   = "client_order_id"     // clientOrderId 不以 "<strategy>_" 开头，或订单不属于该策略
+	ReasonSymbol          = "symbol_not_allowed"  // 交易对不在策略允许的范围内
+	ReasonAccount         = "account_not_allowed" // 账户不在策略允许的范围内
+	ReasonNotional        = "max_notional"        // 单笔名义价值超限，或市价单没有参考价格
+	ReasonPosition        = "max_position"        // 成交后 (含挂单) 净持仓可能超限
+	ReasonNotSeeded       = "state_not_seeded"    // 账户上重启前的策略持仓与挂单尚未恢复，无法判断持仓上限
+	ReasonRate            = "order_rate"          // 一分钟内下单 / 改单次数超限
+)
+
+// Limits 一个策略的身份与限额，零值表示不限制
+type Limits struct {
+	Name        string
+	MaxPosition float64  // 单个账户、单个交易对的净持仓上限 (数量，含未成交挂单)
+	MaxNotional float64  // 单笔订单名义价值上限 (USDT)；市价单按最近标记价格估算
+	Symbols     []string // 允许交易的交易对，空为不限
+	Accounts    []string // 允许操作的账户，空为不限
+	MaxOrders1m int      // 一分钟滑动窗口内的下单与改单次数上限
+}
+
+// Order 待校验的下单 / 改单
+type Order struct {
+	Account       string
+	Symbol        string
+	Side          string // BUY / SELL
+	ClientOrderID string
+	Quantity      float64
+	Price         float64 // 0 表示市价单
+}
+
+// Fill 用于恢复策略持仓的历史成交
+type Fill struct {
+	Symbol        string
+	Side          string
+	ClientOrderID string
+	Qty           float64
+}
+
+// OpenOrder 用于恢复挂单敞口的现有挂单
+type OpenOrder struct {
+	Symbol        string
+	Side          string
+	ClientOrderID string
+	Quantity      float64
+	Filled        float64
+}
+
+// Violation 策略校验失败；Reason 为上面的 Reason* 常量之一
+type Violation struct {
+	Strategy string
+	Reason   string
+	Detail   string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("strategy %q rejected (%s): %s", v.Strategy, v.Reason, v.Detail)
+}
+
+// Retryable 频率超限稍后可重试，其余原因重试也不会通过
+func (v *Violation) Retryable() bool { return v.Reason == ReasonRate }
+
+// pendingOrder 已放行但尚未终结的订单，计入持仓上限的挂单敞口
+type pendingOrder struct {
+	strategy string  // 放行该订单的策略，持仓上限只累计本策略的挂单
+	key      string  // account|symbol
+	sign     float64 // BUY 为 +1，SELL 为 -1
+	qty      float64
+	filled   float64
+}
+
+func (p *pendingOrder) remaining() float64 {
+	return math.Max(p.qty-p.filled, 0)
+}
+
+// state 单个策略的运行时状态
+type state struct {
+	limits    Limits
+	symbols   map[string]bool
+	accounts  map[string]bool
+	sent      []time.Time        // 窗口内放行的下单 / 改单时间，按时间递增
+	positions map[string]float64 // account|symbol → 已成交净持仓
+	rejected  map[string]uint64  // 拒单原因 → 次数
+}
+
+// Registry 策略注册表：校验 UDS 请求声明的策略并执行各策略的限额，
+// 通过订单推送按 clientOrderId 前缀把成交归属回策略，维护各策略的持仓与挂单敞口
+//
+// 未注册任何策略时注册表不启用，所有校验直接放行，保持原有行为
+type Registry struct {
+	mu         sync.Mutex
+	strategies map[string]*state
+	pending    map[string]*pendingOrder // clientOrderId → 挂单
+	seeded     map[string]bool          // 已恢复策略持仓与挂单的账户
+	markPrices map[string]float64
+	now        func() time.Time
+}
+
+// NewRegistry 按配置创建注册表
+func NewRegistry(limits []Limits) *Registry {
+	r := &Registry{
+		strategies: make(map[string]*state, len(limits)),
+		pending:    make(map[string]*pendingOrder),
+		seeded:     make(map[string]bool),
+		markPrices: make(map[string]float64),
+		now:        time.Now,
+	}
+	for _, l := range limits {
+		s := &state{limits: l, positions: make(map[string]float64), rejected: make(map[string]uint64)}
+		if len(l.Symbols) > 0 {
+			s.symbols = make(map[string]bool, len(l.Symbols))
+			for _, sym := range l.Symbols {
+				s.symbols[sym] = true
+			}
+		}
+		if len(l.Accounts) > 0 {
+			s.accounts = make(map[string]bool, len(l.Accounts))
+			for _, a := range l.Accounts {
+				s.accounts[a] = true
+			}
+		}
+		r.strategies[l.Name] = s
+	}
+	return r
+}
+
+// Enabled 是否注册了策略；未启用时 UDS 请求可以不带 strategy
+func (r *Registry) Enabled() bool { return len(r.strategies) > 0 }
+
+// ClientOrderIDPrefix 策略生成 clientOrderId 时使用的前缀
+func ClientOrderIDPrefix(strategy string) string { return strategy + "_" }
+
+// Owns clientOrderId 是否按 "<strategy>_<序号>" 约定归属该策略 (与盈亏、账本的归属规则一致)
+func Owns(strategy, clientOrderID string) bool {
+	return pnl.StrategyFromClientOrderID(clientOrderID) == strategy
+}
+
+// OnMarkPrice 记录最新标记价格，用于估算市价单的名义价值
+func (r *Registry) OnMarkPrice(symbol string, price float64) {
+	if !r.Enabled() || price <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.markPrices[symbol] = price
+}
+
+// AuthorizePlace 校验一笔新订单：策略已注册、clientOrderId 归属该策略、账户与交易对允许、名义价值、
+// 持仓上限与下单频率；通过后计入频率窗口并登记为挂单
+func (r *Registry) AuthorizePlace(strategy string, o Order) error {
+	if !r.Enabled() {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.lookup(strategy, o.ClientOrderID)
+	if err != nil {
+		return err
+	}
+	if err := r.check(s, o, 0); err != nil {
+		return err
+	}
+	s.sent = append(s.sent, r.now())
+	r.pending[o.ClientOrderID] = &pendingOrder{strategy: strategy, key: positionKey(o.Account, o.Symbol), sign: sideSign(o.Side), qty: o.Quantity}
+	return nil
+}
+
+// AuthorizeModify 校验改单：订单必须属于该策略，新数量替换原挂单敞口后仍需满足全部限额
+func (r *Registry) AuthorizeModify(strategy string, o Order) error {
+	if !r.Enabled() {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, err := r.lookup(strategy, o.ClientOrderID)
+	if err != nil {
+		return err
+	}
+	var replaced float64
+	p, tracked := r.pending[o.ClientOrderID]
+	if tracked {
+		replaced = p.sign * p.remaining()
+	}
+	if err := r.check(s, o, replaced); err != nil {
+		return err
+	}
+	s.sent = append(s.sent, r.now())
+	if tracked {
+		p.qty = o.Quantity
+	}
+	return nil
+}
+
+// AuthorizeCancel 撤单只校验订单归属与账户，不受限额约束 (撤单总是降低风险)
+func (r *Registry) AuthorizeCancel(strategy, account, clientOrderID string) error {
+	if !r.Enabled() {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, err := r.lookup(strategy, clientOrderID)
+	if err != nil {
+		return err
+	}
+	return s.checkAccount(account)
+}
+
+// AuthorizeSettings 校验杠杆、保证金模式、逐仓保证金与持仓模式的调整：策略必须已注册且允许操作该账户，
+// symbol 非空时还需在策略允许的交易对内 (持仓模式作用于整个账户，symbol 为空)
+func (r *Registry) AuthorizeSettings(strategy, account, symbol string) error {
+	if !r.Enabled() {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, err := r.find(strategy)
+	if err != nil {
+		return err
+	}
+	if err := s.checkAccount(account); err != nil {
+		return err
+	}
+	if symbol != "" && s.symbols != nil && !s.symbols[symbol] {
+		return s.reject(ReasonSymbol, fmt.Sprintf("symbol %s is not allowed", symbol))
+	}
+	return nil
+}
+
+// Release 下单被交易所拒绝时撤销挂单登记 (频率窗口中的计数保留)
+func (r *Registry) Release(clientOrderID string) {
+	if !r.Enabled() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, clientOrderID)
+}
+
+// Seed 恢复账户在重启前的状态：fills 按 clientOrderId 前缀累计为各策略的持仓，orders 登记为挂单敞口。
+// 持仓只在进程内统计，未恢复的账户上设置了 max_position 的策略一律拒单 (ReasonNotSeeded)，
+// 避免按从零开始的持仓放行超限订单；重复调用时先清空该账户原有的持仓
+func (r *Registry) Seed(account string, fills []Fill, orders []OpenOrder) {
+	if !r.Enabled() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefix := positionKey(account, "")
+	for _, s := range r.strategies {
+		for key := range s.positions {
+			if strings.HasPrefix(key, prefix) {
+				delete(s.positions, key)
+			}
+		}
+	}
+	for _, f := range fills {
+		if s, ok := r.strategies[pnl.StrategyFromClientOrderID(f.ClientOrderID)]; ok {
+			s.positions[positionKey(account, f.Symbol)] += sideSign(f.Side) * f.Qty
+		}
+	}
+	for _, o := range orders {
+		name := pnl.StrategyFromClientOrderID(o.ClientOrderID)
+		if _, ok := r.strategies[name]; !ok {
+			continue
+		}
+		if _, ok := r.pending[o.ClientOrderID]; ok {
+			continue
+		}
+		r.pending[o.ClientOrderID] = &pendingOrder{strategy: name, key: positionKey(account, o.Symbol), sign: sideSign(o.Side),
+			qty: o.Quantity, filled: o.Filled}
+	}
+	r.seeded[account] = true
+}
+
+// ApplyOrderUpdate 应用 ORDER_TRADE_UPDATE：成交计入所属策略的持仓，终态订单移出挂单
+// fresh 为 false 表示该成交已由对账补记 (ApplyTrade)，只更新挂单状态
+func (r *Registry) ApplyOrderUpdate(account string, o *binance.OrderTradeUpdate, fresh bool) {
+	if !r.Enabled() || o == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if fresh && (o.ExecutionType == "TRADE" || o.ExecutionType == "CALCULATED") {
+		r.fill(account, o.Symbol, o.Side, o.ClientOrderID, parseFloat(o.LastFilledQty))
+	}
+	switch o.Status {
+	case "FILLED", "CANCELED", "EXPIRED", "REJECTED", "EXPIRED_IN_MATCH":
+		delete(r.pending, o.ClientOrderID)
+	}
+}
+
+// ApplyTrade 应用对账补记的成交 (对应的订单推送已丢失)
+func (r *Registry) ApplyTrade(account string, t binance.UserTrade, clientOrderID string) {
+	if !r.Enabled() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fill(account, t.Symbol, t.Side, clientOrderID, t.Qty)
+}
+
+func (r *Registry) fill(account, symbol, side, clientOrderID string, qty float64) {
+	if qty <= 0 {
+		return
+	}
+	if p, ok := r.pending[clientOrderID]; ok {
+		p.filled += qty
+	}
+	s, ok := r.strategies[pnl.StrategyFromClientOrderID(clientOrderID)]
+	if !ok {
+		return // 手工单或未注册策略的历史订单
+	}
+	s.positions[positionKey(account, symbol)] += sideSign(side) * qty
+}
+
+// lookup 查找策略并校验 clientOrderId 归属，调用方持有锁
+func (r *Registry) lookup(strategy, clientOrderID string) (*state, error) {
+	s, err := r.find(strategy)
+	if err != nil {
+		return nil, err
+	}
+	if !Owns(strategy, clientOrderID) {
+		return s, s.reject(ReasonClientOrderID, fmt.Sprintf("client_order_id %q must start with %q", clientOrderID, ClientOrderIDPrefix(strategy)))
+	}
+	return s, nil
+}
+
+// find 返回已注册的策略，未声明或未注册时返回 Violation
+func (r *Registry) find(strategy string) (*state, error) {
+	if strategy == "" {
+		return nil, &Violation{Reason: ReasonMissingStrategy, Detail: "strategy is required"}
+	}
+	s, ok := r.strategies[strategy]
+	if !ok {
+		return nil, &Violation{Strategy: strategy, Reason: ReasonUnknownStrategy, Detail: "strategy is not registered"}
+	}
+	return s, nil
+}
+
+// check 校验账户、交易对、名义价值、持仓上限与频率；replaced 为被改单替换掉的原挂单敞口 (带方向)
+func (r *Registry) check(s *state, o Order, replaced float64) error {
+	l := s.limits
+	if err := s.checkAccount(o.Account); err != nil {
+		return err
+	}
+	if s.symbols != nil && !s.symbols[o.Symbol] {
+		return s.reject(ReasonSymbol, fmt.Sprintf("symbol %s is not allowed", o.Symbol))
+	}
+
+	if l.MaxNotional > 0 {
+		price := o.Price
+		if price <= 0 {
+			price = r.markPrices[o.Symbol]
+		}
+		if price <= 0 {
+			return s.reject(ReasonNotional, fmt.Sprintf("no reference price for %s market order", o.Symbol))
+		}
+		if notional := o.Quantity * price; notional > l.MaxNotional+epsilon {
+			return s.reject(ReasonNotional, fmt.Sprintf("notional %.2f exceeds %.2f", notional, l.MaxNotional))
+		}
+	}
+
+	if l.MaxPosition > 0 {
+		if !r.seeded[o.Account] {
+			return s.reject(ReasonNotSeeded, fmt.Sprintf("positions on account %s have not been restored", o.Account))
+		}
+		// 最坏情况：本策略同方向的挂单全部成交；只拦截会扩大该方向敞口的订单，减仓单始终放行
+		key := positionKey(o.Account, o.Symbol)
+		long, short := s.positions[key], s.positions[key]
+		for _, p := range r.pending {
+			if p.strategy != l.Name || p.key != key {
+				continue
+			}
+			if p.sign > 0 {
+				long += p.remaining()
+			} else {
+				short -= p.remaining()
+			}
+		}
+		if replaced > 0 {
+			long -= replaced
+		} else {
+			short -= replaced
+		}
+		qty := sideSign(o.Side) * o.Quantity
+		if qty > 0 && long+qty > l.MaxPosition+epsilon {
+			return s.reject(ReasonPosition, fmt.Sprintf("long exposure %g would exceed %g", long+qty, l.MaxPosition))
+		}
+		if qty < 0 && -(short+qty) > l.MaxPosition+epsilon {
+			return s.reject(ReasonPosition, fmt.Sprintf("short exposure %g would exceed %g", -(short+qty), l.MaxPosition))
+		}
+	}
+
+	if l.MaxOrders1m > 0 {
+		cutoff := r.now().Add(-rateWindow)
+		i := sort.Search(len(s.sent), func(i int) bool { return s.sent[i].After(cutoff) })
+		s.sent = s.sent[i:]
+		if len(s.sent) >= l.MaxOrders1m {
+			return s.reject(ReasonRate, fmt.Sprintf("%d orders in the last minute (limit %d)", len(s.sent), l.MaxOrders1m))
+		}
+	}
+	return nil
+}
+
+// checkAccount 校验账户在策略允许的范围内
+func (s *state) checkAccount(account string) error {
+	if s.accounts != nil && !s.accounts[account] {
+		return s.reject(ReasonAccount, fmt.Sprintf("account %s is not allowed", account))
+	}
+	return nil
+}
+
+func (s *state) reject(reason, detail string) error {
+	s.rejected[reason]++
+	return &Violation{Strategy: s.limits.Name, Reason: reason, Detail: detail}
+}
+
+// Stats 单个策略的限额与当前状态，供 UDS /api/strategies 查询
+type Stats struct {
+	Name          string             `json:"name"`
+	MaxPosition   float64            `json:"max_position,omitempty"`
+	MaxNotional   float64            `json:"max_notional,omitempty"`
+	Symbols       []string           `json:"symbols,omitempty"`
+	Accounts      []string           `json:"accounts,omitempty"`
+	MaxOrders1m   int                `json:"max_orders_1m,omitempty"`
+	OrderCount1m  int                `json:"order_count_1m"`
+	Positions     map[string]float64 `json:"positions"`      // account|symbol → 已成交净持仓
+	PendingOrders int                `json:"pending_orders"` // 已放行且未终结的订单数
+	Rejected      map[string]uint64  `json:"rejected,omitempty"`
+}
+
+// Stats 返回各策略的状态，按名称排序
+func (r *Registry) Stats() []Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := r.now().Add(-rateWindow)
+	pending := make(map[string]int)
+	for _, p := range r.pending {
+		pending[p.strategy]++
+	}
+	out := make([]Stats, 0, len(r.strategies))
+	for name, s := range r.strategies {
+		st := Stats{
+			Name:          name,
+			MaxPosition:   s.limits.MaxPosition,
+			MaxNotional:   s.limits.MaxNotional,
+			Symbols:       s.limits.Symbols,
+			Accounts:      s.limits.Accounts,
+			MaxOrders1m:   s.limits.MaxOrders1m,
+			Positions:     make(map[string]float64, len(s.positions)),
+			PendingOrders: pending[name],
+		}
+		for _, t := range s.sent {
+			if t.After(cutoff) {
+				st.OrderCount1m++
+			}
+		}
+		for k, v := range s.positions {
+			st.Positions[k] = v
+		}
+		if len(s.rejected) > 0 {
+			st.Rejected = make(map[string]uint64, len(s.rejected))
+			for k, v := range s.rejected {
+				st.Rejected[k] = v
+			}
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func positionKey(account, symbol string) string {
+	return account + "|" + symbol
+}
+
+func sideSign(side string) float64 {
+	if side == "SELL" {
+		return -1
+	}
+	return 1
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
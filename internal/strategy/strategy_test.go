@@ -0,0 +1,285 @@
+package strategy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"BinanceAutoBot2/internal/binance"
+)
+
+// newTestRegistry 返回时钟可控的注册表
+func newTestRegistry(limits ...Limits) (*Registry, *time.Time) {
+	r := NewRegistry(limits)
+	clock := time.UnixMilli(1_000_000)
+	r.now = func() time.Time { return clock }
+	return r, &clock
+}
+
+func reason(err error) string {
+	var v *Violation
+	if errors.As(err, &v) {
+		return v.Reason
+	}
+	return ""
+}
+
+func TestAuthorize_IdentityAndSymbols(t *testing.T) {
+	disabled := NewRegistry(nil)
+	if disabled.Enabled() || disabled.AuthorizePlace("", Order{Symbol: "BTCUSDT", ClientOrderID: "bot_1"}) != nil {
+		t.Fatal("an empty registry should allow everything")
+	}
+
+	r, _ := newTestRegistry(Limits{Name: "grid", Symbols: []string{"BTCUSDT"}})
+	cases := []struct {
+		strategy string
+		order    Order
+		want     string
+	}{
+		{"", Order{Symbol: "BTCUSDT", ClientOrderID: "grid_1"}, ReasonMissingStrategy},
+		{"mm", Order{Symbol: "BTCUSDT", ClientOrderID: "mm_1"}, ReasonUnknownStrategy},
+		{"grid", Order{Symbol: "BTCUSDT", ClientOrderID: "mm_1"}, ReasonClientOrderID},
+		{"grid", Order{Symbol: "BTCUSDT", ClientOrderID: "grid"}, ReasonClientOrderID},
+		{"grid", Order{Symbol: "ETHUSDT", ClientOrderID: "grid_2"}, ReasonSymbol},
+		{"grid", Order{Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, ClientOrderID: "grid_3"}, ""},
+	}
+	for _, c := range cases {
+		if got := reason(r.AuthorizePlace(c.strategy, c.order)); got != c.want {
+			t.Errorf("%s %+v: got reason %q, want %q", c.strategy, c.order, got, c.want)
+		}
+	}
+
+	// 撤单只校验归属
+	if reason(r.AuthorizeCancel("grid", "alpha", "mm_1")) != ReasonClientOrderID || r.AuthorizeCancel("grid", "alpha", "grid_3") != nil {
+		t.Error("cancel should only be allowed for the strategy's own orders")
+	}
+	if st := r.Stats(); len(st) != 1 || st[0].Rejected[ReasonClientOrderID] != 3 || st[0].Rejected[ReasonSymbol] != 1 {
+		t.Errorf("rejections should be counted per reason: %+v", st)
+	}
+}
+
+func TestAuthorizeSettings(t *testing.T) {
+	if NewRegistry(nil).AuthorizeSettings("", "alpha", "BTCUSDT") != nil {
+		t.Fatal("an empty registry should allow settings changes without a strategy")
+	}
+
+	r, _ := newTestRegistry(Limits{Name: "grid", Symbols: []string{"BTCUSDT"}}, Limits{Name: "mm"})
+	cases := []struct {
+		strategy, symbol, want string
+	}{
+		{"", "BTCUSDT", ReasonMissingStrategy},
+		{"", "", ReasonMissingStrategy},
+		{"arb", "BTCUSDT", ReasonUnknownStrategy},
+		{"grid", "ETHUSDT", ReasonSymbol},
+		{"grid", "BTCUSDT", ""},
+		{"grid", "", ""}, // 持仓模式等账户级设置不受交易对白名单约束
+		{"mm", "ETHUSDT", ""},
+	}
+	for _, c := range cases {
+		if got := reason(r.AuthorizeSettings(c.strategy, "alpha", c.symbol)); got != c.want {
+			t.Errorf("%q %q: got reason %q, want %q", c.strategy, c.symbol, got, c.want)
+		}
+	}
+}
+
+func TestAuthorize_Accounts(t *testing.T) {
+	r, _ := newTestRegistry(Limits{Name: "grid", Accounts: []string{"alpha"}}, Limits{Name: "mm"})
+	order := func(account, id string) Order {
+		return Order{Account: account, Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, Price: 1, ClientOrderID: id}
+	}
+
+	if err := r.AuthorizePlace("grid", order("alpha", "grid_1")); err != nil {
+		t.Fatalf("allowed account should pass: %v", err)
+	}
+	if reason(r.AuthorizePlace("grid", order("beta", "grid_2"))) != ReasonAccount {
+		t.Error("place on an account outside the allowlist should be rejected")
+	}
+	if reason(r.AuthorizeModify("grid", order("beta", "grid_1"))) != ReasonAccount {
+		t.Error("modify on an account outside the allowlist should be rejected")
+	}
+	if reason(r.AuthorizeCancel("grid", "beta", "grid_1")) != ReasonAccount || r.AuthorizeCancel("grid", "alpha", "grid_1") != nil {
+		t.Error("cancel should be limited to the allowed accounts")
+	}
+	if reason(r.AuthorizeSettings("grid", "beta", "BTCUSDT")) != ReasonAccount || reason(r.AuthorizeSettings("grid", "beta", "")) != ReasonAccount {
+		t.Error("settings changes should be limited to the allowed accounts")
+	}
+
+	// 未配置 accounts 的策略不限账户
+	if err := r.AuthorizePlace("mm", order("beta", "mm_1")); err != nil {
+		t.Errorf("strategy without an allowlist should accept any account: %v", err)
+	}
+	if st := r.Stats()[0]; st.Rejected[ReasonAccount] != 5 || len(st.Accounts) != 1 {
+		t.Errorf("account rejections should be counted: %+v", st)
+	}
+}
+
+func TestAuthorize_MaxPositionCountsPendingAndFills(t *testing.T) {
+	r, _ := newTestRegistry(Limits{Name: "grid", MaxPosition: 1})
+	r.Seed("alpha", nil, nil)
+	r.Seed("beta", nil, nil)
+	buy := func(id string, qty float64) error {
+		return r.AuthorizePlace("grid", Order{Account: "alpha", Symbol: "BTCUSDT", Side: "BUY", Quantity: qty, ClientOrderID: id})
+	}
+	sell := func(id string, qty float64) error {
+		return r.AuthorizePlace("grid", Order{Account: "alpha", Symbol: "BTCUSDT", Side: "SELL", Quantity: qty, ClientOrderID: id})
+	}
+
+	if err := buy("grid_1", 0.6); err != nil {
+		t.Fatal(err)
+	}
+	// 未成交的买单同样占用多头敞口
+	if reason(buy("grid_2", 0.5)) != ReasonPosition {
+		t.Error("pending buy should count towards the long limit")
+	}
+	// 另一个账户的持仓独立计算
+	if err := r.AuthorizePlace("grid", Order{Account: "beta", Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, ClientOrderID: "grid_3"}); err != nil {
+		t.Errorf("accounts should be tracked separately: %v", err)
+	}
+	if reason(sell("grid_4", 1.5)) != ReasonPosition {
+		t.Error("sell beyond the short limit should be rejected")
+	}
+
+	// 成交后挂单转为持仓；交易所拒绝的单释放敞口
+	r.ApplyOrderUpdate("alpha", &binance.OrderTradeUpdate{Symbol: "BTCUSDT", ClientOrderID: "grid_1", Side: "BUY",
+		ExecutionType: "TRADE", Status: "FILLED", LastFilledQty: "0.6"}, true)
+	if err := buy("grid_5", 0.4); err != nil {
+		t.Fatalf("position 0.6 + 0.4 should hit the limit exactly: %v", err)
+	}
+	r.Release("grid_5")
+	if err := buy("grid_6", 0.4); err != nil {
+		t.Fatalf("released order should free its exposure: %v", err)
+	}
+	if reason(buy("grid_7", 0.1)) != ReasonPosition {
+		t.Error("long exposure above the limit should be rejected")
+	}
+	// 多头持仓下卖出 1.6 后净空 1.0，正好在上限内
+	if err := sell("grid_8", 1.6); err != nil {
+		t.Errorf("reducing orders should be allowed: %v", err)
+	}
+
+	// 对账补记的成交同样归属策略；未注册策略的成交被忽略
+	r.ApplyTrade("alpha", binance.UserTrade{Symbol: "BTCUSDT", Side: "SELL", Qty: 0.2}, "grid_8")
+	r.ApplyTrade("alpha", binance.UserTrade{Symbol: "BTCUSDT", Side: "SELL", Qty: 5}, "manual_1")
+	st := r.Stats()[0]
+	if got := st.Positions["alpha|BTCUSDT"]; got < 0.4-1e-9 || got > 0.4+1e-9 {
+		t.Errorf("expected position 0.4, got %v", got)
+	}
+	if st.PendingOrders != 3 { // grid_3 (beta)、grid_6、grid_8
+		t.Errorf("expected 3 pending orders, got %d", st.PendingOrders)
+	}
+
+	// 改单按新数量替换原挂单敞口
+	if reason(r.AuthorizeModify("grid", Order{Account: "alpha", Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.7, Price: 1, ClientOrderID: "grid_6"})) != ReasonPosition {
+		t.Error("modify increasing exposure beyond the limit should be rejected")
+	}
+	if err := r.AuthorizeModify("grid", Order{Account: "alpha", Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.5, Price: 1, ClientOrderID: "grid_6"}); err != nil {
+		t.Errorf("modify within the limit should pass: %v", err)
+	}
+
+	// 各策略的持仓上限只累计自己的挂单：a 的挂单不占用 b 的额度
+	r2, _ := newTestRegistry(Limits{Name: "a", MaxPosition: 1}, Limits{Name: "b", MaxPosition: 1})
+	r2.Seed("alpha", nil, nil)
+	if err := r2.AuthorizePlace("a", Order{Account: "alpha", Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, ClientOrderID: "a_1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.AuthorizePlace("b", Order{Account: "alpha", Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.5, ClientOrderID: "b_1"}); err != nil {
+		t.Errorf("another strategy's pending orders must not count towards b's limit: %v", err)
+	}
+}
+
+func TestSeed_RestoresPositionsAndPendingOrders(t *testing.T) {
+	r, _ := newTestRegistry(Limits{Name: "grid", MaxPosition: 1}, Limits{Name: "mm"})
+	buy := func(strategy, id string, qty float64) error {
+		return r.AuthorizePlace(strategy, Order{Account: "alpha", Symbol: "BTCUSDT", Side: "BUY", Quantity: qty, ClientOrderID: id})
+	}
+
+	// 恢复前持仓未知：设置了持仓上限的策略拒单，未设置的不受影响
+	if reason(buy("grid", "grid_1", 0.1)) != ReasonNotSeeded {
+		t.Error("max_position should not be enforced against an unrestored account")
+	}
+	if err := buy("mm", "mm_1", 5); err != nil {
+		t.Errorf("strategies without max_position should not wait for seeding: %v", err)
+	}
+
+	// 账本成交按前缀归属：grid 净多 0.4，手工单被忽略；现有挂单 grid_3 剩余 0.3 计入敞口
+	r.Seed("alpha", []Fill{
+		{Symbol: "BTCUSDT", Side: "BUY", ClientOrderID: "grid_1", Qty: 0.5},
+		{Symbol: "BTCUSDT", Side: "SELL", ClientOrderID: "grid_2", Qty: 0.1},
+		{Symbol: "BTCUSDT", Side: "BUY", ClientOrderID: "manual_1", Qty: 3},
+	}, []OpenOrder{
+		{Symbol: "BTCUSDT", Side: "BUY", ClientOrderID: "grid_3", Quantity: 0.5, Filled: 0.2},
+		{Symbol: "BTCUSDT", Side: "BUY", ClientOrderID: "other_1", Quantity: 9},
+	})
+	if reason(buy("grid", "grid_4", 0.4)) != ReasonPosition {
+		t.Error("restored position and pending order should count towards the limit")
+	}
+	if err := buy("grid", "grid_5", 0.3); err != nil {
+		t.Errorf("0.4 + 0.3 + 0.3 should hit the limit exactly: %v", err)
+	}
+
+	st := r.Stats()[0]
+	if got := st.Positions["alpha|BTCUSDT"]; got < 0.4-1e-9 || got > 0.4+1e-9 {
+		t.Errorf("expected restored position 0.4, got %v", got)
+	}
+	if st.PendingOrders != 2 { // grid_3、grid_5
+		t.Errorf("expected 2 pending orders, got %d", st.PendingOrders)
+	}
+}
+
+func TestAuthorize_MaxNotional(t *testing.T) {
+	r, _ := newTestRegistry(Limits{Name: "mm", MaxNotional: 1000})
+	order := func(id string, qty, price float64) error {
+		return r.AuthorizePlace("mm", Order{Symbol: "BTCUSDT", Side: "BUY", Quantity: qty, Price: price, ClientOrderID: id})
+	}
+	if err := order("mm_1", 10, 100); err != nil {
+		t.Errorf("notional equal to the limit should pass: %v", err)
+	}
+	if reason(order("mm_2", 11, 100)) != ReasonNotional {
+		t.Error("notional above the limit should be rejected")
+	}
+	// 市价单按标记价格估算，没有参考价格时拒绝
+	if reason(order("mm_3", 1, 0)) != ReasonNotional {
+		t.Error("market order without a reference price should be rejected")
+	}
+	r.OnMarkPrice("BTCUSDT", 900)
+	if err := order("mm_4", 1, 0); err != nil {
+		t.Errorf("market order priced by mark price should pass: %v", err)
+	}
+	if reason(order("mm_5", 2, 0)) != ReasonNotional {
+		t.Error("market order above the limit at mark price should be rejected")
+	}
+}
+
+func TestAuthorize_OrderRateSlidingWindow(t *testing.T) {
+	r, clock := newTestRegistry(Limits{Name: "hft", MaxOrders1m: 2})
+	place := func(id string) error {
+		return r.AuthorizePlace("hft", Order{Symbol: "BTCUSDT", Side: "BUY", Quantity: 1, ClientOrderID: id})
+	}
+	if err := place("hft_1"); err != nil {
+		t.Fatal(err)
+	}
+	*clock = clock.Add(30 * time.Second)
+	if err := r.AuthorizeModify("hft", Order{Symbol: "BTCUSDT", Side: "BUY", Quantity: 2, Price: 1, ClientOrderID: "hft_1"}); err != nil {
+		t.Fatal(err)
+	}
+	err := place("hft_2")
+	var v *Violation
+	if !errors.As(err, &v) || v.Reason != ReasonRate || !v.Retryable() {
+		t.Fatalf("third request within a minute should be rate limited: %v", err)
+	}
+	if r.AuthorizeCancel("hft", "alpha", "hft_1") != nil {
+		t.Error("cancels should not be rate limited")
+	}
+
+	// 第一笔滑出窗口后释放一个名额
+	*clock = clock.Add(31 * time.Second)
+	if err := place("hft_3"); err != nil {
+		t.Errorf("oldest request should have left the window: %v", err)
+	}
+	if reason(place("hft_4")) != ReasonRate {
+		t.Error("window should be full again")
+	}
+	if st := r.Stats()[0]; st.OrderCount1m != 2 {
+		t.Errorf("expected 2 orders in the window, got %d", st.OrderCount1m)
+	}
+}
@@ -0,0 +1,103 @@
+package udsauth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// 审计结果
+const (
+	OutcomeOK       = "ok"       // 2xx
+	OutcomeDenied   = "denied"   // 401 / 403：鉴权或策略限额拒绝
+	OutcomeRejected = "rejected" // 其他 4xx：参数错误或交易所拒绝
+	OutcomeError    = "error"    // 5xx：网关或交易所故障，结果可能未知
+)
+
+// maxPayload 审计日志中请求体的最大长度，超出部分截断
+const maxPayload = 4096
+
+// AuditEntry 一次影响订单 / 持仓的 UDS 调用
+type AuditEntry struct {
+	Time       int64           `json:"time"` // 收到请求的时间 (毫秒)
+	PeerPID    int32           `json:"peer_pid"`
+	PeerUID    uint32          `json:"peer_uid"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Strategy   string          `json:"strategy,omitempty"`
+	Account    string          `json:"account,omitempty"`
+	Auth       string          `json:"auth"` // none / token / hmac
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Status     int             `json:"status"`
+	Outcome    string          `json:"outcome"`
+	Error      string          `json:"error,omitempty"` // 非 2xx 时的响应体或鉴权失败原因
+	DurationMs float64         `json:"duration_ms"`
+}
+
+// Outcome 按 HTTP 状态码归类审计结果
+func Outcome(status int) string {
+	switch {
+	case status < 400:
+		return OutcomeOK
+	case status == 401 || status == 403:
+		return OutcomeDenied
+	case status < 500:
+		return OutcomeRejected
+	default:
+		return OutcomeError
+	}
+}
+
+// Payload 把请求体转换为审计字段：合法 JSON 原样保留，否则 (或超长截断后) 记录为字符串
+func Payload(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) <= maxPayload && json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	if len(body) > maxPayload {
+		body = body[:maxPayload]
+	}
+	return json.RawMessage(strconv.Quote(string(body)))
+}
+
+// AuditLog 追加写入的审计日志文件 (JSON Lines，权限 0600)
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog 打开 (必要时创建) 审计日志文件
+func OpenAuditLog(path string) (*AuditLog, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{file: f}, nil
+}
+
+// Record 写入一条审计记录
+func (a *AuditLog) Record(e AuditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.file.Write(line)
+	return err
+}
+
+// Close 关闭文件
+func (a *AuditLog) Close() error {
+	return a.file.Close()
+}
@@ -0,0 +1,128 @@
+package udsauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 鉴权方式
+const (
+	SchemeNone  = "none"  // 策略未配置凭证 (仅依赖对端身份)
+	SchemeToken = "token" // Authorization: Bearer <token>
+	SchemeHMAC  = "hmac"  // X-Timestamp + X-Signature
+)
+
+// HMAC 请求头
+const (
+	HeaderTimestamp = "X-Timestamp" // 毫秒时间戳
+	HeaderSignature = "X-Signature" // hex(HMAC-SHA256(secret, Sign 的签名串))
+)
+
+// MaxClockSkew HMAC 时间戳允许的最大偏差，窗口内同一签名只能使用一次
+const MaxClockSkew = 5 * time.Second
+
+// 拒绝原因
+const (
+	ReasonMissingCredentials = "missing_credentials" // 未带凭证，或 require_auth 下策略未声明 / 未配置凭证
+	ReasonInvalidToken       = "invalid_token"
+	ReasonInvalidSignature   = "invalid_signature"
+	ReasonStaleTimestamp     = "stale_timestamp" // 时间戳缺失、格式错误或超出 MaxClockSkew
+	ReasonReplayed           = "replayed"        // 窗口内重复使用的签名
+)
+
+// Credential 一个策略的凭证，Token 与 HMACSecret 二选一
+type Credential struct {
+	Token      string
+	HMACSecret string
+}
+
+// AuthError 鉴权失败
+type AuthError struct {
+	Strategy string
+	Reason   string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("strategy %q unauthorized: %s", e.Strategy, e.Reason)
+}
+
+// Verifier 按策略校验 Token / HMAC 凭证
+type Verifier struct {
+	creds   map[string]Credential
+	require bool
+
+	mu   sync.Mutex
+	seen map[string]time.Time // 已使用的签名 → 过期时间
+	now  func() time.Time
+}
+
+// NewVerifier creds 为策略名 → 凭证 (未配置凭证的策略不必出现)；
+// require 为 true 时未声明策略或策略没有凭证的请求同样拒绝
+func NewVerifier(creds map[string]Credential, require bool) *Verifier {
+	return &Verifier{creds: creds, require: require, seen: make(map[string]time.Time), now: time.Now}
+}
+
+// Verify 校验请求 r 是否带有策略 strategy 的有效凭证，body 为原始请求体；返回使用的鉴权方式
+func (v *Verifier) Verify(strategy string, r *http.Request, body []byte) (string, error) {
+	cred, ok := v.creds[strategy]
+	if !ok || (cred.Token == "" && cred.HMACSecret == "") {
+		if v.require {
+			return SchemeNone, &AuthError{Strategy: strategy, Reason: ReasonMissingCredentials}
+		}
+		return SchemeNone, nil
+	}
+
+	if cred.Token != "" {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			return SchemeToken, &AuthError{Strategy: strategy, Reason: ReasonMissingCredentials}
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cred.Token)) != 1 {
+			return SchemeToken, &AuthError{Strategy: strategy, Reason: ReasonInvalidToken}
+		}
+		return SchemeToken, nil
+	}
+
+	signature := r.Header.Get(HeaderSignature)
+	if signature == "" {
+		return SchemeHMAC, &AuthError{Strategy: strategy, Reason: ReasonMissingCredentials}
+	}
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	now := v.now()
+	if err != nil || now.Sub(time.UnixMilli(ts)).Abs() > MaxClockSkew {
+		return SchemeHMAC, &AuthError{Strategy: strategy, Reason: ReasonStaleTimestamp}
+	}
+	expected := Sign(cred.HMACSecret, ts, r.Method, r.URL.Path, body)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return SchemeHMAC, &AuthError{Strategy: strategy, Reason: ReasonInvalidSignature}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for sig, expires := range v.seen {
+		if now.After(expires) {
+			delete(v.seen, sig)
+		}
+	}
+	if _, used := v.seen[expected]; used {
+		return SchemeHMAC, &AuthError{Strategy: strategy, Reason: ReasonReplayed}
+	}
+	v.seen[expected] = time.UnixMilli(ts).Add(MaxClockSkew)
+	return SchemeHMAC, nil
+}
+
+// Sign 计算 HMAC 签名：hex(HMAC-SHA256(secret, "<timestamp>\n<METHOD>\n<path>\n<body>"))
+func Sign(secret string, timestampMs int64, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s\n%s\n", timestampMs, method, path)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package udsauth
+
+import (
+	"net"
+	"os"
+)
+
+// Listen 在 path 上监听 Unix Socket；当前平台没有 umask 与属组，只在创建后设置 mode
+func Listen(path string, mode os.FileMode, gid int) (net.Listener, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package udsauth
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// umaskMu 串行化临时修改进程 umask 的 Listen 调用
+var umaskMu sync.Mutex
+
+// Listen 在 path 上监听 Unix Socket，socket 文件创建时权限即不超过 mode (临时收紧进程 umask)，
+// 不存在先以默认权限创建、再 chmod 收紧的窗口；gid >= 0 时把属组改为 gid，再设置为 mode。
+// 修改 umask 期间其他 goroutine 新建的文件权限同样被收紧，只会更严格
+func Listen(path string, mode os.FileMode, gid int) (net.Listener, error) {
+	umaskMu.Lock()
+	old := syscall.Umask(int(0o777 &^ mode.Perm()))
+	l, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	umaskMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if gid >= 0 {
+		if err := os.Chown(path, -1, gid); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	// umask 只能去掉权限位，显式设置一次保证最终权限恰好为 mode
+	if err := os.Chmod(path, mode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,77 @@
+// Package udsauth UDS 交易通道的访问控制：SO_PEERCRED 对端身份、按策略的 Token / HMAC 鉴权与审计日志
+package udsauth
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrUnsupported 当前平台无法读取对端进程身份
+var ErrUnsupported = errors.New("udsauth: peer credentials are only supported on linux")
+
+// Cred 连接对端进程的身份 (建立连接时由内核记录)
+type Cred struct {
+	PID int32  `json:"pid"`
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+}
+
+// Conn 带对端身份的连接
+type Conn struct {
+	net.Conn
+	Cred Cred
+	// Known 是否成功读取到对端身份；不支持的平台上为 false
+	Known bool
+}
+
+// Listener 包装 Unix Socket 监听：Accept 时读取对端身份，Allow 返回 false 的连接直接关闭，不进入 HTTP 处理
+type Listener struct {
+	net.Listener
+	// Allow 判断对端是否允许连接；ok 为 false 表示无法读取对端身份
+	Allow func(cred Cred, ok bool) bool
+	// OnReject 被拒绝的连接 (可为 nil)
+	OnReject func(cred Cred, ok bool, err error)
+}
+
+// Accept 返回 *Conn；被拒绝的连接关闭后继续等待下一个
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		var cred Cred
+		ok := false
+		if uc, isUnix := c.(*net.UnixConn); isUnix {
+			cred, err = peerCred(uc)
+			ok = err == nil
+		} else {
+			err = errors.New("udsauth: not a unix socket connection")
+		}
+		if l.Allow != nil && !l.Allow(cred, ok) {
+			if l.OnReject != nil {
+				l.OnReject(cred, ok, err)
+			}
+			c.Close()
+			continue
+		}
+		return &Conn{Conn: c, Cred: cred, Known: ok}, nil
+	}
+}
+
+type credKey struct{}
+
+// ConnContext 供 http.Server.ConnContext 使用，把对端身份放入请求的 Context
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	if pc, ok := c.(*Conn); ok && pc.Known {
+		return context.WithValue(ctx, credKey{}, pc.Cred)
+	}
+	return ctx
+}
+
+// FromContext 取出请求对端的身份；未经 Listener 或读取失败时 ok 为 false
+func FromContext(ctx context.Context) (Cred, bool) {
+	cred, ok := ctx.Value(credKey{}).(Cred)
+	return cred, ok
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package udsauth
+
+import (
+	"net"
+	"syscall"
+)
+
+// Supported 当前平台是否支持读取对端身份
+const Supported = true
+
+func peerCred(c *net.UnixConn) (Cred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return Cred{}, err
+	}
+	var ucred *syscall.Ucred
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, sockErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return Cred{}, err
+	}
+	if sockErr != nil {
+		return Cred{}, sockErr
+	}
+	return Cred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
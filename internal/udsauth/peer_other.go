@@ -0,0 +1,12 @@
+//go:build !linux
+
+package udsauth
+
+import "net"
+
+// Supported 当前平台是否支持读取对端身份
+const Supported = false
+
+func peerCred(c *net.UnixConn) (Cred, error) {
+	return Cred{}, ErrUnsupported
+}
@@ -0,0 +1,223 @@
+package udsauth
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func authReason(err error) string {
+	var e *AuthError
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ""
+}
+
+func TestListener_PeerCred(t *testing.T) {
+	if !Supported {
+		t.Skip("peer credentials are not supported on this platform")
+	}
+	raw, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	allowUID := uint32(os.Getuid())
+	rejected := make(chan Cred, 1)
+	l := &Listener{
+		Listener: raw,
+		Allow:    func(cred Cred, ok bool) bool { return ok && cred.UID == allowUID },
+		OnReject: func(cred Cred, ok bool, err error) { rejected <- cred },
+	}
+
+	client, err := net.Dial("unix", raw.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := c.(*Conn)
+	if !pc.Known || pc.Cred.PID != int32(os.Getpid()) || pc.Cred.UID != uint32(os.Getuid()) {
+		t.Fatalf("unexpected peer credentials: %+v", pc)
+	}
+	if cred, ok := FromContext(ConnContext(t.Context(), c)); !ok || cred != pc.Cred {
+		t.Errorf("credentials should be carried into the request context: %+v %v", cred, ok)
+	}
+	c.Close()
+
+	// 不允许的 UID：连接被关闭，Accept 继续等待下一个连接
+	allowUID++
+	denied, err := net.Dial("unix", raw.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer denied.Close()
+	go l.Accept()
+	select {
+	case cred := <-rejected:
+		if cred.PID != int32(os.Getpid()) {
+			t.Errorf("rejected peer should report its pid: %+v", cred)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection from a disallowed uid should be rejected")
+	}
+	denied.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := denied.Read(make([]byte, 1)); err == nil {
+		t.Error("rejected connection should be closed")
+	}
+}
+
+func TestListen_SocketMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not supported on this platform")
+	}
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private.sock")
+	l, err := Listen(private, 0o600, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if info, err := os.Stat(private); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket should be created with 0600 regardless of umask: %v %v", info, err)
+	}
+
+	// 放行其他 UID 时通过属组授权，而不是对所有用户开放
+	shared := filepath.Join(dir, "shared.sock")
+	g, err := Listen(shared, 0o660, os.Getgid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	info, err := os.Stat(shared)
+	if err != nil || info.Mode().Perm() != 0o660 {
+		t.Fatalf("group socket should be 0660: %v %v", info, err)
+	}
+	if c, err := net.Dial("unix", shared); err != nil {
+		t.Errorf("socket should accept connections: %v", err)
+	} else {
+		c.Close()
+	}
+}
+
+func TestVerifier_Token(t *testing.T) {
+	v := NewVerifier(map[string]Credential{"grid": {Token: "s3cret"}}, false)
+	request := func(auth string) error {
+		r := httptest.NewRequest("POST", "/api/order", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		_, err := v.Verify("grid", r, nil)
+		return err
+	}
+	if authReason(request("")) != ReasonMissingCredentials || authReason(request("Bearer wrong")) != ReasonInvalidToken {
+		t.Error("missing or wrong token should be rejected")
+	}
+	if err := request("Bearer s3cret"); err != nil {
+		t.Errorf("valid token should pass: %v", err)
+	}
+
+	// 未配置凭证的策略只依赖对端身份；require 模式下拒绝
+	r := httptest.NewRequest("POST", "/api/order", nil)
+	if scheme, err := v.Verify("mm", r, nil); err != nil || scheme != SchemeNone {
+		t.Errorf("strategy without credentials should pass: %s %v", scheme, err)
+	}
+	strict := NewVerifier(map[string]Credential{"grid": {Token: "s3cret"}}, true)
+	if _, err := strict.Verify("", r, nil); authReason(err) != ReasonMissingCredentials {
+		t.Error("require mode should reject requests without a credentialed strategy")
+	}
+}
+
+func TestVerifier_HMAC(t *testing.T) {
+	v := NewVerifier(map[string]Credential{"mm": {HMACSecret: "k"}}, false)
+	now := time.UnixMilli(1_700_000_000_000)
+	v.now = func() time.Time { return now }
+
+	body := []byte(`{"strategy":"mm","symbol":"BTCUSDT"}`)
+	request := func(ts int64, sig string, payload []byte) error {
+		r := httptest.NewRequest("POST", "/api/order", nil)
+		r.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+		r.Header.Set(HeaderSignature, sig)
+		_, err := v.Verify("mm", r, payload)
+		return err
+	}
+	ts := now.UnixMilli()
+	sig := Sign("k", ts, "POST", "/api/order", body)
+	if err := request(ts, sig, body); err != nil {
+		t.Fatalf("valid signature should pass: %v", err)
+	}
+	if authReason(request(ts, sig, body)) != ReasonReplayed {
+		t.Error("reused signature should be rejected as a replay")
+	}
+	if authReason(request(ts, sig, []byte(`{"strategy":"mm","symbol":"ETHUSDT"}`))) != ReasonInvalidSignature {
+		t.Error("tampered body should fail verification")
+	}
+	old := ts - (MaxClockSkew + time.Second).Milliseconds()
+	if authReason(request(old, Sign("k", old, "POST", "/api/order", body), body)) != ReasonStaleTimestamp {
+		t.Error("timestamp outside the window should be rejected")
+	}
+
+	// 窗口过后签名从防重放缓存中清除，但同时也已超出时间窗
+	now = now.Add(MaxClockSkew + time.Millisecond)
+	next := now.UnixMilli()
+	if err := request(next, Sign("k", next, "POST", "/api/order", body), body); err != nil {
+		t.Errorf("fresh signature should pass: %v", err)
+	}
+	if len(v.seen) != 1 {
+		t.Errorf("expired signatures should be pruned, %d left", len(v.seen))
+	}
+}
+
+func TestAuditLog_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "uds.jsonl")
+	a, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Record(AuditEntry{Time: 1, PeerPID: 42, Path: "/api/order", Strategy: "grid", Auth: SchemeToken,
+		Payload: Payload([]byte(`{"symbol":"BTCUSDT"}`)), Status: 200, Outcome: Outcome(200)})
+	a.Record(AuditEntry{Time: 2, PeerPID: 42, Path: "/api/cancel", Payload: Payload([]byte("not json")),
+		Status: 403, Outcome: Outcome(403), Error: "invalid_token"})
+	a.Close()
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("audit log should be created with 0600: %v %v", info, err)
+	}
+	f, _ := os.Open(path)
+	defer f.Close()
+	var entries []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line is not valid JSON: %s", scanner.Text())
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if p, _ := entries[0]["payload"].(map[string]any); p["symbol"] != "BTCUSDT" || entries[0]["outcome"] != OutcomeOK {
+		t.Errorf("JSON payload should be embedded as-is: %v", entries[0])
+	}
+	if entries[1]["payload"] != "not json" || entries[1]["outcome"] != OutcomeDenied || entries[1]["peer_pid"] != float64(42) {
+		t.Errorf("non-JSON payload should be recorded as a string: %v", entries[1])
+	}
+	if got := string(Payload([]byte(strings.Repeat("x", maxPayload+10)))); len(got) != maxPayload+2 {
+		t.Errorf("oversized payload should be truncated, got %d bytes", len(got))
+	}
+}
@@ -0,0 +1,56 @@
+"""盘口快照解码：与 Go 网关 internal/codec 对应，按首字节自动识别 json / msgpack / binary 三种格式。
+
+解码结果统一为 json.loads 的结构：{"v", "s", "u", "t", "E", "T", "r", "st", "b": [{"p", "q"}], "a": [...]}，
+策略代码 float(book['b'][0]['p']) 等写法无需改动。
+"""
+import json
+import struct
+
+BOOK_VERSION = 2
+
+# 二进制布局中状态的数值编码
+BOOK_STATES = ("", "SYNCED", "RESYNCING", "STALE")
+
+# 定长二进制头部：magic(2) 版本(1) 状态(1) 买档数(2) 卖档数(2) u(8) t(8) E(8) T(8) r(8) symbol(16)，小端序
+_BINARY_HEADER = struct.Struct("<2sBBHHqqqqq16s")
+_LEVEL = struct.Struct("<dd")
+
+
+def decode_book(raw):
+    """解码 Redis 中的 OrderBook:<SYM> 或 Stream:Book:<SYM> 的 data 字段"""
+    if not raw:
+        return None
+    if isinstance(raw, str):
+        raw = raw.encode()
+
+    first = raw[0]
+    if first == ord('{'):
+        return json.loads(raw)
+    if raw[:2] == b"OB":
+        return _decode_binary(raw)
+    if first & 0xf0 == 0x80 or first == 0xde:
+        import msgpack  # 仅在使用 msgpack 格式时需要
+        return msgpack.unpackb(raw, raw=False)
+    raise ValueError(f"unknown book payload format: 0x{first:02x}")
+
+
+def _decode_binary(raw):
+    _, version, state, nb, na, update_id, ts, event_time, tx_time, recv_time, symbol = _BINARY_HEADER.unpack_from(raw)
+    offset = _BINARY_HEADER.size
+    levels = []
+    for _ in range(nb + na):
+        price, qty = _LEVEL.unpack_from(raw, offset)
+        levels.append({"p": price, "q": qty})
+        offset += _LEVEL.size
+    return {
+        "v": version,
+        "s": symbol.rstrip(b"\x00").decode(),
+        "u": update_id,
+        "t": ts,
+        "E": event_time,
+        "T": tx_time,
+        "r": recv_time,
+        "st": BOOK_STATES[state] if state < len(BOOK_STATES) else "",
+        "b": levels[:nb],
+        "a": levels[nb:],
+    }
@@ -2,12 +2,15 @@ import redis
 import json
 import time
 import os
+import hmac
+import hashlib
 import sys
 import requests_unixsocket
 
 from macd_strategy import MACD5MinStrategy
 from strategies import SpreadBreakoutStrategy  # noqa: F401 - available for strategy switching
 from obi_strategy import OBIMomentumStrategy
+from book_codec import decode_book
 
 
 class QuantEngine:
@@ -15,10 +18,26 @@ class QuantEngine:
         self.config = self._load_config()
         self.symbol = self.config['binance']['symbol']
         self.redis_client = self._init_redis()
+        # 盘口可能是 msgpack / binary 编码 (redis.book_format)，单独用不解码响应的连接读取
+        self.book_client = self._init_redis(decode_responses=False)
         self.session = requests_unixsocket.Session()
         self.uds_url = 'http+unix://%2Ftmp%2Fquant_engine.sock/api/order'
+        self.ready_url = 'http+unix://%2Ftmp%2Fquant_engine.sock/readyz'
+        # 网关就绪状态缓存 1 秒，避免每个信号都多一次 UDS 往返
+        self.ready_cache_ttl = 1.0
+        self._ready_checked_at = 0.0
+        self._ready = False
 
         strat_config = self.config.get('strategy', {})
+        # 多账户网关 (config.json accounts) 下必须声明交易账户，Redis 中该账户的 Key 带 "<account>:" 前缀
+        self.account = strat_config.get('account', '')
+        self.key_prefix = f"{self.account}:" if self.account else ""
+        # 网关配置了策略注册表 (config.json strategies) 时必须声明策略 ID，网关据此校验限额并作为 clientOrderId 前缀
+        self.strategy_id = strat_config.get('id', '')
+        # 网关为该策略配置了 token / hmac_secret 时，凭证与网关一样从环境变量读取，不写入 config.json
+        env_prefix = f"BINANCE_STRATEGY_{self.strategy_id.upper().replace('-', '_')}"
+        self.auth_token = os.environ.get(f"{env_prefix}_TOKEN", '') if self.strategy_id else ''
+        self.hmac_secret = os.environ.get(f"{env_prefix}_HMAC_SECRET", '') if self.strategy_id else ''
         active_env = self.config['binance']['active_env']
         self.last_print_time = 0.0
 
@@ -38,20 +57,41 @@ class QuantEngine:
             print(f"❌ 配置加载失败: {e}")
             sys.exit(1)
 
-    def _init_redis(self):
+    def _init_redis(self, decode_responses=True):
         host, port = self.config['redis']['addr'].split(':')
         db = self.config['redis']['db']
         try:
-            r = redis.Redis(host=host, port=int(port), db=db, decode_responses=True)
+            r = redis.Redis(host=host, port=int(port), db=db, decode_responses=decode_responses)
             r.ping()
             return r
         except Exception as e:
             print(f"❌ Redis 连接失败: {e}")
             sys.exit(1)
 
+    def gateway_ready(self):
+        """查询网关 /readyz：行情未同步、私有流断开或 ListenKey 失效时拒绝交易"""
+        now = time.monotonic()
+        if now - self._ready_checked_at < self.ready_cache_ttl:
+            return self._ready
+        self._ready_checked_at = now
+        try:
+            resp = self.session.get(self.ready_url, timeout=1.0)
+            self._ready = resp.status_code == 200
+            if not self._ready:
+                down = {name: c.get('detail', '') for name, c in resp.json().get('components', {}).items()
+                        if c.get('status') == 'down'}
+                print(f"⛔ [主引擎] 网关未就绪: {down}")
+        except Exception as e:
+            self._ready = False
+            print(f"🚨 [UDS 通信异常] 就绪检查失败: {e}")
+        return self._ready
+
     def execute_signal(self, signal):
         """执行策略模块产生的标准交易信号"""
         print(f"\n🚨 [主引擎] 接收到开火信号: {signal['reason']}")
+        if not self.gateway_ready():
+            print("⛔ [主引擎] 网关处于降级状态，放弃本次信号\n")
+            return
         print(f"🎯 [主引擎] 正在下达指令: {signal['side']} {signal['quantity']} {self.symbol} @ {signal['price']:.2f}")
 
         payload = {
@@ -61,11 +101,16 @@ class QuantEngine:
             "quantity": signal['quantity'],
             "price": signal['price']
         }
+        if self.account:
+            payload["account"] = self.account
+        if self.strategy_id:
+            payload["strategy"] = self.strategy_id
 
         try:
             start_t = time.perf_counter()
             # 🌟 修复 2：把底层 UDS 通信的超时时间从 2.0 延长到 10.0，防止 Testnet 偶尔卡顿导致误判
-            resp = self.session.post(self.uds_url, json=payload, timeout=10.0)
+            body = json.dumps(payload).encode()
+            resp = self.session.post(self.uds_url, data=body, headers=self._auth_headers('/api/order', body), timeout=10.0)
             latency = (time.perf_counter() - start_t) * 1000
 
             if resp.status_code == 200:
@@ -76,6 +121,18 @@ class QuantEngine:
         except Exception as e:
             print(f"🚨 [UDS 通信异常] {e}\n")
 
+    def _auth_headers(self, path, body):
+        """UDS 鉴权头：Bearer Token，或 X-Timestamp + X-Signature (HMAC-SHA256 签名串与网关 udsauth.Sign 一致)"""
+        headers = {'Content-Type': 'application/json'}
+        if self.auth_token:
+            headers['Authorization'] = f"Bearer {self.auth_token}"
+        elif self.hmac_secret:
+            ts = str(int(time.time() * 1000))
+            message = f"{ts}\nPOST\n{path}\n".encode() + body
+            headers['X-Timestamp'] = ts
+            headers['X-Signature'] = hmac.new(self.hmac_secret.encode(), message, hashlib.sha256).hexdigest()
+        return headers
+
     def run(self):
         print(f"🚀 量化主引擎启动 | 当前环境: {self.config['binance']['active_env'].upper()}")
         # 🌟 动态打印当前策略的所有配置参数，不再写死具体的属性名
@@ -96,14 +153,19 @@ class QuantEngine:
         try:
             while True:
                 try:
-                    raw_data = self.redis_client.get(redis_key)
+                    raw_data = self.book_client.get(redis_key)
                     if not raw_data:
                         time.sleep(0.01)  # 稍微降低睡眠时间，提高轮询精度
                         continue
 
-                    book = json.loads(raw_data)
+                    book = decode_book(raw_data)
                     current_id = book.get("u")
 
+                    # 盘口重同步或行情停滞时不做决策 (旧版网关没有 st 字段，视为 SYNCED)
+                    if book.get("st", "SYNCED") != "SYNCED":
+                        time.sleep(0.01)
+                        continue
+
                     if current_id == last_update_id:
                         time.sleep(0.005)
                         continue
@@ -125,10 +187,10 @@ class QuantEngine:
                         monitor_start_time = now
 
                     # 从 Redis 读取真实仓位和开仓均价
-                    pos_key = f"Position:{self.symbol}"
+                    pos_key = f"{self.key_prefix}Position:{self.symbol}"
                     pos_str = self.redis_client.get(pos_key)
 
-                    ep_key = f"EntryPrice:{self.symbol}"
+                    ep_key = f"{self.key_prefix}EntryPrice:{self.symbol}"
                     ep_str = self.redis_client.get(ep_key)
 
                     current_position = float(pos_str) if pos_str else 0.0
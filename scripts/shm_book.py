@@ -0,0 +1,68 @@
+"""共享内存盘口读取：与 Go 网关 internal/shmbook 对应。
+
+网关开启 shared_memory 后把 TopN 盘口写入 /dev/shm/quant_book_<SYM>，
+这里 mmap 后直接读内存，每个 tick 没有系统调用。布局与 seqlock 协议见 README「共享内存盘口」。
+"""
+import mmap
+import os
+import struct
+
+MAGIC = b"QBOOKSHM"
+LAYOUT_VERSION = 2
+BOOK_STATES = ("", "SYNCED", "RESYNCING", "STALE")
+
+_HEADER = struct.Struct("<8sII")           # magic, 布局版本, depth
+_SEQ = struct.Struct("<Q")                 # 偏移 16
+_BODY = struct.Struct("<qqIIqqqI4x16s")    # 偏移 24：u, t, nb, na, E, T, r, state, 保留, symbol
+_HEADER_SIZE = 96
+_LEVEL_SIZE = 16
+
+
+class ShmBookReader:
+    def __init__(self, symbol, shm_dir="/dev/shm"):
+        path = os.path.join(shm_dir, f"quant_book_{symbol}")
+        with open(path, "rb") as f:
+            self._mm = mmap.mmap(f.fileno(), 0, access=mmap.ACCESS_READ)
+
+        magic, version, depth = _HEADER.unpack_from(self._mm, 0)
+        if magic != MAGIC or version != LAYOUT_VERSION or len(self._mm) != _HEADER_SIZE + 2 * depth * _LEVEL_SIZE:
+            self._mm.close()
+            raise ValueError(f"{path} 不是兼容的共享内存盘口文件")
+        self.depth = depth
+        self._levels = struct.Struct(f"<{2 * depth}d")
+        self._asks_offset = _HEADER_SIZE + depth * _LEVEL_SIZE
+
+    def seq(self):
+        """当前序号，与上次相同说明盘口未更新"""
+        return _SEQ.unpack_from(self._mm, 16)[0]
+
+    def read(self, retries=1000):
+        """按 seqlock 协议读取一致的快照，返回 (seq, book)，book 结构与 Redis 中的 JSON 一致；网关尚未写入时 book 为 None"""
+        mm = self._mm
+        for _ in range(retries):
+            s1 = _SEQ.unpack_from(mm, 16)[0]
+            if s1 & 1:
+                continue
+            update_id, ts, nb, na, event_time, tx_time, recv_time, state, symbol = _BODY.unpack_from(mm, 24)
+            bids = self._levels.unpack_from(mm, _HEADER_SIZE)
+            asks = self._levels.unpack_from(mm, self._asks_offset)
+            if _SEQ.unpack_from(mm, 16)[0] != s1:
+                continue
+            if s1 == 0:
+                return 0, None
+            nb, na = min(nb, self.depth), min(na, self.depth)
+            return s1, {
+                "s": symbol.rstrip(b"\x00").decode(),
+                "u": update_id,
+                "t": ts,
+                "E": event_time,
+                "T": tx_time,
+                "r": recv_time,
+                "st": BOOK_STATES[state] if state < len(BOOK_STATES) else "",
+                "b": [{"p": bids[2 * i], "q": bids[2 * i + 1]} for i in range(nb)],
+                "a": [{"p": asks[2 * i], "q": asks[2 * i + 1]} for i in range(na)],
+            }
+        raise BlockingIOError("共享内存盘口持续写入中，稍后重试")
+
+    def close(self):
+        self._mm.close()
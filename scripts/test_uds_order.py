@@ -4,6 +4,8 @@ import json
 import redis
 import requests_unixsocket
 
+from book_codec import decode_book
+
 
 def load_config():
     # 🌟 动态计算绝对路径：无论在哪运行，都能精准定位到项目根目录的 config.json
@@ -29,8 +31,8 @@ def main():
 
     # 2. 连接 Redis
     try:
-        # decode_responses=True 会自动将 Redis 的 bytes 解码为 string
-        rdb = redis.Redis(host=redis_host, port=int(redis_port), db=redis_db, decode_responses=True)
+        # 盘口可能是 msgpack / binary 编码，保持 bytes 交给 decode_book 识别
+        rdb = redis.Redis(host=redis_host, port=int(redis_port), db=redis_db)
         rdb.ping()
         print(f"✅ Redis 连接成功，准备拉取 [{symbol}] 最新盘口...")
     except Exception as e:
@@ -45,7 +47,7 @@ def main():
         return
 
     # 4. 解析盘口数据并计算开火价
-    ob_data = json.loads(ob_json)
+    ob_data = decode_book(ob_json)
     asks = ob_data.get('a', [])
     if not asks:
         print("❌ 盘口 Ask(卖盘) 数据为空！")